@@ -4,8 +4,13 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"runtime"
+	"strings"
+	"time"
 
+	"monitor-agent/exporter"
 	"monitor-agent/service"
+	"monitor-agent/types"
 )
 
 var version = "1.0.0"
@@ -16,6 +21,68 @@ func main() {
 		cpuThreshold = flag.Float64("cpu-threshold", 80.0, "CPU threshold percentage")
 		cpuExceed    = flag.Int("cpu-exceed-count", 5, "consecutive CPU exceed count")
 		logDir       = flag.String("log-dir", "", "log directory (default: ./logs)")
+
+		// MQTT 发布
+		mqttBroker   = flag.String("mqtt-broker", "", "MQTT broker URL, e.g. tcp://127.0.0.1:1883 (empty: disabled)")
+		mqttClientID = flag.String("mqtt-client-id", "", "MQTT client ID (default: monitor-agent-<hostname>)")
+		mqttUser     = flag.String("mqtt-username", "", "MQTT username")
+		mqttPass     = flag.String("mqtt-password", "", "MQTT password")
+		mqttPrefix   = flag.String("mqtt-prefix", "plant", "MQTT topic prefix")
+		mqttQoS      = flag.Int("mqtt-qos", 1, "MQTT QoS level (0-2)")
+		mqttRetain   = flag.Bool("mqtt-retain", true, "retain latest metrics message per target")
+		mqttCA       = flag.String("mqtt-ca", "", "MQTT TLS CA certificate file")
+		mqttCert     = flag.String("mqtt-cert", "", "MQTT TLS client certificate file")
+		mqttKey      = flag.String("mqtt-key", "", "MQTT TLS client key file")
+		mqttInsecure = flag.Bool("mqtt-insecure", false, "skip MQTT broker certificate verification")
+		mqttBuffer   = flag.Int("mqtt-buffer", 10000, "max messages buffered while MQTT is offline")
+
+		// 历史存储与单向外发
+		historyDays     = flag.Int("history-retention", 30, "history retention in days")
+		outboxDir       = flag.String("outbox-dir", "", "one-way export outbox directory (empty: disabled)")
+		outboxInterval  = flag.Int("outbox-interval", 60, "outbox batch interval in seconds")
+		outboxFormat    = flag.String("outbox-format", "jsonl", "outbox batch format: jsonl or csv")
+		outboxGzip      = flag.Bool("outbox-gzip", false, "gzip outbox batch files")
+		outboxRetention = flag.Int("outbox-retention", 168, "hours to keep batches not picked up by the gateway")
+		importDir       = flag.String("import", "", "import sealed outbox batches from directory into history and exit")
+
+		// 崩溃现场采集
+		forensics          = flag.Bool("forensics", true, "save forensics bundles on exit/threshold/hang events")
+		forensicsMinutes   = flag.Int("forensics-minutes", 5, "minutes of metrics included in forensics bundles")
+		forensicsRetention = flag.Int("forensics-retention", 30, "forensics bundle retention in days")
+		forensicsMax       = flag.Int("forensics-max", 20, "max forensics bundles kept per target")
+		coreWatch          = flag.Bool("core-watch", true, "detect core dumps of monitored targets")
+		coreDirs           = flag.String("core-dir", "", "extra directories to scan for core files (comma separated)")
+		coreArchive        = flag.String("core-archive", "", "move detected core files into this archive directory (empty: keep in place)")
+		coreArchiveMax     = flag.Int64("core-archive-max", 10240, "core archive size cap in MB")
+		coreArchiveDays    = flag.Int("core-archive-retention", 30, "core archive retention in days")
+		kernelLog          = flag.String("kernel-log", defaultKernelLog(), "kernel log to watch for OOM kills/segfaults (/dev/kmsg or a file, empty: disabled)")
+		discoveryInterval  = flag.Int("discovery-interval", 10, "auto-discovery scan interval in seconds")
+		inventory          = flag.Bool("inventory", false, "detect processes outside the learned inventory baseline")
+		inventoryInterval  = flag.Int("inventory-interval", 30, "inventory scan interval in seconds")
+		inventoryAllow     = flag.String("inventory-allow", "", "additionally allowed executable path patterns (comma separated)")
+		inventoryDeny      = flag.String("inventory-deny", "", "denied executable path or process name patterns (comma separated)")
+		inventoryKill      = flag.Bool("inventory-kill", false, "kill denied processes automatically")
+		inventoryKeep      = flag.Int("inventory-keep", 24, "hours to keep unknown entries that are no longer running")
+		heartbeatUDP       = flag.String("heartbeat-udp", "", "UDP address to receive application heartbeats (empty: disabled)")
+		heartbeatHTTP      = flag.String("heartbeat-http", "", "HTTP address to receive application heartbeats at POST /heartbeat (empty: disabled)")
+		statsdAddr         = flag.String("statsd", "", "UDP address of the StatsD listener for custom metrics (empty: disabled)")
+		customHTTP         = flag.String("custom-http", "", "HTTP address to receive custom metrics at POST /metrics (empty: disabled)")
+		customMaxSeries    = flag.Int("custom-max-series", 100, "maximum number of custom metrics per target, new names beyond it are rejected")
+		checksFile         = flag.String("checks", "", "JSON file of Nagios-plugin style checks to schedule at startup")
+		checksConcurrent   = flag.Int("checks-concurrency", 4, "maximum number of checks running at the same time")
+		diskWatch          = flag.Bool("disk", true, "monitor filesystem space/inodes and disk IO")
+		diskInterval       = flag.Int("disk-interval", 30, "disk sampling interval in seconds")
+		diskInclude        = flag.String("disk-include", "", "mount point patterns to monitor (comma separated, empty: all)")
+		diskExclude        = flag.String("disk-exclude", "", "mount point patterns to skip (comma separated)")
+		diskUsed           = flag.Float64("disk-used", 90, "filesystem space usage alarm threshold percentage")
+		diskInode          = flag.Float64("disk-inode", 90, "filesystem inode usage alarm threshold percentage")
+		diskForecast       = flag.Float64("disk-forecast", 24, "alarm when a filesystem is forecast to fill within this many hours (negative: disabled)")
+		systemInterval     = flag.Int("system-interval", 5, "interval in seconds for recording host metrics into history")
+		sensors            = flag.Bool("sensors", true, "monitor hardware temperature/fan/voltage sensors (Linux hwmon/thermal)")
+		sensorsRoot        = flag.String("sensors-root", "/sys", "sysfs root for hardware sensors")
+		sensorsInterval    = flag.Int("sensors-interval", 10, "sensor sampling interval in seconds")
+		sensorsTempMax     = flag.Float64("sensors-temp-max", 0, "temperature alarm threshold in °C for all sensors (0: use chip limits)")
+		sensorsHysteresis  = flag.Float64("sensors-hysteresis", 5, "recovery margin in percent of the limit before a sensor alarm clears")
 		
 		// 服务管理命令
 		runService   = flag.Bool("service", false, "run as service")
@@ -34,6 +101,25 @@ func main() {
 		return
 	}
 
+	// 导入外发批次
+	if *importDir != "" {
+		res, err := service.ImportOutbox(service.Config{LogDir: *logDir, HistoryRetention: *historyDays}, *importDir)
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		fmt.Printf("Imported %d batches (%d metrics, %d events), skipped %d\n", res.Batches, res.Metrics, res.Events, res.Skipped)
+		for _, f := range res.Failed {
+			fmt.Printf("  rejected: %s\n", f)
+		}
+		for _, f := range res.Blocked {
+			fmt.Printf("  blocked:  %s\n", f)
+		}
+		for _, m := range res.Missing {
+			fmt.Printf("  missing:  %s\n", m)
+		}
+		return
+	}
+
 	// 服务管理命令
 	if *install {
 		if err := service.InstallService(); err != nil {
@@ -76,10 +162,85 @@ func main() {
 
 	// 配置
 	cfg := service.Config{
-		Addr:           *addr,
-		CPUThreshold:   *cpuThreshold,
-		CPUExceedCount: *cpuExceed,
-		LogDir:         *logDir,
+		Addr:              *addr,
+		CPUThreshold:      *cpuThreshold,
+		CPUExceedCount:    *cpuExceed,
+		LogDir:            *logDir,
+		HistoryRetention:  *historyDays,
+		KernelLog:         *kernelLog,
+		DiscoveryInterval: *discoveryInterval,
+		SystemInterval:    *systemInterval,
+		MQTT: exporter.MQTTConfig{
+			Broker:             *mqttBroker,
+			ClientID:           *mqttClientID,
+			Username:           *mqttUser,
+			Password:           *mqttPass,
+			TopicPrefix:        *mqttPrefix,
+			QoS:                byte(*mqttQoS),
+			Retain:             *mqttRetain,
+			CAFile:             *mqttCA,
+			CertFile:           *mqttCert,
+			KeyFile:            *mqttKey,
+			InsecureSkipVerify: *mqttInsecure,
+			BufferLen:          *mqttBuffer,
+		},
+		Outbox: exporter.OutboxConfig{
+			Dir:       *outboxDir,
+			Interval:  time.Duration(*outboxInterval) * time.Second,
+			Format:    *outboxFormat,
+			Gzip:      *outboxGzip,
+			Retention: time.Duration(*outboxRetention) * time.Hour,
+		},
+		CoreDumps: types.CoreDumpConfig{
+			Enabled:              *coreWatch,
+			Dirs:                 splitList(*coreDirs),
+			ArchiveDir:           *coreArchive,
+			ArchiveMaxBytes:      *coreArchiveMax << 20,
+			ArchiveRetentionDays: *coreArchiveDays,
+		},
+		Inventory: types.InventoryConfig{
+			Enabled:          *inventory,
+			Interval:         *inventoryInterval,
+			Allow:            splitList(*inventoryAllow),
+			Deny:             splitList(*inventoryDeny),
+			KillDenied:       *inventoryKill,
+			UnknownRetention: *inventoryKeep,
+		},
+		Heartbeat: types.HeartbeatConfig{
+			UDPAddr:  *heartbeatUDP,
+			HTTPAddr: *heartbeatHTTP,
+		},
+		CustomMetrics: types.CustomMetricsConfig{
+			StatsDAddr: *statsdAddr,
+			HTTPAddr:   *customHTTP,
+			MaxSeries:  *customMaxSeries,
+		},
+		Checks: types.ChecksConfig{
+			File:          *checksFile,
+			MaxConcurrent: *checksConcurrent,
+		},
+		Disk: types.DiskConfig{
+			Enabled:       *diskWatch,
+			Interval:      *diskInterval,
+			Include:       splitList(*diskInclude),
+			Exclude:       splitList(*diskExclude),
+			UsedPercent:   *diskUsed,
+			InodePercent:  *diskInode,
+			ForecastHours: *diskForecast,
+		},
+		Sensors: types.SensorConfig{
+			Enabled:    *sensors,
+			SysfsRoot:  *sensorsRoot,
+			Interval:   *sensorsInterval,
+			TempMax:    *sensorsTempMax,
+			Hysteresis: *sensorsHysteresis,
+		},
+		Forensics: types.ForensicsConfig{
+			Enabled:       *forensics,
+			Minutes:       *forensicsMinutes,
+			RetentionDays: *forensicsRetention,
+			MaxPerTarget:  *forensicsMax,
+		},
 	}
 
 	// 运行服务
@@ -113,3 +274,22 @@ func runInteractive(cfg service.Config) {
 	
 	s.Stop()
 }
+
+// splitList 解析逗号分隔的列表
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// defaultKernelLog Linux 下默认读取 /dev/kmsg，其他平台不启用
+func defaultKernelLog() string {
+	if runtime.GOOS == "linux" {
+		return "/dev/kmsg"
+	}
+	return ""
+}
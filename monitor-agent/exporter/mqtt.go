@@ -0,0 +1,303 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"monitor-agent/types"
+)
+
+// MQTTConfig MQTT 发布配置
+type MQTTConfig struct {
+	Broker             string // 例如 tcp://127.0.0.1:1883、ssl://broker:8883
+	ClientID           string // 为空时使用 monitor-agent-<主机名>
+	Username           string
+	Password           string
+	TopicPrefix        string // 主题前缀，默认 plant
+	QoS                byte   // 0/1/2
+	Retain             bool   // 指标消息是否保留
+	CAFile             string // TLS CA 证书
+	CertFile           string // TLS 客户端证书
+	KeyFile            string // TLS 客户端私钥
+	InsecureSkipVerify bool   // 跳过服务端证书校验（仅测试用）
+	BufferLen          int    // 离线缓存消息条数
+}
+
+// mqttMessage 待发布消息
+type mqttMessage struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// MQTTPublisher 将指标和事件发布到 MQTT
+// 主题格式：<prefix>/<host>/<target>/metrics 与 <prefix>/<host>/<target>/events
+type MQTTPublisher struct {
+	config   MQTTConfig
+	host     string
+	client   mqtt.Client
+	mu       sync.Mutex
+	pending  []mqttMessage // 离线缓存，重连后补发
+	dropped  int
+	flushing bool
+	stopCh   chan struct{}
+}
+
+// NewMQTTPublisher 创建 MQTT 发布器
+func NewMQTTPublisher(cfg MQTTConfig) (*MQTTPublisher, error) {
+	if cfg.Broker == "" {
+		return nil, fmt.Errorf("mqtt broker required")
+	}
+	if cfg.QoS > 2 {
+		return nil, fmt.Errorf("invalid mqtt qos %d", cfg.QoS)
+	}
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = "plant"
+	}
+	cfg.TopicPrefix = strings.TrimSuffix(cfg.TopicPrefix, "/")
+	if cfg.BufferLen <= 0 {
+		cfg.BufferLen = 10000
+	}
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "unknown"
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "monitor-agent-" + host
+	}
+
+	p := &MQTTPublisher{config: cfg, host: topicSegment(host), stopCh: make(chan struct{})}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second).
+		SetMaxReconnectInterval(time.Minute).
+		SetKeepAlive(30 * time.Second).
+		SetOnConnectHandler(p.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("[MQTT] connection lost: %v", err)
+		})
+
+	// Last Will：异常断开时由 broker 发布离线状态
+	will, _ := json.Marshal(map[string]any{"host": host, "status": "offline"})
+	opts.SetBinaryWill(p.statusTopic(), will, cfg.QoS, true)
+
+	if cfg.CAFile != "" || cfg.CertFile != "" || cfg.InsecureSkipVerify {
+		tlsCfg, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsCfg)
+	}
+
+	p.client = mqtt.NewClient(opts)
+	return p, nil
+}
+
+func newTLSConfig(cfg MQTTConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read mqtt ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load mqtt client cert: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// Start 连接 broker（连接失败时后台持续重试）
+func (p *MQTTPublisher) Start() {
+	p.client.Connect()
+	log.Printf("[MQTT] connecting to %s (prefix=%s)", p.config.Broker, p.config.TopicPrefix)
+}
+
+// Stop 发布离线状态并断开连接
+func (p *MQTTPublisher) Stop() {
+	close(p.stopCh)
+	if p.client.IsConnected() {
+		payload, _ := json.Marshal(map[string]any{"host": p.host, "status": "offline"})
+		p.client.Publish(p.statusTopic(), p.config.QoS, true, payload).WaitTimeout(2 * time.Second)
+	}
+	p.client.Disconnect(1000)
+	log.Printf("[MQTT] disconnected")
+}
+
+// OnMetric 实现 monitor.Sink
+func (p *MQTTPublisher) OnMetric(target types.MonitorTarget, metric types.ProcessMetrics) {
+	payload, err := json.Marshal(map[string]any{
+		"host":   p.host,
+		"target": target.Name,
+		"alias":  target.Alias,
+		"metric": metric,
+	})
+	if err != nil {
+		return
+	}
+	p.publish(mqttMessage{
+		topic:   p.targetTopic(target.Name, "metrics"),
+		payload: payload,
+		retain:  p.config.Retain,
+	})
+}
+
+// OnEvent 实现 monitor.Sink
+func (p *MQTTPublisher) OnEvent(evt types.Event) {
+	payload, err := json.Marshal(map[string]any{
+		"host":  p.host,
+		"event": evt,
+	})
+	if err != nil {
+		return
+	}
+	// 事件不保留，避免新订阅者收到过期告警
+	p.publish(mqttMessage{
+		topic:   p.targetTopic(evt.Name, "events"),
+		payload: payload,
+	})
+}
+
+// publish 已连接且缓存为空时直接发布，否则写入缓存按顺序补发
+func (p *MQTTPublisher) publish(msg mqttMessage) {
+	p.mu.Lock()
+	if !p.client.IsConnectionOpen() || p.flushing || len(p.pending) > 0 {
+		p.enqueueLocked(msg)
+		p.startFlushLocked()
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	token := p.client.Publish(msg.topic, p.config.QoS, msg.retain, msg.payload)
+	go func() {
+		if token.WaitTimeout(10*time.Second) && token.Error() == nil {
+			return
+		}
+		p.mu.Lock()
+		p.requeueLocked(msg)
+		p.startFlushLocked()
+		p.mu.Unlock()
+	}()
+}
+
+// enqueueLocked 写入离线缓存，超出容量时丢弃最旧的消息
+func (p *MQTTPublisher) enqueueLocked(msg mqttMessage) {
+	if len(p.pending) >= p.config.BufferLen {
+		p.pending = p.pending[1:]
+		p.dropped++
+	}
+	p.pending = append(p.pending, msg)
+}
+
+// requeueLocked 发送失败的消息放回队首，缓存已满时丢弃（它是最旧的消息）
+func (p *MQTTPublisher) requeueLocked(msg mqttMessage) {
+	if len(p.pending) >= p.config.BufferLen {
+		p.dropped++
+		return
+	}
+	p.pending = append([]mqttMessage{msg}, p.pending...)
+}
+
+// startFlushLocked 连接可用且未在补发时启动补发（调用方持有 p.mu）
+func (p *MQTTPublisher) startFlushLocked() {
+	if p.flushing || len(p.pending) == 0 || !p.client.IsConnectionOpen() {
+		return
+	}
+	p.flushing = true
+	go p.flush(p.client)
+}
+
+// onConnect 连接（或重连）成功：发布在线状态并补发离线缓存
+func (p *MQTTPublisher) onConnect(c mqtt.Client) {
+	log.Printf("[MQTT] connected to %s", p.config.Broker)
+	online, _ := json.Marshal(map[string]any{"host": p.host, "status": "online", "timestamp": time.Now()})
+	c.Publish(p.statusTopic(), p.config.QoS, true, online)
+
+	p.mu.Lock()
+	p.startFlushLocked()
+	p.mu.Unlock()
+}
+
+// flush 按顺序补发缓存；连接仍在时发送失败按退避重试，连接断开后由重连触发
+func (p *MQTTPublisher) flush(c mqtt.Client) {
+	sent := 0
+	backoff := time.Second
+	for {
+		p.mu.Lock()
+		if len(p.pending) == 0 || !c.IsConnectionOpen() {
+			dropped := p.dropped
+			p.dropped = 0
+			p.flushing = false
+			p.mu.Unlock()
+			if sent > 0 || dropped > 0 {
+				log.Printf("[MQTT] flushed %d buffered messages (%d dropped while offline)", sent, dropped)
+			}
+			return
+		}
+		msg := p.pending[0]
+		p.pending = p.pending[1:]
+		p.mu.Unlock()
+
+		token := c.Publish(msg.topic, p.config.QoS, msg.retain, msg.payload)
+		if !token.WaitTimeout(10*time.Second) || token.Error() != nil {
+			p.mu.Lock()
+			p.requeueLocked(msg)
+			p.mu.Unlock()
+			select {
+			case <-p.stopCh:
+				p.mu.Lock()
+				p.flushing = false
+				p.mu.Unlock()
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		sent++
+		backoff = time.Second
+	}
+}
+
+func (p *MQTTPublisher) statusTopic() string {
+	return fmt.Sprintf("%s/%s/status", p.config.TopicPrefix, p.host)
+}
+
+func (p *MQTTPublisher) targetTopic(target, kind string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", p.config.TopicPrefix, p.host, topicSegment(target), kind)
+}
+
+// topicSegment 清理主题中的通配符和分隔符
+func topicSegment(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_", " ", "_").Replace(s)
+}
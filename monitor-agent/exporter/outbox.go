@@ -0,0 +1,390 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// OutboxConfig 单向外发（数据二极管/安全区隔离）配置
+type OutboxConfig struct {
+	Dir       string        // 外发目录，由单向网关拾取
+	Interval  time.Duration // 批次封存周期
+	Format    string        // jsonl 或 csv
+	Gzip      bool          // 是否 gzip 压缩
+	Retention time.Duration // 未被网关取走的批次保留时长
+	MaxRecord int           // 单批次最大记录数，超过立即封存
+	MaxBuffer int           // 封存失败时最多缓存的记录数，超出丢弃最旧的
+}
+
+// BatchManifest 批次清单，与数据文件同名加 .manifest.json 后缀
+// 清单出现即表示批次已封存，导入端只处理有清单的批次
+type BatchManifest struct {
+	Host     string    `json:"host"`
+	Seq      uint64    `json:"seq"`
+	Created  time.Time `json:"created"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	File     string    `json:"file"`
+	Format   string    `json:"format"`
+	Gzip     bool      `json:"gzip"`
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Metrics  int       `json:"metrics"`
+	Events   int       `json:"events"`
+	Previous uint64    `json:"previous"` // 上一批次序号，用于检测缺失
+}
+
+// outboxRecord 批次中的一条记录
+type outboxRecord struct {
+	Kind   string                `json:"kind"` // metric / event
+	Metric *types.ProcessMetrics `json:"metric,omitempty"`
+	Event  *types.Event          `json:"event,omitempty"`
+}
+
+const manifestSuffix = ".manifest.json"
+
+// csvHeader CSV 批次列定义
+var csvHeader = []string{"kind", "timestamp", "pid", "name", "cpu_pct", "rss_bytes", "alive", "event_type", "message"}
+
+// OutboxExporter 周期性将指标和事件写入外发目录的封存批次
+type OutboxExporter struct {
+	config  OutboxConfig
+	host    string
+	staging string
+	mu      sync.Mutex
+	records []outboxRecord
+	dropped int
+	writeMu sync.Mutex // 串行化批次写入，保护 seq
+	seq     uint64
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewOutboxExporter 创建外发导出器
+func NewOutboxExporter(cfg OutboxConfig) (*OutboxExporter, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("outbox dir required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Format == "" {
+		cfg.Format = "jsonl"
+	}
+	if cfg.Format != "jsonl" && cfg.Format != "csv" {
+		return nil, fmt.Errorf("invalid outbox format %q (jsonl or csv)", cfg.Format)
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.MaxRecord <= 0 {
+		cfg.MaxRecord = 100000
+	}
+	if cfg.MaxBuffer < cfg.MaxRecord {
+		cfg.MaxBuffer = 10 * cfg.MaxRecord
+	}
+
+	// 临时文件写入隐藏的 staging 子目录，完成后 rename 到外发目录，保证网关只看到完整文件
+	staging := filepath.Join(cfg.Dir, ".staging")
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return nil, fmt.Errorf("create outbox dir: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "unknown"
+	}
+
+	e := &OutboxExporter{
+		config:  cfg,
+		host:    topicSegment(host),
+		staging: staging,
+		flushCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+	}
+	e.seq = e.loadSeq()
+	return e, nil
+}
+
+// Start 启动周期封存
+func (e *OutboxExporter) Start() {
+	e.wg.Add(1)
+	go e.loop()
+	log.Printf("[OUTBOX] exporting to %s every %v (format=%s gzip=%v)", e.config.Dir, e.config.Interval, e.config.Format, e.config.Gzip)
+}
+
+// Stop 停止并封存剩余记录
+func (e *OutboxExporter) Stop() {
+	close(e.stopCh)
+	e.wg.Wait()
+	if err := e.Flush(); err != nil {
+		log.Printf("[OUTBOX] final flush failed: %v", err)
+	}
+}
+
+func (e *OutboxExporter) loop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			if err := e.Flush(); err != nil {
+				log.Printf("[OUTBOX] write batch failed: %v", err)
+			}
+			e.cleanup()
+		case <-e.flushCh:
+			if err := e.Flush(); err != nil {
+				log.Printf("[OUTBOX] write batch failed: %v", err)
+			}
+		}
+	}
+}
+
+// OnMetric 实现 monitor.Sink
+func (e *OutboxExporter) OnMetric(_ types.MonitorTarget, metric types.ProcessMetrics) {
+	e.add(outboxRecord{Kind: "metric", Metric: &metric})
+}
+
+// OnEvent 实现 monitor.Sink
+func (e *OutboxExporter) OnEvent(evt types.Event) {
+	e.add(outboxRecord{Kind: "event", Event: &evt})
+}
+
+func (e *OutboxExporter) add(rec outboxRecord) {
+	e.mu.Lock()
+	e.records = append(e.records, rec)
+	e.trimLocked()
+	full := len(e.records) >= e.config.MaxRecord
+	e.mu.Unlock()
+	if full {
+		// 已有待处理的封存请求时不重复通知
+		select {
+		case e.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// trimLocked 缓存超过上限时丢弃最旧的记录（调用方持有 e.mu）
+func (e *OutboxExporter) trimLocked() {
+	if over := len(e.records) - e.config.MaxBuffer; over > 0 {
+		e.records = e.records[over:]
+		e.dropped += over
+	}
+}
+
+// Flush 立即封存当前缓存的记录
+// 编码和写入不持有 e.mu，封存期间采样路径的 add 不被阻塞
+func (e *OutboxExporter) Flush() error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	e.mu.Lock()
+	records, dropped := e.records, e.dropped
+	e.records, e.dropped = nil, 0
+	e.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("[OUTBOX] dropped %d records while batches could not be written", dropped)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if err := e.writeBatch(records); err != nil {
+		// 放回缓存（早于封存期间新增的记录），下个周期重试
+		e.mu.Lock()
+		e.records = append(records, e.records...)
+		e.trimLocked()
+		e.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// writeBatch 写入数据文件和清单（均先写 staging 再 rename），调用方持有 e.writeMu
+func (e *OutboxExporter) writeBatch(records []outboxRecord) error {
+	data, err := e.encode(records)
+	if err != nil {
+		return err
+	}
+
+	seq := e.seq + 1
+	ext := "." + e.config.Format
+	if e.config.Gzip {
+		ext += ".gz"
+	}
+	name := fmt.Sprintf("%s_%010d_%s%s", e.host, seq, time.Now().Format("20060102T150405"), ext)
+	sum := sha256.Sum256(data)
+
+	manifest := BatchManifest{
+		Host:     e.host,
+		Seq:      seq,
+		Created:  time.Now(),
+		File:     name,
+		Format:   e.config.Format,
+		Gzip:     e.config.Gzip,
+		Size:     int64(len(data)),
+		SHA256:   hex.EncodeToString(sum[:]),
+		Previous: e.seq,
+	}
+	for _, r := range records {
+		var ts time.Time
+		if r.Metric != nil {
+			ts = r.Metric.Timestamp
+			manifest.Metrics++
+		} else if r.Event != nil {
+			ts = r.Event.Timestamp
+			manifest.Events++
+		}
+		if manifest.From.IsZero() || ts.Before(manifest.From) {
+			manifest.From = ts
+		}
+		if ts.After(manifest.To) {
+			manifest.To = ts
+		}
+	}
+	manifestData, _ := json.MarshalIndent(manifest, "", "  ")
+
+	// 先持久化序号再发布清单，崩溃后不会复用已发布的序号（导入端会当作已导入丢弃）
+	e.seq = seq
+	if err := e.saveSeq(); err != nil {
+		e.seq = seq - 1
+		return fmt.Errorf("save outbox seq: %w", err)
+	}
+	err = writeFileAtomic(e.staging, e.config.Dir, name, data)
+	if err == nil {
+		err = writeFileAtomic(e.staging, e.config.Dir, name+manifestSuffix, manifestData)
+	}
+	if err != nil {
+		// 清单未发布，序号可以复用
+		os.Remove(filepath.Join(e.config.Dir, name))
+		e.seq = seq - 1
+		e.saveSeq()
+		return err
+	}
+	return nil
+}
+
+// encode 按配置格式编码并压缩
+func (e *OutboxExporter) encode(records []outboxRecord) ([]byte, error) {
+	var raw bytes.Buffer
+	if e.config.Format == "csv" {
+		w := csv.NewWriter(&raw)
+		w.Write(csvHeader)
+		for _, r := range records {
+			w.Write(recordToCSV(r))
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+	} else {
+		enc := json.NewEncoder(&raw)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !e.config.Gzip {
+		return raw.Bytes(), nil
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return gz.Bytes(), nil
+}
+
+func recordToCSV(r outboxRecord) []string {
+	if r.Metric != nil {
+		m := r.Metric
+		return []string{"metric", m.Timestamp.Format(time.RFC3339Nano), strconv.Itoa(int(m.PID)), m.Name,
+			strconv.FormatFloat(m.CPUPct, 'f', -1, 64), strconv.FormatUint(m.RSSBytes, 10), strconv.FormatBool(m.Alive), "", ""}
+	}
+	ev := r.Event
+	return []string{"event", ev.Timestamp.Format(time.RFC3339Nano), strconv.Itoa(int(ev.PID)), ev.Name, "", "", "", ev.Type, ev.Message}
+}
+
+// writeFileAtomic 在 staging 写入并 fsync 后 rename 到目标目录
+func writeFileAtomic(staging, dir, name string, data []byte) error {
+	tmp := filepath.Join(staging, name+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	f.Close()
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+// cleanup 删除超过保留时长仍未被网关取走的批次
+func (e *OutboxExporter) cleanup() {
+	entries, err := os.ReadDir(e.config.Dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-e.config.Retention)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		os.Remove(filepath.Join(e.config.Dir, entry.Name()))
+	}
+}
+
+func (e *OutboxExporter) seqPath() string {
+	return filepath.Join(e.staging, "seq")
+}
+
+// loadSeq 读取持久化的序号，保证重启后序号连续
+func (e *OutboxExporter) loadSeq() uint64 {
+	data, err := os.ReadFile(e.seqPath())
+	if err != nil {
+		return 0
+	}
+	seq, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return seq
+}
+
+func (e *OutboxExporter) saveSeq() error {
+	tmp := e.seqPath() + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(e.seq, 10)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.seqPath())
+}
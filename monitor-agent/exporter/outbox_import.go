@@ -0,0 +1,247 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor-agent/history"
+	"monitor-agent/types"
+)
+
+// ImportResult 批次导入结果
+type ImportResult struct {
+	Batches int      `json:"batches"`
+	Metrics int      `json:"metrics"`
+	Events  int      `json:"events"`
+	Skipped int      `json:"skipped"` // 已导入过的批次
+	Failed  []string `json:"failed"`  // 校验失败或无法解析的批次
+	Blocked []string `json:"blocked"` // 同一主机前序批次失败，推迟到下次导入的批次
+	Missing []string `json:"missing"` // 序号不连续（疑似丢失）的批次
+}
+
+// importState 导入进度
+type importState struct {
+	Seq     map[string]uint64 `json:"seq"`               // 每个来源主机已导入的最大序号
+	Partial map[string]int    `json:"partial,omitempty"` // 写入中途失败的批次 -> 已写入的记录数
+}
+
+// loadImportState 读取导入进度，兼容旧格式（主机 -> 序号）
+func loadImportState(path string) importState {
+	state := importState{}
+	if data, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(data, &state) != nil || state.Seq == nil {
+			json.Unmarshal(data, &state.Seq)
+		}
+	}
+	if state.Seq == nil {
+		state.Seq = make(map[string]uint64)
+	}
+	if state.Partial == nil {
+		state.Partial = make(map[string]int)
+	}
+	return state
+}
+
+func saveImportState(path string, state importState) error {
+	data, _ := json.MarshalIndent(state, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ImportOutbox 将外发目录（网关另一侧的接收目录）中的封存批次导入历史存储
+// 只处理已有清单的批次；校验大小和 SHA-256，按序号去重
+// 某主机的批次失败后，本次不再导入该主机的后续批次，避免序号越过失败批次导致重传后被当作已导入
+func ImportOutbox(dir string, store *history.Store) (ImportResult, error) {
+	var result ImportResult
+
+	manifests, err := filepath.Glob(filepath.Join(dir, "*"+manifestSuffix))
+	if err != nil {
+		return result, err
+	}
+
+	var batches []BatchManifest
+	for _, path := range manifests {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			result.Failed = append(result.Failed, filepath.Base(path))
+			continue
+		}
+		var m BatchManifest
+		if err := json.Unmarshal(data, &m); err != nil || m.File == "" {
+			result.Failed = append(result.Failed, filepath.Base(path))
+			continue
+		}
+		batches = append(batches, m)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		if batches[i].Host != batches[j].Host {
+			return batches[i].Host < batches[j].Host
+		}
+		return batches[i].Seq < batches[j].Seq
+	})
+
+	statePath := filepath.Join(store.Dir(), "import_state.json")
+	state := loadImportState(statePath)
+	blocked := make(map[string]bool)
+
+	for _, m := range batches {
+		if blocked[m.Host] {
+			result.Blocked = append(result.Blocked, m.File)
+			continue
+		}
+		last := state.Seq[m.Host]
+		if m.Seq <= last {
+			result.Skipped++
+			continue
+		}
+		if last > 0 && m.Seq != last+1 {
+			result.Missing = append(result.Missing, fmt.Sprintf("%s:%d-%d", m.Host, last+1, m.Seq-1))
+		}
+
+		// 上次写入中途失败的批次跳过已写入的记录，重试不产生重复数据
+		done := state.Partial[m.File]
+		metrics, events, written, err := importBatch(dir, m, store, done)
+		if err != nil {
+			log.Printf("[IMPORT] batch %s rejected: %v", m.File, err)
+			result.Failed = append(result.Failed, m.File)
+			blocked[m.Host] = true
+			if written > done {
+				state.Partial[m.File] = written
+			}
+		} else {
+			delete(state.Partial, m.File)
+			state.Seq[m.Host] = m.Seq
+			result.Batches++
+		}
+		result.Metrics += metrics
+		result.Events += events
+		// 每个批次后保存进度，导入中断后重新执行不会重复写入
+		if err := saveImportState(statePath, state); err != nil {
+			return result, fmt.Errorf("save import state: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// importBatch 校验并导入单个批次，跳过前 skip 条已写入的记录
+// 返回本次写入的指标数、事件数，以及批次中累计已写入的记录数
+func importBatch(dir string, m BatchManifest, store *history.Store, skip int) (metrics, events, written int, err error) {
+	// 清单中的文件名不得包含路径
+	if filepath.Base(m.File) != m.File {
+		return 0, 0, skip, fmt.Errorf("invalid file name %q", m.File)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, m.File))
+	if err != nil {
+		return 0, 0, skip, err
+	}
+	if int64(len(data)) != m.Size {
+		return 0, 0, skip, fmt.Errorf("size mismatch: %d != %d", len(data), m.Size)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != m.SHA256 {
+		return 0, 0, skip, fmt.Errorf("sha256 mismatch")
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if m.Gzip {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return 0, 0, skip, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var records []outboxRecord
+	switch m.Format {
+	case "csv":
+		records, err = decodeCSV(r)
+	case "jsonl":
+		records, err = decodeJSONL(r)
+	default:
+		err = fmt.Errorf("unknown format %q", m.Format)
+	}
+	if err != nil {
+		return 0, 0, skip, err
+	}
+
+	written = skip
+	for ; written < len(records); written++ {
+		rec := records[written]
+		if rec.Metric != nil {
+			if err := store.WriteMetric(*rec.Metric); err != nil {
+				return metrics, events, written, err
+			}
+			metrics++
+		} else if rec.Event != nil {
+			if err := store.WriteEvent(*rec.Event); err != nil {
+				return metrics, events, written, err
+			}
+			events++
+		}
+	}
+	return metrics, events, written, nil
+}
+
+func decodeJSONL(r io.Reader) ([]outboxRecord, error) {
+	var records []outboxRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec outboxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+func decodeCSV(r io.Reader) ([]outboxRecord, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var records []outboxRecord
+	for i, row := range rows {
+		if i == 0 || len(row) < len(csvHeader) {
+			continue
+		}
+		ts, _ := time.Parse(time.RFC3339Nano, row[1])
+		pid, _ := strconv.ParseInt(row[2], 10, 32)
+		switch strings.TrimSpace(row[0]) {
+		case "metric":
+			cpu, _ := strconv.ParseFloat(row[4], 64)
+			rss, _ := strconv.ParseUint(row[5], 10, 64)
+			alive, _ := strconv.ParseBool(row[6])
+			records = append(records, outboxRecord{Kind: "metric", Metric: &types.ProcessMetrics{
+				Timestamp: ts, PID: int32(pid), Name: row[3], CPUPct: cpu, RSSBytes: rss, Alive: alive,
+			}})
+		case "event":
+			records = append(records, outboxRecord{Kind: "event", Event: &types.Event{
+				Timestamp: ts, PID: int32(pid), Name: row[3], Type: row[7], Message: row[8],
+			}})
+		}
+	}
+	return records, nil
+}
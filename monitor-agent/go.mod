@@ -2,10 +2,15 @@ module monitor-agent
 
 go 1.19
 
-require github.com/shirou/gopsutil/v3 v3.23.12
+require (
+	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/shirou/gopsutil/v3 v3.23.12
+	golang.org/x/sys v0.15.0
+)
 
 require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/leoluk/perflib_exporter v0.2.1 // indirect
 	github.com/lufia/plan9stats v0.0.0-20211012122336-39d0f177ccd0 // indirect
 	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
@@ -13,5 +18,6 @@ require (
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.3 // indirect
-	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/net v0.8.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
 )
@@ -0,0 +1,140 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// prefixAlive 按天的存活区间摘要（alive_YYYYMMDD.json），由当天的指标文件生成
+const prefixAlive = "alive"
+
+// AliveRun 进程连续存活的采样区间
+type AliveRun struct {
+	PID    int32     `json:"pid"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Closed bool      `json:"closed,omitempty"` // 以退出采样结束
+}
+
+// AliveRuns 返回 [from, to] 内各进程的存活区间（按开始时间升序）。相邻采样间隔超过 gap 时视为中断。
+// 已结束的日期使用摘要文件，摘要不存在或早于指标文件（如导入了数据）时扫描指标文件后重新生成；
+// 当天只扫描指标文件。
+func (s *Store) AliveRuns(from, to time.Time, gap time.Duration) (map[int32][]AliveRun, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, prefixMetrics+"_*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	today := time.Now().Format(dayLayout)
+
+	result := make(map[int32][]AliveRun)
+	for _, path := range files {
+		day := filepath.Base(path)[len(prefixMetrics)+1 : len(prefixMetrics)+1+len(dayLayout)]
+		if day < from.Format(dayLayout) || day > to.Format(dayLayout) {
+			continue
+		}
+		var runs []AliveRun
+		if day < today {
+			runs = s.daySummary(path, day, gap)
+		} else {
+			runs = scanAliveRuns(path, to, gap)
+		}
+		for _, r := range runs {
+			if r.End.Before(from) || r.Start.After(to) {
+				continue
+			}
+			result[r.PID] = append(result[r.PID], r)
+		}
+	}
+	for pid, runs := range result {
+		result[pid] = mergeAliveRuns(runs, gap)
+	}
+	return result, nil
+}
+
+// daySummary 读取或生成已结束日期的摘要
+func (s *Store) daySummary(metricsPath, day string, gap time.Duration) []AliveRun {
+	path := s.summaryPath(day)
+	if info, err := os.Stat(path); err == nil {
+		if minfo, err := os.Stat(metricsPath); err == nil && !info.ModTime().Before(minfo.ModTime()) {
+			var runs []AliveRun
+			if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &runs) == nil {
+				return runs
+			}
+		}
+	}
+
+	runs := scanAliveRuns(metricsPath, time.Time{}, gap)
+	if data, err := json.Marshal(runs); err == nil {
+		tmp := path + ".tmp"
+		if os.WriteFile(tmp, data, 0644) == nil {
+			os.Rename(tmp, path)
+		}
+	}
+	return runs
+}
+
+// scanAliveRuns 逐行扫描一个指标文件，to 非零时忽略之后的采样
+func scanAliveRuns(path string, to time.Time, gap time.Duration) []AliveRun {
+	open := make(map[int32]*AliveRun)
+	var runs []AliveRun
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	scanLines(f, func(line []byte) {
+		var m types.ProcessMetrics
+		if json.Unmarshal(line, &m) != nil || (!to.IsZero() && m.Timestamp.After(to)) {
+			return
+		}
+		last := open[m.PID]
+		switch {
+		case !m.Alive:
+			if last != nil {
+				last.Closed = true
+				runs = append(runs, *last)
+				delete(open, m.PID)
+			}
+		case last != nil && m.Timestamp.Sub(last.End) <= gap && !m.Timestamp.Before(last.End):
+			last.End = m.Timestamp
+		default:
+			if last != nil {
+				runs = append(runs, *last)
+			}
+			open[m.PID] = &AliveRun{PID: m.PID, Start: m.Timestamp, End: m.Timestamp}
+		}
+	})
+	for _, r := range open {
+		runs = append(runs, *r)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Start.Before(runs[j].Start) })
+	return runs
+}
+
+func (s *Store) summaryPath(day string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%s.json", prefixAlive, day))
+}
+
+// mergeAliveRuns 按开始时间排序，合并跨日期（或导入数据交错）的相邻区间
+func mergeAliveRuns(runs []AliveRun, gap time.Duration) []AliveRun {
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Start.Before(runs[j].Start) })
+	merged := runs[:0]
+	for _, r := range runs {
+		if n := len(merged); n > 0 && !merged[n-1].Closed && r.Start.Sub(merged[n-1].End) <= gap {
+			last := &merged[n-1]
+			if r.End.After(last.End) {
+				last.End, last.Closed = r.End, r.Closed
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
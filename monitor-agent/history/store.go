@@ -0,0 +1,334 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor-agent/types"
+)
+
+const dayLayout = "20060102"
+
+// Query 历史查询条件，零值字段表示不限制
+type Query struct {
+	From  time.Time
+	To    time.Time
+	PID   int32
+	Name  string
+	Names []string // 匹配任一名称（与 Name 同时设置时均需满足）
+	Type  string   // 仅对事件有效
+	Limit int      // 返回最近的 Limit 条
+}
+
+// 各类记录的文件前缀
+const (
+	prefixMetrics = "metrics"
+	prefixEvents  = "events"
+	prefixDisk    = "disk"
+	prefixSystem  = "system"
+)
+
+var prefixes = []string{prefixMetrics, prefixEvents, prefixDisk, prefixSystem}
+
+// Store 历史存储：按天分文件的 JSONL（metrics_YYYYMMDD.jsonl / events_YYYYMMDD.jsonl / disk_YYYYMMDD.jsonl / system_YYYYMMDD.jsonl）
+type Store struct {
+	mu            sync.Mutex
+	dir           string
+	retentionDays int
+	day           string
+	files         map[string]*os.File // 前缀 -> 当天文件
+}
+
+// Open 打开（或创建）历史存储目录
+func Open(dir string, retentionDays int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+	if retentionDays <= 0 {
+		retentionDays = 30
+	}
+	return &Store{dir: dir, retentionDays: retentionDays}, nil
+}
+
+// Dir 返回存储目录
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// WriteMetric 写入一条进程指标
+func (s *Store) WriteMetric(m types.ProcessMetrics) error {
+	return s.write(m.Timestamp, prefixMetrics, m)
+}
+
+// WriteEvent 写入一条事件
+func (s *Store) WriteEvent(e types.Event) error {
+	return s.write(e.Timestamp, prefixEvents, e)
+}
+
+// WriteDisk 写入一次磁盘采样
+func (s *Store) WriteDisk(d types.DiskMetrics) error {
+	return s.write(d.Timestamp, prefixDisk, d)
+}
+
+// WriteSystem 写入一次系统指标采样
+func (s *Store) WriteSystem(m types.SystemMetrics) error {
+	return s.write(m.Timestamp, prefixSystem, m)
+}
+
+func (s *Store) write(ts time.Time, prefix string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := ts.Format(dayLayout)
+	// 导入的历史数据可能不属于当天，直接追加到对应日期文件
+	if day != time.Now().Format(dayLayout) {
+		f, err := os.OpenFile(s.path(prefix, day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.Write(append(data, '\n'))
+		return err
+	}
+
+	f, err := s.fileLocked(prefix, day)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// fileLocked 返回当天的文件，日期变化时关闭旧文件并清理过期文件
+func (s *Store) fileLocked(prefix, day string) (*os.File, error) {
+	if s.day != day {
+		s.closeLocked()
+		s.day = day
+		s.cleanupLocked()
+	}
+	if f, ok := s.files[prefix]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(s.path(prefix, day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if s.files == nil {
+		s.files = make(map[string]*os.File)
+	}
+	s.files[prefix] = f
+	return f, nil
+}
+
+// cleanupLocked 删除超过保留天数的文件
+func (s *Store) cleanupLocked() {
+	cutoff := time.Now().AddDate(0, 0, -s.retentionDays).Format(dayLayout)
+	for _, prefix := range prefixes {
+		files, _ := filepath.Glob(filepath.Join(s.dir, prefix+"_*.jsonl"))
+		for _, f := range files {
+			day := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), prefix+"_"), ".jsonl")
+			if day < cutoff {
+				os.Remove(f)
+			}
+		}
+	}
+	summaries, _ := filepath.Glob(filepath.Join(s.dir, prefixAlive+"_*.json"))
+	for _, f := range summaries {
+		day := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), prefixAlive+"_"), ".json")
+		if day < cutoff {
+			os.Remove(f)
+		}
+	}
+}
+
+func (s *Store) path(prefix, day string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%s.jsonl", prefix, day))
+}
+
+// QueryMetrics 查询历史指标（按时间升序）
+func (s *Store) QueryMetrics(q Query) ([]types.ProcessMetrics, error) {
+	var result []types.ProcessMetrics
+	err := s.scan(prefixMetrics, q, func(line []byte) {
+		var m types.ProcessMetrics
+		if json.Unmarshal(line, &m) != nil || !q.match(m.Timestamp, m.PID, m.Name) {
+			return
+		}
+		result = append(result, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result, nil
+}
+
+// ScanMetrics 按文件顺序逐条回调历史指标，不在内存中保留全部结果（用于长周期统计）
+func (s *Store) ScanMetrics(q Query, fn func(m types.ProcessMetrics)) error {
+	return s.scan(prefixMetrics, q, func(line []byte) {
+		var m types.ProcessMetrics
+		if json.Unmarshal(line, &m) != nil || !q.match(m.Timestamp, m.PID, m.Name) {
+			return
+		}
+		fn(m)
+	})
+}
+
+// QueryEvents 查询历史事件（按时间升序）
+func (s *Store) QueryEvents(q Query) ([]types.Event, error) {
+	var result []types.Event
+	err := s.scan(prefixEvents, q, func(line []byte) {
+		var e types.Event
+		if json.Unmarshal(line, &e) != nil || !q.match(e.Timestamp, e.PID, e.Name) {
+			return
+		}
+		if q.Type != "" && e.Type != q.Type {
+			return
+		}
+		result = append(result, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result, nil
+}
+
+// QueryDisk 查询磁盘采样（按时间升序），只使用时间范围和 Limit
+func (s *Store) QueryDisk(q Query) ([]types.DiskMetrics, error) {
+	var result []types.DiskMetrics
+	err := s.scan(prefixDisk, q, func(line []byte) {
+		var d types.DiskMetrics
+		if json.Unmarshal(line, &d) != nil || !q.match(d.Timestamp, q.PID, q.Name) {
+			return
+		}
+		result = append(result, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result, nil
+}
+
+// QuerySystem 查询系统指标（按时间升序），只使用时间范围和 Limit
+func (s *Store) QuerySystem(q Query) ([]types.SystemMetrics, error) {
+	var result []types.SystemMetrics
+	err := s.scan(prefixSystem, q, func(line []byte) {
+		var m types.SystemMetrics
+		if json.Unmarshal(line, &m) != nil || !q.match(m.Timestamp, q.PID, q.Name) {
+			return
+		}
+		result = append(result, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result, nil
+}
+
+// scan 逐行读取查询时间范围内的日期文件
+func (s *Store) scan(prefix string, q Query, fn func(line []byte)) error {
+	files, err := filepath.Glob(filepath.Join(s.dir, prefix+"_*.jsonl"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		day := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix+"_"), ".jsonl")
+		if !q.From.IsZero() && day < q.From.Format(dayLayout) {
+			continue
+		}
+		if !q.To.IsZero() && day > q.To.Format(dayLayout) {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanLines(f, fn)
+		f.Close()
+	}
+	return nil
+}
+
+func scanLines(r io.Reader, fn func(line []byte)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
+}
+
+func (q Query) match(ts time.Time, pid int32, name string) bool {
+	if !q.From.IsZero() && ts.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && ts.After(q.To) {
+		return false
+	}
+	if q.PID != 0 && pid != q.PID {
+		return false
+	}
+	if q.Name != "" && name != q.Name {
+		return false
+	}
+	if len(q.Names) > 0 {
+		found := false
+		for _, n := range q.Names {
+			if n == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Close 关闭当前打开的文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+	return nil
+}
+
+func (s *Store) closeLocked() {
+	for prefix, f := range s.files {
+		f.Close()
+		delete(s.files, prefix)
+	}
+	s.day = ""
+}
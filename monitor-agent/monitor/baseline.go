@@ -0,0 +1,347 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"monitor-agent/history"
+	"monitor-agent/types"
+)
+
+const hoursPerWeek = 7 * 24
+
+// baselineMetrics 支持的基线指标及最小标准差（避免方差过小导致误报）
+var baselineMetrics = map[string]struct {
+	value  func(m types.ProcessMetrics) float64
+	minStd float64
+	format func(v float64) string
+}{
+	"cpu": {func(m types.ProcessMetrics) float64 { return m.CPUPct }, 1,
+		func(v float64) string { return fmt.Sprintf("%.2f%%", v) }},
+	"rss": {func(m types.ProcessMetrics) float64 { return float64(m.RSSBytes) }, 4 * 1024 * 1024,
+		func(v float64) string { return fmt.Sprintf("%.1f MB", v/1024/1024) }},
+	"io": {func(m types.ProcessMetrics) float64 { return m.IOReadRate + m.IOWriteRate }, 64 * 1024,
+		func(v float64) string { return fmt.Sprintf("%.1f KB/s", v/1024) }},
+}
+
+// bucketStat Welford 在线均值/方差，并记录样本覆盖的周数
+type bucketStat struct {
+	N     int64   `json:"n"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+	Weeks int     `json:"weeks"` // 有样本的周数
+	Week  int64   `json:"week"`  // 最近样本所在的周序号
+}
+
+func (b *bucketStat) add(v float64, t time.Time) {
+	if w := weekIndex(t); w != b.Week {
+		b.Week = w
+		b.Weeks++
+	}
+	b.N++
+	d := v - b.Mean
+	b.Mean += d / float64(b.N)
+	b.M2 += d * (v - b.Mean)
+}
+
+func (b *bucketStat) std() float64 {
+	if b.N < 2 {
+		return 0
+	}
+	return math.Sqrt(b.M2 / float64(b.N-1))
+}
+
+// baselineModel 单个目标（按名称）的基线模型
+type baselineModel struct {
+	UpdatedAt time.Time                            `json:"updated_at"`
+	Metrics   map[string]*[hoursPerWeek]bucketStat `json:"metrics"`
+}
+
+// baselineStore 基线模型集合，持久化到 LogDir/baselines.json
+type baselineStore struct {
+	mu       sync.Mutex
+	path     string
+	models   map[string]*baselineModel
+	loading  map[string]bool // 正在从历史存储回溯学习的目标，完成前不做异常判定
+	dirty    bool
+	lastSave time.Time
+}
+
+// baselineState 目标的异常判定状态（仅由采样协程访问）
+type baselineState struct {
+	exceed    map[string]int
+	anomalous map[string]bool
+}
+
+func loadBaselineStore(path string) *baselineStore {
+	bs := &baselineStore{path: path, models: make(map[string]*baselineModel), loading: make(map[string]bool), lastSave: time.Now()}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return bs
+	}
+	if err := json.Unmarshal(data, &bs.models); err != nil {
+		log.Printf("[WARN] 基线文件解析失败 %s: %v", path, err)
+		bs.models = make(map[string]*baselineModel)
+	}
+	return bs
+}
+
+// save 写入临时文件后 rename，避免写入中断导致模型损坏
+func (bs *baselineStore) save(force bool) {
+	bs.mu.Lock()
+	if !bs.dirty || (!force && time.Since(bs.lastSave) < 5*time.Minute) {
+		bs.mu.Unlock()
+		return
+	}
+	data, err := json.Marshal(bs.models)
+	bs.dirty = false
+	bs.lastSave = time.Now()
+	bs.mu.Unlock()
+	if err != nil {
+		return
+	}
+	tmp := bs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("[WARN] 保存基线失败: %v", err)
+		return
+	}
+	os.Rename(tmp, bs.path)
+}
+
+func hourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// weekIndex 本地时间自 1970-01-04（周日）起的周序号，与 hourOfWeek 的周边界一致
+func weekIndex(t time.Time) int64 {
+	_, offset := t.Zone()
+	return (t.Unix() + int64(offset) - 3*86400) / (7 * 86400)
+}
+
+func baselineDefaults(cfg types.BaselineCheck) types.BaselineCheck {
+	if len(cfg.Metrics) == 0 {
+		cfg.Metrics = []string{"cpu", "rss", "io"}
+	}
+	if cfg.Sigma <= 0 {
+		cfg.Sigma = 3
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 300
+	}
+	if cfg.MinWeeks <= 0 {
+		cfg.MinWeeks = 2
+	}
+	if cfg.ExceedCount <= 0 {
+		cfg.ExceedCount = 5
+	}
+	if cfg.LearnDays <= 0 {
+		cfg.LearnDays = 7
+	}
+	return cfg
+}
+
+// checkBaseline 对比当前值与所在小时分桶的基线，偏离超过 sigma 倍标准差时告警；正常样本继续学习
+func (m *MultiMonitor) checkBaseline(state *targetState, target types.MonitorTarget, metric types.ProcessMetrics) {
+	if target.Baseline == nil {
+		return
+	}
+	cfg := baselineDefaults(*target.Baseline)
+	bs := m.baselines
+
+	bs.mu.Lock()
+	model, ok := bs.models[target.Name]
+	if !ok {
+		// 新目标在后台从历史存储回溯学习，不阻塞采样，完成前跳过判定
+		if !bs.loading[target.Name] {
+			bs.loading[target.Name] = true
+			go m.bootstrapBaseline(target.Name, metric.Name, cfg.LearnDays)
+		}
+		bs.mu.Unlock()
+		return
+	}
+	bs.mu.Unlock()
+
+	m.mu.Lock()
+	st := state.baseline
+	if st == nil {
+		st = &baselineState{exceed: make(map[string]int), anomalous: make(map[string]bool)}
+		state.baseline = st
+	}
+	m.mu.Unlock()
+
+	how := hourOfWeek(metric.Timestamp)
+	var events []types.Event
+
+	bs.mu.Lock()
+	for _, name := range cfg.Metrics {
+		def, ok := baselineMetrics[name]
+		if !ok {
+			continue
+		}
+		buckets := model.Metrics[name]
+		if buckets == nil {
+			buckets = new([hoursPerWeek]bucketStat)
+			model.Metrics[name] = buckets
+		}
+		b := &buckets[how]
+		v := def.value(metric)
+
+		deviating := false
+		var dev float64
+		std := math.Max(b.std(), def.minStd)
+		// 分桶需覆盖多周的数据，单日的少量样本不足以代表该时段
+		if b.N >= int64(cfg.MinSamples) && b.Weeks >= cfg.MinWeeks {
+			dev = (v - b.Mean) / std
+			deviating = math.Abs(dev) > cfg.Sigma
+		}
+
+		if deviating {
+			st.exceed[name]++
+		} else {
+			st.exceed[name] = 0
+			st.anomalous[name] = false
+		}
+		if deviating && st.exceed[name] >= cfg.ExceedCount && !st.anomalous[name] {
+			st.anomalous[name] = true
+			events = append(events, types.Event{
+				Timestamp: metric.Timestamp,
+				Type:      "anomaly",
+				PID:       target.PID,
+				Name:      target.Name,
+				Message: fmt.Sprintf("%s 当前 %s 偏离基线 %s ± %s（%.1fσ，周%d %02d时）", name, def.format(v),
+					def.format(b.Mean), def.format(std), dev, how/24, how%24),
+				Details: map[string]interface{}{
+					"metric":       name,
+					"value":        v,
+					"mean":         b.Mean,
+					"std":          std,
+					"sigma":        dev,
+					"hour_of_week": how,
+				},
+			})
+		}
+
+		// 偏离样本不参与学习，避免异常逐渐被当作正常
+		if !deviating {
+			b.add(v, metric.Timestamp)
+			model.UpdatedAt = metric.Timestamp
+			bs.dirty = true
+		}
+	}
+	bs.mu.Unlock()
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+}
+
+// bootstrapBaseline 新目标首次建模时，从历史存储逐条回溯学习（后台运行，完成后清除 loading 标记）
+// 模型按目标名称保存；历史指标按采样记录的进程名 procName 查询（目标名称可能是自定义的）
+func (m *MultiMonitor) bootstrapBaseline(name, procName string, days int) {
+	model := &baselineModel{Metrics: make(map[string]*[hoursPerWeek]bucketStat)}
+	var n int
+	if procName != "" {
+		m.history.ScanMetrics(history.Query{Name: procName, From: time.Now().AddDate(0, 0, -days)}, func(h types.ProcessMetrics) {
+			if !h.Alive {
+				return
+			}
+			how := hourOfWeek(h.Timestamp)
+			for metric, def := range baselineMetrics {
+				buckets := model.Metrics[metric]
+				if buckets == nil {
+					buckets = new([hoursPerWeek]bucketStat)
+					model.Metrics[metric] = buckets
+				}
+				buckets[how].add(def.value(h), h.Timestamp)
+			}
+			if h.Timestamp.After(model.UpdatedAt) {
+				model.UpdatedAt = h.Timestamp
+			}
+			n++
+		})
+	}
+	if n > 0 {
+		log.Printf("[INFO] 基线 %s 从历史存储学习 %d 个样本", name, n)
+	}
+
+	m.baselines.mu.Lock()
+	defer m.baselines.mu.Unlock()
+	delete(m.baselines.loading, name)
+	if _, ok := m.baselines.models[name]; ok {
+		return
+	}
+	m.baselines.models[name] = model
+	m.baselines.dirty = true
+}
+
+// GetBaseline 获取目标基线（按目标名称）
+func (m *MultiMonitor) GetBaseline(name string) *types.BaselineInfo {
+	bs := m.baselines
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	model, ok := bs.models[name]
+	if !ok {
+		return nil
+	}
+	info := &types.BaselineInfo{Name: name, UpdatedAt: model.UpdatedAt, Metrics: make(map[string][]types.BaselineBucket)}
+	for metric, buckets := range model.Metrics {
+		list := make([]types.BaselineBucket, 0, hoursPerWeek)
+		for how := range buckets {
+			b := buckets[how]
+			list = append(list, types.BaselineBucket{HourOfWeek: how, N: b.N, Weeks: b.Weeks, Mean: b.Mean, Std: b.std()})
+		}
+		info.Metrics[metric] = list
+	}
+	return info
+}
+
+// ListBaselines 列出已学习基线的目标名称
+func (m *MultiMonitor) ListBaselines() []string {
+	bs := m.baselines
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	names := make([]string, 0, len(bs.models))
+	for name := range bs.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResetBaseline 清除目标基线（metric 为空时清除全部指标），之后重新从零学习
+func (m *MultiMonitor) ResetBaseline(name, metric string) error {
+	bs := m.baselines
+	bs.mu.Lock()
+	model, ok := bs.models[name]
+	if !ok {
+		bs.mu.Unlock()
+		return fmt.Errorf("baseline %s not found", name)
+	}
+	if metric == "" {
+		// 保留空模型，避免再次从历史存储回溯学习
+		model.Metrics = make(map[string]*[hoursPerWeek]bucketStat)
+	} else {
+		delete(model.Metrics, metric)
+	}
+	model.UpdatedAt = time.Now()
+	bs.dirty = true
+	bs.mu.Unlock()
+
+	m.mu.Lock()
+	for _, state := range m.targets {
+		if state.target.Name == name {
+			state.baseline = nil
+		}
+	}
+	m.mu.Unlock()
+
+	bs.save(true)
+	log.Printf("[INFO] 基线已重置: %s %s", name, metric)
+	return nil
+}
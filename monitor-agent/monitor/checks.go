@@ -0,0 +1,342 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor-agent/buffer"
+	"monitor-agent/types"
+)
+
+// Nagios 插件退出码对应的状态
+var checkStates = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// execCheck 外部检查运行状态
+type execCheck struct {
+	cfg  types.ExecCheck
+	stop chan struct{}
+	run  chan struct{} // 立即执行请求
+
+	mu      sync.Mutex
+	status  types.CheckStatus
+	metrics *buffer.RingBuffer[types.ProcessMetrics]
+}
+
+func checkDefaults(cfg types.ExecCheck) types.ExecCheck {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 60
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+	return cfg
+}
+
+// checkTargetName 检查作为监控目标展示时的名称（指标、事件和历史记录中使用）
+func checkTargetName(name string) string {
+	return "check:" + name
+}
+
+// loadChecks 加载检查定义文件
+func (m *MultiMonitor) loadChecks(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("[ERROR] 外部检查: 读取 %s 失败: %v", path, err)
+		return
+	}
+	var checks []types.ExecCheck
+	if err := json.Unmarshal(data, &checks); err != nil {
+		log.Printf("[ERROR] 外部检查: 解析 %s 失败: %v", path, err)
+		return
+	}
+	for _, c := range checks {
+		if err := m.AddCheck(c); err != nil {
+			log.Printf("[ERROR] 外部检查: %v", err)
+		}
+	}
+}
+
+// AddCheck 添加外部检查并开始调度
+func (m *MultiMonitor) AddCheck(cfg types.ExecCheck) error {
+	if cfg.Name == "" || cfg.Command == "" {
+		return fmt.Errorf("check requires name and command")
+	}
+	cfg = checkDefaults(cfg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checks == nil {
+		m.checks = make(map[string]*execCheck)
+	}
+	if _, exists := m.checks[cfg.Name]; exists {
+		return fmt.Errorf("check %s already exists", cfg.Name)
+	}
+	c := &execCheck{
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+		run:     make(chan struct{}, 1),
+		status:  types.CheckStatus{Check: cfg, State: "PENDING", ExitCode: -1},
+		metrics: buffer.NewRingBuffer[types.ProcessMetrics](m.config.MetricsBufferLen),
+	}
+	m.checks[cfg.Name] = c
+	go m.checkLoop(c)
+	log.Printf("[INFO] Added check: %s (interval=%ds timeout=%ds)", cfg.Name, cfg.Interval, cfg.Timeout)
+	return nil
+}
+
+// RemoveCheck 移除外部检查
+func (m *MultiMonitor) RemoveCheck(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.checks[name]
+	if !ok {
+		return fmt.Errorf("check %s not found", name)
+	}
+	close(c.stop)
+	delete(m.checks, name)
+	log.Printf("[INFO] Removed check: %s", name)
+	return nil
+}
+
+// RunCheck 立即执行一次检查（不等待结果）
+func (m *MultiMonitor) RunCheck(name string) error {
+	m.mu.RLock()
+	c, ok := m.checks[name]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("check %s not found", name)
+	}
+	select {
+	case c.run <- struct{}{}:
+	default: // 已有待执行的请求
+	}
+	return nil
+}
+
+// GetChecks 获取所有外部检查状态（按名称排序）
+func (m *MultiMonitor) GetChecks() []types.CheckStatus {
+	m.mu.RLock()
+	checks := make([]*execCheck, 0, len(m.checks))
+	for _, c := range m.checks {
+		checks = append(checks, c)
+	}
+	m.mu.RUnlock()
+
+	result := make([]types.CheckStatus, 0, len(checks))
+	for _, c := range checks {
+		c.mu.Lock()
+		result = append(result, c.status)
+		c.mu.Unlock()
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Check.Name < result[j].Check.Name })
+	return result
+}
+
+// GetCheckTargets 外部检查作为监控目标展示：名称 check:<检查名>，附带状态和最近的性能数据（按名称排序）
+func (m *MultiMonitor) GetCheckTargets() []types.MonitorTarget {
+	checks := m.GetChecks()
+	result := make([]types.MonitorTarget, 0, len(checks))
+	for i := range checks {
+		result = append(result, types.MonitorTarget{
+			Name:  checkTargetName(checks[i].Check.Name),
+			Alias: checks[i].Check.Name,
+			Check: &checks[i],
+		})
+	}
+	return result
+}
+
+// GetCheckMetrics 获取检查最近 n 次执行的指标（性能数据在 custom 字段）
+func (m *MultiMonitor) GetCheckMetrics(name string, n int) ([]types.ProcessMetrics, error) {
+	m.mu.RLock()
+	c, ok := m.checks[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("check %s not found", name)
+	}
+	return c.metrics.GetRecent(n), nil
+}
+
+func (m *MultiMonitor) checkLoop(c *execCheck) {
+	ticker := time.NewTicker(time.Duration(c.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	first := time.After(time.Second) // 添加后尽快执行首次检查
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-first:
+		case <-ticker.C:
+		case <-c.run:
+		}
+		if m.IsRunning() {
+			m.runCheck(c)
+		}
+	}
+}
+
+// runCheck 在并发上限内执行检查，记录结果和指标，状态变化时产生 check_state 事件
+func (m *MultiMonitor) runCheck(c *execCheck) {
+	select {
+	case m.checkSlots <- struct{}{}:
+	case <-c.stop:
+		return
+	}
+	start := time.Now()
+	code, output, err := runCommand(c.cfg.Command, time.Duration(c.cfg.Timeout)*time.Second)
+	duration := time.Since(start)
+	<-m.checkSlots
+
+	text, long, perf := parsePluginOutput(output)
+	if err != nil {
+		code = 3
+		if errors.Is(err, errCommandTimeout) {
+			text = fmt.Sprintf("检查超时（%d 秒）", c.cfg.Timeout)
+		} else {
+			text = fmt.Sprintf("检查执行失败: %v", err)
+		}
+	}
+	state := "UNKNOWN"
+	if code >= 0 && code < len(checkStates) {
+		state = checkStates[code]
+	}
+
+	now := time.Now()
+	name := checkTargetName(c.cfg.Name)
+	metric := types.ProcessMetrics{
+		Timestamp: now,
+		Name:      name,
+		Alive:     state == "OK" || state == "WARNING",
+	}
+	if len(perf) > 0 {
+		metric.Custom = make(map[string]float64, len(perf))
+		for _, p := range perf {
+			metric.Custom[p.Label] = p.Value
+		}
+	}
+
+	c.mu.Lock()
+	prev := c.status.State
+	c.status.State, c.status.ExitCode = state, code
+	c.status.Output, c.status.LongOutput, c.status.PerfData = text, long, perf
+	c.status.LastRun, c.status.Duration = now, duration.Seconds()
+	c.status.Runs++
+	if prev != state {
+		c.status.LastChange = now
+	}
+	c.mu.Unlock()
+	c.metrics.Push(metric)
+
+	m.history.WriteMetric(metric)
+	target := types.MonitorTarget{Name: name, Alias: c.cfg.Name}
+	for _, sink := range m.getSinks() {
+		sink.OnMetric(target, metric)
+	}
+
+	// 首次执行结果为 OK 时不产生事件
+	if prev == state || (prev == "PENDING" && state == "OK") {
+		return
+	}
+	m.addEvent(types.Event{
+		Timestamp: now,
+		Type:      "check_state",
+		Name:      name,
+		Message:   fmt.Sprintf("检查 %s 状态 %s -> %s: %s", c.cfg.Name, prev, state, text),
+		Details: map[string]interface{}{
+			"check":     c.cfg.Name,
+			"from":      prev,
+			"to":        state,
+			"exit_code": code,
+			"output":    text,
+		},
+	})
+}
+
+// parsePluginOutput 解析插件输出：第一行 "文本|性能数据"，其余行为长输出，其中 | 之后也是性能数据
+func parsePluginOutput(output string) (string, string, []types.PerfData) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	text, perf, _ := strings.Cut(lines[0], "|")
+	var long []string
+	inPerf := false
+	for _, line := range lines[1:] {
+		if inPerf {
+			perf += " " + line
+			continue
+		}
+		before, after, ok := strings.Cut(line, "|")
+		long = append(long, before)
+		if ok {
+			perf += " " + after
+			inPerf = true
+		}
+	}
+	return strings.TrimSpace(text), strings.TrimSpace(strings.Join(long, "\n")), parsePerfData(perf)
+}
+
+// parsePerfData 解析性能数据，标签可用单引号包含空格，值为 U（无法确定）或无法解析的项忽略
+func parsePerfData(s string) []types.PerfData {
+	var result []types.PerfData
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var label string
+		if strings.HasPrefix(s, "'") {
+			end := strings.Index(s[1:], "'=")
+			if end < 0 {
+				break
+			}
+			label, s = s[1:end+1], s[end+3:]
+		} else {
+			eq := strings.Index(s, "=")
+			if eq < 0 {
+				break
+			}
+			label, s = s[:eq], s[eq+1:]
+		}
+		field := s
+		if sp := strings.IndexAny(s, " \t"); sp >= 0 {
+			field, s = s[:sp], strings.TrimSpace(s[sp:])
+		} else {
+			s = ""
+		}
+		if p, ok := parsePerfField(label, field); ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func parsePerfField(label, field string) (types.PerfData, bool) {
+	parts := strings.Split(field, ";")
+	value := parts[0]
+	unit := strings.TrimLeft(value, "0123456789.-+eE") // 单位（s、ms、%、B、KB、c 等）不以 e 开头
+	v, err := strconv.ParseFloat(value[:len(value)-len(unit)], 64)
+	if label == "" || err != nil {
+		return types.PerfData{}, false
+	}
+	p := types.PerfData{Label: label, Value: v, Unit: unit}
+	if len(parts) > 1 {
+		p.Warn = parts[1]
+	}
+	if len(parts) > 2 {
+		p.Crit = parts[2]
+	}
+	if len(parts) > 3 {
+		if f, err := strconv.ParseFloat(parts[3], 64); err == nil {
+			p.Min = &f
+		}
+	}
+	if len(parts) > 4 {
+		if f, err := strconv.ParseFloat(parts[4], 64); err == nil {
+			p.Max = &f
+		}
+	}
+	return p, true
+}
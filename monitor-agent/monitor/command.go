@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCommandOutput 命令输出最多保留的字节数
+const maxCommandOutput = 8192
+
+// errCommandTimeout 命令执行超时
+var errCommandTimeout = errors.New("command timeout")
+
+// outputBuffer 命令输出缓冲：超时返回时 exec 的复制协程可能仍在写入，读写加锁；超出上限的输出丢弃
+type outputBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := maxCommandOutput - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	// 总是报告全部写入，避免输出过多的命令因管道关闭而失败
+	return len(p), nil
+}
+
+func (b *outputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// runCommand 通过系统 shell 同步执行命令，超时后结束整个进程树。
+// 返回退出码（无法获取时为 -1）、合并后的标准输出/错误输出
+func runCommand(command string, timeout time.Duration) (int, string, error) {
+	cmd := shellCommand(command)
+	out := &outputBuffer{}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		return -1, "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		killCommandTree(cmd)
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second): // 子进程仍持有输出管道时不再等待
+		}
+		return -1, truncateOutput(out.String()), fmt.Errorf("%w after %s", errCommandTimeout, timeout)
+	}
+
+	output := truncateOutput(out.String())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), output, nil
+	}
+	if err != nil {
+		return -1, output, err
+	}
+	return 0, output, nil
+}
+
+func truncateOutput(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxCommandOutput {
+		s = s[:maxCommandOutput]
+	}
+	return s
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand 使用 sh -c 执行，放入独立进程组以便超时后整体结束
+func shellCommand(command string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func killCommandTree(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package monitor
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// shellCommand 使用 cmd /C 执行
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
+
+// killCommandTree 使用 taskkill /T 结束命令及其子进程
+func killCommandTree(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
@@ -0,0 +1,534 @@
+package monitor
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"monitor-agent/types"
+)
+
+// coreLocation core 文件所在目录及文件名规则
+type coreLocation struct {
+	dir     string
+	pattern *regexp.Regexp // 命名分组：pid / comm / exe / sig
+}
+
+// genericCorePattern 额外目录中按 core、core.PID 命名的文件
+var genericCorePattern = regexp.MustCompile(`^core(?:\.(?P<pid>\d+))?$`)
+
+// corePID 监控目标使用过的 PID（目标重启或退出后仍保留一段时间，用于关联延迟写完的 core）
+type corePID struct {
+	name   string
+	cwd    string
+	seen   time.Time
+	exited time.Time
+}
+
+// coreWatcher core 文件检测器
+type coreWatcher struct {
+	cfg     types.CoreDumpConfig
+	path    string // 检测记录持久化文件
+	mu      sync.Mutex
+	pids    map[int32]*corePID
+	seen    map[string]bool  // 已处理（或启动时已存在）的文件
+	pending map[string]int64 // 等待写完的文件 -> 上次扫描大小
+	records []types.CoreDump
+	started time.Time // 早于启动时间的文件不报告
+}
+
+const (
+	corePIDRetention = time.Hour
+	coreMaxRecords   = 500
+)
+
+func newCoreWatcher(cfg types.CoreDumpConfig, logDir string) *coreWatcher {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30
+	}
+	if cfg.ArchiveMaxBytes <= 0 {
+		cfg.ArchiveMaxBytes = 10 << 30
+	}
+	if cfg.ArchiveRetentionDays <= 0 {
+		cfg.ArchiveRetentionDays = 30
+	}
+	cw := &coreWatcher{
+		cfg:     cfg,
+		path:    filepath.Join(logDir, "cores.json"),
+		pids:    make(map[int32]*corePID),
+		seen:    make(map[string]bool),
+		pending: make(map[string]int64),
+		started: time.Now(),
+	}
+	if data, err := os.ReadFile(cw.path); err == nil {
+		json.Unmarshal(data, &cw.records)
+	}
+	for _, r := range cw.records {
+		cw.seen[r.OriginalPath] = true
+	}
+	return cw
+}
+
+// watchCoreDumps 周期扫描 core 目录
+func (m *MultiMonitor) watchCoreDumps(stop <-chan struct{}) {
+	cw := m.cores
+	ticker := time.NewTicker(time.Duration(cw.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	m.scanCoreDumps()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.scanCoreDumps()
+		}
+	}
+}
+
+// noteExit 记录目标退出时的 PID（托管进程重启后 PID 会立即变化）
+func (cw *coreWatcher) noteExit(pid int32, name string) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	p, ok := cw.pids[pid]
+	if !ok {
+		p = &corePID{name: name}
+		cw.pids[pid] = p
+	}
+	p.seen = time.Now()
+	p.exited = time.Now()
+}
+
+// scanCoreDumps 扫描 core 目录，文件大小稳定（两次扫描不变）后视为写完并处理
+func (m *MultiMonitor) scanCoreDumps() {
+	cw := m.cores
+	now := time.Now()
+
+	// 更新目标 PID 与工作目录（core_pattern 为相对路径时 core 写入进程工作目录）
+	m.mu.RLock()
+	current := make(map[int32]string, len(m.targets))
+	for pid, state := range m.targets {
+		current[pid] = state.target.Name
+	}
+	m.mu.RUnlock()
+
+	cw.mu.Lock()
+	for pid, name := range current {
+		p, ok := cw.pids[pid]
+		if !ok {
+			p = &corePID{name: name}
+			cw.pids[pid] = p
+			if proc, err := process.NewProcess(pid); err == nil {
+				p.cwd, _ = proc.Cwd()
+			}
+		}
+		p.seen = now
+	}
+	for pid, p := range cw.pids {
+		if now.Sub(p.seen) > corePIDRetention {
+			delete(cw.pids, pid)
+		}
+	}
+	var cwds []string
+	for _, p := range cw.pids {
+		if p.cwd != "" {
+			cwds = append(cwds, p.cwd)
+		}
+	}
+	cw.mu.Unlock()
+
+	// 同一目录可能对应多条规则，按目录合并后每个文件只匹配一次
+	var dirs []string
+	patterns := make(map[string][]*regexp.Regexp)
+	for _, loc := range coreLocations(cwds) {
+		if _, ok := patterns[loc.dir]; !ok {
+			dirs = append(dirs, loc.dir)
+		}
+		patterns[loc.dir] = append(patterns[loc.dir], loc.pattern)
+	}
+	for _, dir := range cw.cfg.Dirs {
+		if _, ok := patterns[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		patterns[dir] = append(patterns[dir], genericCorePattern)
+	}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			var pattern *regexp.Regexp
+			var match []string
+			for _, re := range patterns[dir] {
+				if match = re.FindStringSubmatch(e.Name()); match != nil {
+					pattern = re
+					break
+				}
+			}
+			if match == nil {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+
+			cw.mu.Lock()
+			if cw.seen[path] {
+				cw.mu.Unlock()
+				continue
+			}
+			if info.ModTime().Before(cw.started) {
+				// 启动前已存在的文件不报告
+				cw.seen[path] = true
+				cw.mu.Unlock()
+				continue
+			}
+			last, waiting := cw.pending[path]
+			if !waiting || last != info.Size() {
+				cw.pending[path] = info.Size()
+				cw.mu.Unlock()
+				continue
+			}
+			delete(cw.pending, path)
+			cw.seen[path] = true
+			cw.mu.Unlock()
+
+			groups := make(map[string]string)
+			for i, name := range pattern.SubexpNames() {
+				if name != "" && match[i] != "" {
+					groups[name] = match[i]
+				}
+			}
+			m.handleCoreFile(path, info, groups)
+		}
+	}
+	cw.pruneSeen()
+	cw.cleanupArchive()
+}
+
+// pruneSeen 清除已不存在（被删除或归档移走）的文件记录，避免记录无限增长
+func (cw *coreWatcher) pruneSeen() {
+	cw.mu.Lock()
+	paths := make([]string, 0, len(cw.seen)+len(cw.pending))
+	for path := range cw.seen {
+		paths = append(paths, path)
+	}
+	for path := range cw.pending {
+		paths = append(paths, path)
+	}
+	cw.mu.Unlock()
+
+	var gone []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			gone = append(gone, path)
+		}
+	}
+
+	cw.mu.Lock()
+	for _, path := range gone {
+		delete(cw.seen, path)
+		delete(cw.pending, path)
+	}
+	cw.mu.Unlock()
+}
+
+// handleCoreFile 关联 core 文件到监控目标，产生事件并按配置归档
+func (m *MultiMonitor) handleCoreFile(path string, info os.FileInfo, groups map[string]string) {
+	cw := m.cores
+	rec := types.CoreDump{Path: path, OriginalPath: path, Size: info.Size(), Time: info.ModTime()}
+
+	if v, err := strconv.ParseInt(groups["pid"], 10, 32); err == nil {
+		rec.PID = int32(v)
+	}
+	rec.Exe = groups["comm"]
+	if exe := groups["exe"]; exe != "" {
+		rec.Exe = strings.ReplaceAll(exe, "!", "/") // %E 中的路径分隔符为 !
+	}
+	if exe := groups["apport"]; exe != "" {
+		rec.Exe = strings.ReplaceAll(exe, "_", "/")
+	}
+	if v, err := strconv.Atoi(groups["sig"]); err == nil {
+		rec.Signal = signalName(v)
+	}
+	// 文件名未包含 PID 时尝试读取 ELF core 的进程信息
+	if rec.PID == 0 {
+		if pid, fname, ok := readCorePrpsinfo(path); ok {
+			rec.PID, rec.Exe = pid, fname
+		}
+	}
+
+	rec.Name = cw.matchTarget(rec.PID, rec.Exe)
+	if rec.Name == "" {
+		return
+	}
+
+	if cw.cfg.ArchiveDir != "" {
+		// 文件名可能不含 PID（如 core），归档时加上时间和 PID 避免覆盖
+		dst := filepath.Join(cw.cfg.ArchiveDir, safeFileName(rec.Name),
+			fmt.Sprintf("%s_%d_%s", rec.Time.Format("20060102_150405"), rec.PID, filepath.Base(path)))
+		if err := archiveCore(path, dst); err != nil {
+			log.Printf("[WARN] 归档 core 文件失败 %s: %v", path, err)
+		} else {
+			rec.Path, rec.Archived = dst, true
+		}
+	}
+
+	cw.mu.Lock()
+	cw.records = append(cw.records, rec)
+	if len(cw.records) > coreMaxRecords {
+		cw.records = cw.records[len(cw.records)-coreMaxRecords:]
+	}
+	cw.saveLocked()
+	cw.mu.Unlock()
+
+	details := map[string]interface{}{"path": rec.Path, "size": rec.Size, "core_pid": rec.PID}
+	if rec.Exe != "" {
+		details["exe"] = rec.Exe
+	}
+	if rec.Signal != "" {
+		details["signal"] = rec.Signal
+	}
+	if rec.Archived {
+		details["original_path"] = rec.OriginalPath
+	}
+	m.addEvent(types.Event{
+		Timestamp: time.Now(),
+		Type:      "core_dump",
+		PID:       rec.PID,
+		Name:      rec.Name,
+		Message:   fmt.Sprintf("检测到 core 文件 %s (%.1f MB)", rec.Path, float64(rec.Size)/1024/1024),
+		Details:   details,
+	})
+}
+
+// matchTarget 按 PID 关联目标（已知可执行文件名时还需与目标进程名一致，避免 PID 被复用后误关联）；
+// 无 PID 时按进程名关联最近退出的目标
+func (cw *coreWatcher) matchTarget(pid int32, exe string) string {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	comm := filepath.Base(exe)
+	if len(comm) > 15 {
+		comm = comm[:15]
+	}
+	if p, ok := cw.pids[pid]; ok && pid != 0 {
+		if exe == "" || commMatches(comm, p.name) {
+			return p.name
+		}
+		return ""
+	}
+	if pid != 0 || exe == "" {
+		return ""
+	}
+	for _, p := range cw.pids {
+		if !p.exited.IsZero() && time.Since(p.exited) < 10*time.Minute && commMatches(comm, p.name) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+func (cw *coreWatcher) saveLocked() {
+	data, err := json.MarshalIndent(cw.records, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := cw.path + ".tmp"
+	if os.WriteFile(tmp, data, 0644) == nil {
+		os.Rename(tmp, cw.path)
+	}
+}
+
+// archiveCore 移动 core 文件到归档目录（跨文件系统时复制后删除）
+func archiveCore(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst + ".tmp")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst + ".tmp")
+		return err
+	}
+	if err := os.Rename(dst+".tmp", dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// cleanupArchive 删除超过保留天数的归档文件，并按容量上限从最旧的开始删除
+func (cw *coreWatcher) cleanupArchive() {
+	if cw.cfg.ArchiveDir == "" {
+		return
+	}
+	type archived struct {
+		path string
+		size int64
+		mod  time.Time
+	}
+	var files []archived
+	var total int64
+	filepath.Walk(cw.cfg.ArchiveDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() && !strings.HasSuffix(path, ".tmp") {
+			files = append(files, archived{path, info.Size(), info.ModTime()})
+			total += info.Size()
+		}
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+	cutoff := time.Now().AddDate(0, 0, -cw.cfg.ArchiveRetentionDays)
+	for _, f := range files {
+		if total <= cw.cfg.ArchiveMaxBytes && f.mod.After(cutoff) {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+			log.Printf("[INFO] 删除过期归档 core 文件: %s", f.path)
+		}
+	}
+}
+
+// readCorePrpsinfo 从 ELF core 的 NT_PRPSINFO 注释读取 PID 和进程名（仅支持 64 位小端）
+func readCorePrpsinfo(path string) (int32, string, bool) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return 0, "", false
+	}
+	defer f.Close()
+	if f.Type != elf.ET_CORE || f.Class != elf.ELFCLASS64 || f.ByteOrder != binary.LittleEndian {
+		return 0, "", false
+	}
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		data := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(data, 0); err != nil {
+			continue
+		}
+		for len(data) >= 12 {
+			namesz := int(binary.LittleEndian.Uint32(data[0:]))
+			descsz := int(binary.LittleEndian.Uint32(data[4:]))
+			typ := binary.LittleEndian.Uint32(data[8:])
+			descOff := 12 + align4(namesz)
+			if descOff+descsz > len(data) {
+				break
+			}
+			desc := data[descOff : descOff+descsz]
+			// struct elf_prpsinfo: pr_pid 偏移 24，pr_fname[16] 偏移 40
+			if typ == uint32(elf.NT_PRPSINFO) && len(desc) >= 56 {
+				pid := int32(binary.LittleEndian.Uint32(desc[24:]))
+				fname := strings.TrimRight(string(desc[40:56]), "\x00")
+				return pid, fname, true
+			}
+			data = data[descOff+align4(descsz):]
+		}
+	}
+	return 0, "", false
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// corePatternRegexp 将 core_pattern 的文件名部分转换为正则（%p→pid，%e→comm，%E→exe，%s→sig）
+func corePatternRegexp(pattern string, usesPID bool) *regexp.Regexp {
+	var sb strings.Builder
+	named := make(map[string]bool)
+	group := func(name, expr string) {
+		if name != "" && !named[name] {
+			named[name] = true
+			fmt.Fprintf(&sb, "(?P<%s>%s)", name, expr)
+		} else {
+			fmt.Fprintf(&sb, "(?:%s)", expr)
+		}
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c != '%' || i+1 >= len(pattern) {
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case 'p', 'P':
+			group("pid", `\d+`)
+		case 'i', 'I', 'u', 'g', 't', 'c', 'd':
+			group("", `\d+`)
+		case 's':
+			group("sig", `\d+`)
+		case 'e':
+			group("comm", `.+?`)
+		case 'E':
+			group("exe", `.+?`)
+		case '%':
+			sb.WriteString("%")
+		default:
+			group("", `.+?`)
+		}
+	}
+	if usesPID && !named["pid"] {
+		// core_uses_pid：文件名追加 .PID
+		sb.WriteString(`\.`)
+		group("pid", `\d+`)
+	}
+	return regexp.MustCompile("^" + sb.String() + "$")
+}
+
+// ListCoreDumps 列出检测到的 core 文件（name 为空时返回全部，按时间倒序；已删除的文件不返回）
+func (m *MultiMonitor) ListCoreDumps(name string) []types.CoreDump {
+	result := []types.CoreDump{}
+	cw := m.cores
+	if cw == nil {
+		return result
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	for _, r := range cw.records {
+		if name != "" && r.Name != name {
+			continue
+		}
+		if _, err := os.Stat(r.Path); err != nil {
+			continue
+		}
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time.After(result[j].Time) })
+	return result
+}
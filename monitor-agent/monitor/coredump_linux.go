@@ -0,0 +1,65 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// systemd-coredump: core.<comm>.<uid>.<boot id>.<pid>.<usec>[.zst|.lz4|.xz]
+	systemdCorePattern = regexp.MustCompile(`^core\.(?P<comm>.+)\.\d+\.[0-9a-f]+\.(?P<pid>\d+)\.\d+(?:\.\w+)?$`)
+	// apport: <exe 路径，/ 替换为 _>.<uid>.crash
+	apportCrashPattern = regexp.MustCompile(`^(?P<apport>.+)\.\d+\.crash$`)
+)
+
+// coreLocations 根据 /proc/sys/kernel/core_pattern 确定 core 文件位置；
+// 相对路径时 core 写入进程工作目录（cwds）
+func coreLocations(cwds []string) []coreLocation {
+	data, err := os.ReadFile("/proc/sys/kernel/core_pattern")
+	if err != nil {
+		return nil
+	}
+	pattern := strings.TrimSpace(string(data))
+	usesPID := false
+	if v, err := os.ReadFile("/proc/sys/kernel/core_uses_pid"); err == nil {
+		usesPID = strings.TrimSpace(string(v)) == "1"
+	}
+
+	if strings.HasPrefix(pattern, "|") {
+		switch {
+		case strings.Contains(pattern, "systemd-coredump"):
+			return []coreLocation{{dir: "/var/lib/systemd/coredump", pattern: systemdCorePattern}}
+		case strings.Contains(pattern, "apport"):
+			return []coreLocation{{dir: "/var/crash", pattern: apportCrashPattern}}
+		}
+		return nil
+	}
+
+	dir, base := filepath.Split(pattern)
+	re := corePatternRegexp(base, usesPID)
+	if filepath.IsAbs(pattern) {
+		if strings.Contains(dir, "%") {
+			return nil // 目录中含模板变量时无法确定位置
+		}
+		return []coreLocation{{dir: filepath.Clean(dir), pattern: re}}
+	}
+	var locs []coreLocation
+	for _, cwd := range cwds {
+		locs = append(locs, coreLocation{dir: filepath.Join(cwd, dir), pattern: re})
+	}
+	return locs
+}
+
+func signalName(n int) string {
+	if name := unix.SignalName(syscall.Signal(n)); name != "" {
+		return name
+	}
+	return syscall.Signal(n).String()
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// WER LocalDumps 默认文件名：<exe>.<pid>.dmp
+var werDumpPattern = regexp.MustCompile(`^(?P<comm>.+)\.(?P<pid>\d+)\.dmp$`)
+
+// coreLocations Windows 下为 WER LocalDumps 默认目录（%LOCALAPPDATA%\CrashDumps）
+func coreLocations(cwds []string) []coreLocation {
+	local := os.Getenv("LOCALAPPDATA")
+	if local == "" {
+		return nil
+	}
+	return []coreLocation{{dir: filepath.Join(local, "CrashDumps"), pattern: werDumpPattern}}
+}
+
+func signalName(n int) string {
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,346 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor-agent/buffer"
+	"monitor-agent/types"
+)
+
+// customState 目标的自定义指标
+type customState struct {
+	series   map[string]*customSeries
+	exceed   []int  // 与 target.CustomThresholds 对应的连续超限次数
+	rejected uint64 // 超出指标数上限被拒绝的样本数
+}
+
+// defaultCustomMaxSeries 每个目标默认最多的自定义指标数
+const defaultCustomMaxSeries = 100
+
+// customSeries 单个指标序列：gauge 保留最新值，counter 累加后在采样时换算为每秒速率
+type customSeries struct {
+	kind       string
+	value      float64
+	pending    float64 // counter 自上次采样以来的累加
+	total      float64
+	lastSample time.Time
+	buf        *buffer.RingBuffer[types.CustomPoint]
+}
+
+// customSample 一次推送的指标值
+type customSample struct {
+	Name  string  `json:"name"`
+	Type  string  `json:"type"` // gauge / counter
+	Value float64 `json:"value"`
+}
+
+// ingestCustom 记录目标的自定义指标，ref 为备注名称或进程名（pid 非 0 时按 PID），返回匹配的目标数
+func (m *MultiMonitor) ingestCustom(ref string, pid int32, samples []customSample) (int, error) {
+	for _, s := range samples {
+		if s.Name == "" || strings.ContainsAny(s.Name, " \t|:") {
+			return 0, fmt.Errorf("invalid metric name %q", s.Name)
+		}
+		if s.Type != "gauge" && s.Type != "counter" {
+			return 0, fmt.Errorf("invalid metric type %q for %s", s.Type, s.Name)
+		}
+	}
+
+	limit := m.config.CustomMetrics.MaxSeries
+	if limit <= 0 {
+		limit = defaultCustomMaxSeries
+	}
+	var rejected []string
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bound := 0
+	for p, state := range m.targets {
+		if pid != 0 {
+			if p != pid {
+				continue
+			}
+		} else if !depMatches(state.target, ref) {
+			continue
+		}
+		bound++
+		cs := state.custom
+		if cs == nil {
+			cs = &customState{series: make(map[string]*customSeries)}
+			state.custom = cs
+		}
+		for _, s := range samples {
+			series, ok := cs.series[s.Name]
+			if !ok && len(cs.series) >= limit {
+				// 指标名来自外部输入，限制每个目标的序列数，避免不断出现的新名称耗尽内存
+				if cs.rejected == 0 {
+					log.Printf("[WARN] 自定义指标: 目标 %s (PID: %d) 的指标数已达上限 %d，拒绝新指标 %s", state.target.Name, p, limit, s.Name)
+				}
+				cs.rejected++
+				rejected = append(rejected, s.Name)
+				continue
+			}
+			if !ok || series.kind != s.Type {
+				series = &customSeries{kind: s.Type, lastSample: time.Now(), buf: buffer.NewRingBuffer[types.CustomPoint](m.config.MetricsBufferLen)}
+				cs.series[s.Name] = series
+			}
+			if s.Type == "gauge" {
+				series.value = s.Value
+			} else {
+				series.pending += s.Value
+				series.total += s.Value
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return bound, fmt.Errorf("metric limit %d reached, rejected: %s", limit, strings.Join(rejected, ", "))
+	}
+	return bound, nil
+}
+
+// collectCustom 采样自定义指标：心跳数值字段和推送的指标（同名时以推送的为准），counter 换算为每秒速率
+func (m *MultiMonitor) collectCustom(state *targetState, now time.Time) map[string]float64 {
+	result := m.heartbeatCustom(state)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state.custom == nil {
+		return result
+	}
+	if result == nil {
+		result = make(map[string]float64, len(state.custom.series))
+	}
+	for name, s := range state.custom.series {
+		if s.kind == "counter" {
+			if elapsed := now.Sub(s.lastSample).Seconds(); elapsed > 0 {
+				s.value = s.pending / elapsed
+			}
+			s.pending = 0
+		}
+		s.lastSample = now
+		s.buf.Push(types.CustomPoint{Timestamp: now, Value: s.value})
+		result[name] = s.value
+	}
+	return result
+}
+
+// checkCustomThresholds 自定义指标阈值：与 CPU/内存阈值相同，连续超限 exceed_count 次产生事件并按重启配置重启
+func (m *MultiMonitor) checkCustomThresholds(state *targetState, target types.MonitorTarget, metric types.ProcessMetrics) {
+	if len(target.CustomThresholds) == 0 {
+		return
+	}
+	var fired []types.Event
+	m.mu.Lock()
+	if state.custom == nil {
+		state.custom = &customState{series: make(map[string]*customSeries)}
+	}
+	cs := state.custom
+	if len(cs.exceed) != len(target.CustomThresholds) {
+		cs.exceed = make([]int, len(target.CustomThresholds))
+	}
+	for i, th := range target.CustomThresholds {
+		value, ok := metric.Custom[th.Metric]
+		exceeded := ok && ((th.Op == "<" && value < th.Value) || (th.Op != "<" && value > th.Value))
+		if !exceeded {
+			cs.exceed[i] = 0
+			continue
+		}
+		cs.exceed[i]++
+		limit := th.ExceedCount
+		if limit <= 0 {
+			limit = 3 // 默认连续3次
+		}
+		if cs.exceed[i] < limit {
+			continue
+		}
+		op := th.Op
+		if op == "" {
+			op = ">"
+		}
+		fired = append(fired, types.Event{
+			Timestamp: time.Now(),
+			Type:      "custom_threshold",
+			PID:       target.PID,
+			Name:      target.Name,
+			Message:   fmt.Sprintf("%s = %g 超出阈值 (%s %g) 连续 %d 次", th.Metric, value, op, th.Value, cs.exceed[i]),
+			Details:   map[string]interface{}{"metric": th.Metric, "value": value, "op": op, "threshold": th.Value},
+		})
+		cs.exceed[i] = 0
+	}
+	m.mu.Unlock()
+
+	for _, evt := range fired {
+		m.addEvent(evt)
+	}
+	if len(fired) > 0 && (target.RestartCmd != "" || target.Supervise != nil) {
+		m.tryRestart(target.PID, "custom_threshold")
+	}
+}
+
+// GetCustomSeries 获取目标的自定义指标序列（按名称排序），metric 非空时只返回该指标并带最近 n 个采样点
+func (m *MultiMonitor) GetCustomSeries(pid int32, metric string, n int) ([]types.CustomSeries, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, ok := m.targets[pid]
+	if !ok {
+		return nil, fmt.Errorf("target PID %d not found", pid)
+	}
+	result := []types.CustomSeries{}
+	if state.custom == nil {
+		return result, nil
+	}
+	for name, s := range state.custom.series {
+		if metric != "" && name != metric {
+			continue
+		}
+		cs := types.CustomSeries{Name: name, Kind: s.kind, Value: s.value, Total: s.total}
+		if metric != "" {
+			cs.Points = s.buf.GetRecent(n)
+		}
+		result = append(result, cs)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// parseStatsD 解析 StatsD 行：name:value|g 或 name:value|c[|@rate]，目标取标签 #target:xxx / #pid:123，
+// 无标签时取名称第一段（target.metric）
+func parseStatsD(line string) (ref string, pid int32, sample customSample, err error) {
+	parts := strings.Split(line, "|")
+	if len(parts) < 2 {
+		return "", 0, sample, fmt.Errorf("invalid statsd line %q", line)
+	}
+	name, value, ok := strings.Cut(parts[0], ":")
+	if !ok {
+		return "", 0, sample, fmt.Errorf("invalid statsd line %q", line)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", 0, sample, fmt.Errorf("invalid value in %q", line)
+	}
+	switch parts[1] {
+	case "g":
+		sample.Type = "gauge"
+	case "c":
+		sample.Type = "counter"
+	default:
+		return "", 0, sample, fmt.Errorf("unsupported statsd type %q", parts[1])
+	}
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			// 采样率：计数按比例还原
+			if rate, err := strconv.ParseFloat(p[1:], 64); err == nil && rate > 0 && sample.Type == "counter" {
+				v /= rate
+			}
+		case strings.HasPrefix(p, "#"):
+			for _, tag := range strings.Split(p[1:], ",") {
+				k, tv, _ := strings.Cut(tag, ":")
+				switch k {
+				case "target":
+					ref = tv
+				case "pid":
+					if n, err := strconv.ParseInt(tv, 10, 32); err == nil {
+						pid = int32(n)
+					}
+				}
+			}
+		}
+	}
+	if ref == "" && pid == 0 {
+		if ref, name, ok = strings.Cut(name, "."); !ok {
+			return "", 0, sample, fmt.Errorf("no target in %q", line)
+		}
+	}
+	sample.Name, sample.Value = name, v
+	return ref, pid, sample, nil
+}
+
+// startCustomListeners 启动 StatsD / HTTP 自定义指标接收，stop 关闭时停止监听
+func (m *MultiMonitor) startCustomListeners(cfg types.CustomMetricsConfig, stop <-chan struct{}) {
+	if cfg.StatsDAddr != "" {
+		conn, err := net.ListenPacket("udp", cfg.StatsDAddr)
+		if err != nil {
+			log.Printf("[ERROR] 自定义指标: StatsD 监听 %s 失败: %v", cfg.StatsDAddr, err)
+		} else {
+			log.Printf("[INFO] 自定义指标: StatsD 监听 %s", cfg.StatsDAddr)
+			go func() {
+				<-stop
+				conn.Close()
+			}()
+			go m.serveStatsD(conn)
+		}
+	}
+	if cfg.HTTPAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", m.handleCustomHTTP)
+		srv := &http.Server{Addr: cfg.HTTPAddr, Handler: mux, ReadTimeout: 10 * time.Second}
+		ln, err := net.Listen("tcp", cfg.HTTPAddr)
+		if err != nil {
+			log.Printf("[ERROR] 自定义指标: HTTP 监听 %s 失败: %v", cfg.HTTPAddr, err)
+			return
+		}
+		log.Printf("[INFO] 自定义指标: HTTP 监听 %s", cfg.HTTPAddr)
+		go func() {
+			<-stop
+			srv.Close()
+		}()
+		go srv.Serve(ln)
+	}
+}
+
+func (m *MultiMonitor) serveStatsD(conn net.PacketConn) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			ref, pid, sample, err := parseStatsD(line)
+			if err != nil {
+				continue
+			}
+			m.ingestCustom(ref, pid, []customSample{sample})
+		}
+	}
+}
+
+// handleCustomHTTP POST /metrics {"target":"xxx","pid":0,"metrics":[{"name":"queue_len","type":"gauge","value":12}]}
+func (m *MultiMonitor) handleCustomHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Target  string         `json:"target"`
+		PID     int32          `json:"pid"`
+		Metrics []customSample `json:"metrics"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil || (req.Target == "" && req.PID == 0) {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	n, err := m.ingestCustom(req.Target, req.PID, req.Metrics)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "target not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
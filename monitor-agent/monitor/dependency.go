@@ -0,0 +1,519 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// readyRetryInterval 就绪检查命令失败后的重试间隔
+const readyRetryInterval = 2 * time.Second
+
+// dependencyState 目标的依赖状态（由依赖协程更新，读写均持有 m.mu）
+type dependencyState struct {
+	ready       bool      // 作为上游时已就绪
+	aliveSince  time.Time // 本次存活的起始观测时间
+	checkedAt   time.Time // 最近一次执行就绪检查命令
+	bounced     bool      // 曾停止或重启，重新就绪后级联重启下游
+	degradedBy  []string  // 未就绪的上游（含间接上游）
+	waitRestart string    // 上游未就绪时推迟的重启原因
+}
+
+// suppressedWhenDegraded 上游停止期间下游不再单独上报的告警类事件
+var suppressedWhenDegraded = map[string]bool{
+	"cpu_threshold":    true,
+	"mem_threshold":    true,
+	"hung":             true,
+	"leak_suspected":   true,
+	"anomaly":          true,
+	"listen_missing":   true,
+	"peer_lost":        true,
+	"file_stale":       true,
+	"heartbeat_missed": true,
+	"custom_threshold": true,
+}
+
+// depKey 目标在依赖图中的名称（优先使用备注名称）
+func depKey(t types.MonitorTarget) string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Name
+}
+
+func depMatches(t types.MonitorTarget, ref string) bool {
+	return ref != "" && (t.Alias == ref || t.Name == ref)
+}
+
+// dependencyNodesLocked 当前所有目标及等待启动的目标，exclude 为被替换的目标 PID（调用方持有锁）
+func (m *MultiMonitor) dependencyNodesLocked(exclude int32) []types.MonitorTarget {
+	nodes := make([]types.MonitorTarget, 0, len(m.targets)+len(m.pendingStarts))
+	for pid, state := range m.targets {
+		if exclude == 0 || pid != exclude {
+			nodes = append(nodes, state.target)
+		}
+	}
+	nodes = append(nodes, m.pendingStarts...)
+	sort.Slice(nodes, func(i, j int) bool { return depKey(nodes[i]) < depKey(nodes[j]) })
+	return nodes
+}
+
+// validateDependenciesLocked 加入或更新 target 后依赖图不能有环（调用方持有锁）
+func (m *MultiMonitor) validateDependenciesLocked(target types.MonitorTarget, replacePID int32) error {
+	if len(target.DependsOn) == 0 && replacePID == 0 {
+		return nil // 新目标没有上游，不会形成环
+	}
+	nodes := append(m.dependencyNodesLocked(replacePID), target)
+	if cycle := findDependencyCycle(nodes); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	for _, ref := range target.DependsOn {
+		found := false
+		for _, n := range nodes[:len(nodes)-1] {
+			if depMatches(n, ref) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Printf("[WARN] 目标 %s 依赖的上游 %s 尚未监控，视为未就绪", depKey(target), ref)
+		}
+	}
+	return nil
+}
+
+// dependencyEdges 每个节点的上游节点下标
+func dependencyEdges(nodes []types.MonitorTarget) [][]int {
+	edges := make([][]int, len(nodes))
+	for i, n := range nodes {
+		for _, ref := range n.DependsOn {
+			for j, u := range nodes {
+				if depMatches(u, ref) {
+					edges[i] = append(edges[i], j)
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// findDependencyCycle 深度优先查找环，返回环上的目标名称（首尾相同），无环返回 nil
+func findDependencyCycle(nodes []types.MonitorTarget) []string {
+	edges := dependencyEdges(nodes)
+	color := make([]int, len(nodes)) // 0 未访问，1 访问中，2 已完成
+	var stack []int
+	var cycle []string
+
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		color[i] = 1
+		stack = append(stack, i)
+		for _, j := range edges[i] {
+			if color[j] == 1 {
+				for k := len(stack) - 1; k >= 0; k-- {
+					if stack[k] == j {
+						for _, idx := range stack[k:] {
+							cycle = append(cycle, depKey(nodes[idx]))
+						}
+						break
+					}
+				}
+				cycle = append(cycle, depKey(nodes[j]))
+				return true
+			}
+			if color[j] == 0 && visit(j) {
+				return true
+			}
+		}
+		color[i] = 2
+		stack = stack[:len(stack)-1]
+		return false
+	}
+	for i := range nodes {
+		if color[i] == 0 && visit(i) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// dependencyLevels 每个节点的启动层级：无依赖为 0，否则为上游最大层级加 1（依赖图无环）
+func dependencyLevels(nodes []types.MonitorTarget) []int {
+	edges := dependencyEdges(nodes)
+	levels := make([]int, len(nodes))
+	done := make([]bool, len(nodes))
+	var level func(i int) int
+	level = func(i int) int {
+		if done[i] {
+			return levels[i]
+		}
+		done[i] = true // 防御性标记，异常的环不会无限递归
+		for _, j := range edges[i] {
+			if l := level(j) + 1; l > levels[i] {
+				levels[i] = l
+			}
+		}
+		return levels[i]
+	}
+	for i := range nodes {
+		level(i)
+	}
+	return levels
+}
+
+// upstreamDownLocked 目标未就绪的上游（含间接上游），调用方持有锁
+func (m *MultiMonitor) upstreamDownLocked(target types.MonitorTarget) []string {
+	var down []string
+	for _, ref := range target.DependsOn {
+		ready := false
+		var inherited []string
+		for _, state := range m.targets {
+			if !depMatches(state.target, ref) {
+				continue
+			}
+			if state.dep != nil && state.dep.ready {
+				ready = true
+				inherited = append(inherited, state.dep.degradedBy...)
+			}
+		}
+		if !ready {
+			down = append(down, ref)
+		}
+		down = append(down, inherited...)
+	}
+	return uniqueSorted(down)
+}
+
+func uniqueSorted(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Strings(list)
+	out := list[:1]
+	for _, s := range list[1:] {
+		if s != out[len(out)-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// deferSupervisedStart 有上游的托管目标加入等待队列，由依赖协程在上游就绪后按顺序启动
+func (m *MultiMonitor) deferSupervisedStart(target types.MonitorTarget) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.pendingStarts {
+		if depKey(p) == depKey(target) {
+			return fmt.Errorf("target %s already waiting to start", depKey(target))
+		}
+	}
+	if err := m.validateDependenciesLocked(target, 0); err != nil {
+		return err
+	}
+	m.pendingStarts = append(m.pendingStarts, target)
+	log.Printf("[INFO] 托管目标 %s 等待上游 %s 就绪后启动", depKey(target), strings.Join(target.DependsOn, ","))
+	return nil
+}
+
+func (m *MultiMonitor) watchDependencies(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(m.config.SampleInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.evaluateDependencies()
+		}
+	}
+}
+
+// evaluateDependencies 更新上游就绪状态，标记/恢复降级的下游，执行推迟的启动、重启和级联重启
+func (m *MultiMonitor) evaluateDependencies() {
+	// 被依赖的目标
+	m.mu.Lock()
+	refs := make(map[string]bool)
+	for _, state := range m.targets {
+		for _, ref := range state.target.DependsOn {
+			refs[ref] = true
+		}
+	}
+	for _, t := range m.pendingStarts {
+		for _, ref := range t.DependsOn {
+			refs[ref] = true
+		}
+	}
+	var upstreams []*targetState
+	for _, state := range m.targets {
+		if refs[state.target.Alias] || refs[state.target.Name] {
+			if state.dep == nil {
+				state.dep = &dependencyState{}
+			}
+			upstreams = append(upstreams, state)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, state := range upstreams {
+		m.updateReadiness(state)
+	}
+
+	now := time.Now()
+	var events []types.Event
+	var restarts []int32
+	restartReasons := make(map[int32]string)
+	var starts []types.MonitorTarget
+
+	m.mu.Lock()
+	nodes := m.dependencyNodesLocked(0)
+	levels := dependencyLevels(nodes)
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return levels[order[a]] < levels[order[b]] })
+
+	// 按层级计算降级状态，间接上游的停止沿依赖链传递
+	for _, idx := range order {
+		state, ok := m.targets[nodes[idx].PID]
+		if ok && len(state.target.DependsOn) > 0 {
+			if state.dep == nil {
+				state.dep = &dependencyState{}
+			}
+			prev := state.dep.degradedBy
+			down := m.upstreamDownLocked(state.target)
+			state.dep.degradedBy = down
+			target := state.target
+			switch {
+			case len(prev) == 0 && len(down) > 0:
+				events = append(events, types.Event{
+					Timestamp: now,
+					Type:      "degraded",
+					PID:       target.PID,
+					Name:      target.Name,
+					Message:   fmt.Sprintf("上游 %s 未就绪，降级运行", strings.Join(down, ",")),
+					Details:   map[string]interface{}{"degraded_by": down},
+				})
+			case len(prev) > 0 && len(down) == 0:
+				events = append(events, types.Event{
+					Timestamp: now,
+					Type:      "degraded_recovered",
+					PID:       target.PID,
+					Name:      target.Name,
+					Message:   fmt.Sprintf("上游 %s 已恢复", strings.Join(prev, ",")),
+					Details:   map[string]interface{}{"degraded_by": prev},
+				})
+			}
+			if len(down) == 0 && state.dep.waitRestart != "" {
+				restarts = append(restarts, target.PID)
+				restartReasons[target.PID] = state.dep.waitRestart
+				state.dep.waitRestart = ""
+			}
+		}
+	}
+
+	// 上游重新就绪后级联重启配置了 cascade_restart 的下游
+	for _, up := range upstreams {
+		if !up.dep.ready || !up.dep.bounced {
+			continue
+		}
+		up.dep.bounced = false
+		for _, state := range m.targets {
+			if !state.target.CascadeRestart || state.exitReported || state.dep == nil || len(state.dep.degradedBy) > 0 {
+				continue
+			}
+			for _, ref := range state.target.DependsOn {
+				if depMatches(up.target, ref) {
+					if _, queued := restartReasons[state.target.PID]; !queued {
+						restarts = append(restarts, state.target.PID)
+						restartReasons[state.target.PID] = "upstream_restart:" + depKey(up.target)
+					}
+					break
+				}
+			}
+		}
+	}
+
+	// 上游全部就绪的等待目标按层级启动，同一轮中启动的目标需就绪后才会启动其下游
+	var remaining []types.MonitorTarget
+	for _, t := range m.pendingStarts {
+		if len(m.upstreamDownLocked(t)) == 0 {
+			starts = append(starts, t)
+		} else {
+			remaining = append(remaining, t)
+		}
+	}
+	m.pendingStarts = remaining
+	m.mu.Unlock()
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+	for _, pid := range restarts {
+		log.Printf("[INFO] 上游已就绪，执行重启 PID=%d (原因:%s)", pid, restartReasons[pid])
+		m.tryRestart(pid, restartReasons[pid])
+	}
+	for _, t := range starts {
+		if err := m.addSupervisedTarget(t); err != nil {
+			log.Printf("[ERROR] 启动托管目标 %s 失败: %v", depKey(t), err)
+			m.addEvent(types.Event{
+				Timestamp: time.Now(),
+				Type:      "restart",
+				Name:      t.Name,
+				Message:   fmt.Sprintf("上游就绪后启动失败: %v", err),
+				Details:   map[string]interface{}{"reason": "dependency_start", "success": false},
+			})
+		}
+	}
+}
+
+// updateReadiness 更新上游目标的就绪状态；非托管目标重启后 PID 变化时重新关联
+func (m *MultiMonitor) updateReadiness(state *targetState) {
+	m.mu.RLock()
+	target := state.target
+	supervised := state.sup != nil
+	dep := *state.dep
+	m.mu.RUnlock()
+
+	alive := m.provider.IsAlive(target.PID)
+	if !alive && !supervised && target.RestartCmd != "" {
+		if pid := m.reattachTarget(&target); pid != 0 {
+			alive = true
+			dep.ready, dep.aliveSince = false, time.Time{} // rekeyTargetLocked 已重置依赖状态
+		}
+	}
+
+	now := time.Now()
+	if !alive {
+		m.mu.Lock()
+		if state.dep.ready {
+			state.dep.bounced = true
+		}
+		state.dep.ready = false
+		state.dep.aliveSince = time.Time{}
+		m.mu.Unlock()
+		return
+	}
+	if dep.ready {
+		return
+	}
+
+	aliveSince := dep.aliveSince
+	if aliveSince.IsZero() {
+		aliveSince = now
+	}
+	ready := true
+	checked := dep.checkedAt
+	if rc := target.Ready; rc != nil {
+		if now.Sub(aliveSince) < time.Duration(rc.Delay)*time.Second {
+			ready = false
+		} else if rc.Cmd != "" {
+			if now.Sub(checked) < readyRetryInterval {
+				ready = false
+			} else {
+				timeout := time.Duration(rc.Timeout) * time.Second
+				if timeout <= 0 {
+					timeout = 10 * time.Second
+				}
+				code, _, err := runCommand(rc.Cmd, timeout)
+				ready = err == nil && code == 0
+				checked = time.Now()
+			}
+		}
+	}
+
+	m.mu.Lock()
+	state.dep.aliveSince = aliveSince
+	state.dep.checkedAt = checked
+	state.dep.ready = ready
+	m.mu.Unlock()
+	if ready {
+		log.Printf("[INFO] 上游目标 %s 已就绪 (PID=%d)", depKey(target), state.target.PID)
+	}
+}
+
+// waitUpstreamReady 托管进程重启前等待上游就绪；返回 false 表示已停止托管
+func (m *MultiMonitor) waitUpstreamReady(state *targetState) bool {
+	logged := false
+	for {
+		m.mu.RLock()
+		stopping := state.sup.stopping
+		var down []string
+		if state.dep != nil {
+			down = state.dep.degradedBy
+		}
+		name := depKey(state.target)
+		m.mu.RUnlock()
+		if stopping {
+			return false
+		}
+		if len(down) == 0 {
+			return true
+		}
+		if !logged {
+			log.Printf("[INFO] 托管目标 %s 等待上游 %s 就绪后重启", name, strings.Join(down, ","))
+			logged = true
+		}
+		select {
+		case <-state.sup.stop:
+			return false
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// isDegraded 目标是否因上游未就绪而降级
+func (m *MultiMonitor) isDegraded(pid int32) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if state, ok := m.targets[pid]; ok && state.dep != nil {
+		return state.dep.degradedBy
+	}
+	return nil
+}
+
+// GetDependencies 获取依赖图（按启动层级、名称排序）
+func (m *MultiMonitor) GetDependencies() []types.DependencyNode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodes := m.dependencyNodesLocked(0)
+	levels := dependencyLevels(nodes)
+	edges := dependencyEdges(nodes)
+	dependents := make([][]string, len(nodes))
+	for i := range nodes {
+		for _, j := range edges[i] {
+			dependents[j] = append(dependents[j], depKey(nodes[i]))
+		}
+	}
+
+	result := make([]types.DependencyNode, 0, len(nodes))
+	for i, n := range nodes {
+		node := types.DependencyNode{
+			Name:       depKey(n),
+			PID:        n.PID,
+			DependsOn:  n.DependsOn,
+			Dependents: uniqueSorted(dependents[i]),
+			Level:      levels[i],
+		}
+		if state, ok := m.targets[n.PID]; ok && n.PID != 0 {
+			if state.dep != nil {
+				node.Ready = state.dep.ready
+				node.DegradedBy = state.dep.degradedBy
+				if state.dep.waitRestart != "" {
+					node.Waiting = "restart"
+				}
+			}
+		} else {
+			node.Waiting = "start"
+			node.DegradedBy = m.upstreamDownLocked(n)
+		}
+		result = append(result, node)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Level < result[j].Level })
+	return result
+}
@@ -0,0 +1,310 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// discoveryRule 自动发现规则运行状态（由发现协程访问，读写持有 m.mu）
+type discoveryRule struct {
+	rule      types.DiscoveryRule
+	cmdlineRe *regexp.Regexp
+	deadSince map[int32]time.Time  // 已退出实例的首次发现时间
+	indexes   map[*targetState]int // 实例序号（{index}），按状态对象记录，PID 变化不影响
+	below     bool                 // 已报告实例数不足
+}
+
+// AddDiscoveryRule 添加自动发现规则（同名规则覆盖）
+func (m *MultiMonitor) AddDiscoveryRule(rule types.DiscoveryRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule name required")
+	}
+	if rule.NamePattern == "" && rule.ExePattern == "" && rule.User == "" && rule.CmdlineRegex == "" {
+		return fmt.Errorf("rule %s has no match condition", rule.Name)
+	}
+	if rule.Template.Supervise != nil {
+		return fmt.Errorf("supervised template not supported")
+	}
+	if _, err := path.Match(rule.NamePattern, ""); err != nil {
+		return fmt.Errorf("invalid name_pattern %q: %v", rule.NamePattern, err)
+	}
+	if _, err := filepath.Match(rule.ExePattern, ""); err != nil {
+		return fmt.Errorf("invalid exe_pattern %q: %v", rule.ExePattern, err)
+	}
+	dr := &discoveryRule{rule: rule, deadSince: make(map[int32]time.Time), indexes: make(map[*targetState]int)}
+	if rule.CmdlineRegex != "" {
+		re, err := regexp.Compile(rule.CmdlineRegex)
+		if err != nil {
+			return fmt.Errorf("invalid cmdline_regex: %v", err)
+		}
+		dr.cmdlineRe = re
+	}
+	if dr.rule.RetireAfter <= 0 {
+		dr.rule.RetireAfter = 300
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.discovery == nil {
+		m.discovery = make(map[string]*discoveryRule)
+	}
+	m.discovery[rule.Name] = dr
+	log.Printf("[INFO] Added discovery rule: %s", rule.Name)
+	return nil
+}
+
+// RemoveDiscoveryRule 移除自动发现规则，已加入的目标保留
+func (m *MultiMonitor) RemoveDiscoveryRule(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.discovery[name]; !ok {
+		return fmt.Errorf("discovery rule %s not found", name)
+	}
+	delete(m.discovery, name)
+	for _, state := range m.targets {
+		if state.target.Discovery == name {
+			state.target.Discovery = ""
+		}
+	}
+	log.Printf("[INFO] Removed discovery rule: %s", name)
+	return nil
+}
+
+// GetDiscoveryStatus 获取自动发现规则及其实例（按规则名排序）
+func (m *MultiMonitor) GetDiscoveryStatus() []types.DiscoveryStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make([]types.DiscoveryStatus, 0, len(m.discovery))
+	for _, dr := range m.discovery {
+		st := types.DiscoveryStatus{Rule: dr.rule, Instances: []int32{}, Below: dr.below}
+		for pid, state := range m.targets {
+			if state.target.Discovery != dr.rule.Name {
+				continue
+			}
+			st.Instances = append(st.Instances, pid)
+			if _, dead := dr.deadSince[pid]; !dead {
+				st.Running++
+			}
+		}
+		sort.Slice(st.Instances, func(i, j int) bool { return st.Instances[i] < st.Instances[j] })
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Rule.Name < result[j].Rule.Name })
+	return result
+}
+
+func (m *MultiMonitor) watchDiscovery(stop <-chan struct{}) {
+	interval := m.config.DiscoveryInterval
+	if interval <= 0 {
+		interval = 10
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.scanDiscovery()
+		}
+	}
+}
+
+// matches 进程是否满足规则的全部匹配条件
+func (dr *discoveryRule) matches(p types.ProcessInfo) bool {
+	r := dr.rule
+	if r.NamePattern != "" {
+		if ok, _ := path.Match(r.NamePattern, p.Name); !ok {
+			return false
+		}
+	}
+	if r.ExePattern != "" {
+		if ok, _ := filepath.Match(r.ExePattern, p.Exe); !ok {
+			return false
+		}
+	}
+	if r.User != "" && p.Username != r.User {
+		return false
+	}
+	if dr.cmdlineRe != nil && !dr.cmdlineRe.MatchString(p.Cmdline) {
+		return false
+	}
+	return true
+}
+
+// scanDiscovery 扫描进程列表：加入匹配的新进程，已退出实例在新进程出现时重新关联，
+// 超过 retire_after 仍未恢复的实例移除，运行实例数低于期望值时告警
+func (m *MultiMonitor) scanDiscovery() {
+	m.mu.RLock()
+	rules := make([]*discoveryRule, 0, len(m.discovery))
+	for _, dr := range m.discovery {
+		rules = append(rules, dr)
+	}
+	m.mu.RUnlock()
+	if len(rules) == 0 {
+		return
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].rule.Name < rules[j].rule.Name })
+
+	procs, err := m.provider.ListAllProcesses()
+	if err != nil {
+		log.Printf("[WARN] 自动发现: 获取进程列表失败: %v", err)
+		return
+	}
+	self := int32(os.Getpid())
+	now := time.Now()
+
+	for _, dr := range rules {
+		var events []types.Event
+		var adds []types.MonitorTarget
+		var indexes []int
+
+		m.mu.Lock()
+		// 当前实例及已退出的实例
+		used := make(map[int]bool)
+		var dead []*targetState
+		running := 0
+		for pid, state := range m.targets {
+			if state.target.Discovery != dr.rule.Name {
+				continue
+			}
+			used[dr.indexes[state]] = true
+			if m.provider.IsAlive(pid) {
+				delete(dr.deadSince, pid)
+				running++
+				continue
+			}
+			if _, ok := dr.deadSince[pid]; !ok {
+				dr.deadSince[pid] = now
+			}
+			dead = append(dead, state)
+		}
+		sort.Slice(dead, func(i, j int) bool { return dr.deadSince[dead[i].target.PID].Before(dr.deadSince[dead[j].target.PID]) })
+
+		for _, p := range procs {
+			if p.PID == self || !dr.matches(p) {
+				continue
+			}
+			if _, monitored := m.targets[p.PID]; monitored {
+				continue
+			}
+			// 优先复用同名且已报告退出的实例（保留备注名称、统计和历史缓冲）
+			reused := false
+			for i, state := range dead {
+				if state.target.Name == p.Name && state.exitReported {
+					oldPID := state.target.PID
+					delete(dr.deadSince, oldPID)
+					m.rekeyTargetLocked(oldPID, p.PID)
+					dead = append(dead[:i], dead[i+1:]...)
+					running++
+					reused = true
+					log.Printf("[INFO] 自动发现: 规则 %s 实例 %s 重新关联到 PID=%d (原 PID=%d)", dr.rule.Name, depKey(state.target), p.PID, oldPID)
+					break
+				}
+			}
+			if reused {
+				continue
+			}
+
+			t := dr.rule.Template
+			t.PID, t.Name, t.Cmdline, t.Discovery = p.PID, p.Name, p.Cmdline, dr.rule.Name
+			index := 1
+			for used[index] {
+				index++
+			}
+			used[index] = true
+			if dr.rule.AliasPattern != "" {
+				t.Alias = strings.NewReplacer("{name}", p.Name, "{pid}", strconv.Itoa(int(p.PID)), "{index}", strconv.Itoa(index)).Replace(dr.rule.AliasPattern)
+			}
+			adds = append(adds, t)
+			indexes = append(indexes, index)
+		}
+
+		// 退出超过 retire_after 的实例移除
+		var retire []types.MonitorTarget
+		for _, state := range dead {
+			if now.Sub(dr.deadSince[state.target.PID]) >= time.Duration(dr.rule.RetireAfter)*time.Second {
+				retire = append(retire, state.target)
+				delete(dr.deadSince, state.target.PID)
+			}
+		}
+		m.mu.Unlock()
+
+		for _, t := range retire {
+			m.mu.Lock()
+			delete(dr.indexes, m.targets[t.PID])
+			m.mu.Unlock()
+			m.RemoveTarget(t.PID)
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "discovery_retired",
+				PID:       t.PID,
+				Name:      t.Name,
+				Message:   fmt.Sprintf("自动发现实例 %s 退出超过 %d 秒，已移除（规则 %s）", depKey(t), dr.rule.RetireAfter, dr.rule.Name),
+				Details:   map[string]interface{}{"rule": dr.rule.Name},
+			})
+		}
+		for i, t := range adds {
+			if err := m.AddTarget(t); err != nil {
+				log.Printf("[WARN] 自动发现: 加入 %s (PID=%d) 失败: %v", t.Name, t.PID, err)
+				continue
+			}
+			m.mu.Lock()
+			if state, ok := m.targets[t.PID]; ok {
+				dr.indexes[state] = indexes[i]
+			}
+			m.mu.Unlock()
+			running++
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "discovered",
+				PID:       t.PID,
+				Name:      t.Name,
+				Message:   fmt.Sprintf("自动发现新实例 %s 并加入监控（规则 %s）", depKey(t), dr.rule.Name),
+				Details:   map[string]interface{}{"rule": dr.rule.Name, "cmdline": t.Cmdline},
+			})
+		}
+
+		// 期望实例数检查（状态变化时上报）
+		if expected := dr.rule.ExpectedInstances; expected > 0 {
+			m.mu.Lock()
+			wasBelow := dr.below
+			dr.below = running < expected
+			below := dr.below
+			m.mu.Unlock()
+			details := map[string]interface{}{"rule": dr.rule.Name, "running": running, "expected": expected}
+			switch {
+			case below && !wasBelow:
+				events = append(events, types.Event{
+					Timestamp: now,
+					Type:      "instances_below_expected",
+					Name:      dr.rule.Name,
+					Message:   fmt.Sprintf("规则 %s 运行实例 %d 个，低于期望的 %d 个", dr.rule.Name, running, expected),
+					Details:   details,
+				})
+			case !below && wasBelow:
+				events = append(events, types.Event{
+					Timestamp: now,
+					Type:      "instances_recovered",
+					Name:      dr.rule.Name,
+					Message:   fmt.Sprintf("规则 %s 运行实例恢复到 %d 个", dr.rule.Name, running),
+					Details:   details,
+				})
+			}
+		}
+
+		for _, evt := range events {
+			m.addEvent(evt)
+		}
+	}
+}
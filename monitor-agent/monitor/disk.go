@@ -0,0 +1,306 @@
+package monitor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"monitor-agent/buffer"
+	"monitor-agent/history"
+	"monitor-agent/stats"
+	"monitor-agent/types"
+)
+
+// 只读镜像类文件系统始终 100% 使用，不纳入监控
+var diskSkipFSTypes = map[string]bool{"squashfs": true, "iso9660": true, "udf": true}
+
+// diskMinSamples 预测写满所需的最少样本数
+const diskMinSamples = 10
+
+// diskMonitor 磁盘空间/IO 采样状态
+type diskMonitor struct {
+	cfg types.DiskConfig
+
+	mu       sync.Mutex
+	buf      *buffer.RingBuffer[types.DiskMetrics]
+	io       map[string]disk.IOCountersStat // 上次 IO 计数
+	ioTime   time.Time
+	points   map[string][]diskPoint // 挂载点 -> 趋势窗口内的已用空间样本
+	loaded   bool                   // 是否已从历史存储加载趋势样本
+	reported map[string]bool        // 已上报的告警键，恢复前不重复上报
+}
+
+type diskPoint struct {
+	t    time.Time
+	used float64
+}
+
+func newDiskMonitor(cfg types.DiskConfig, bufLen int) *diskMonitor {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30
+	}
+	if cfg.UsedPercent <= 0 {
+		cfg.UsedPercent = 90
+	}
+	if cfg.InodePercent <= 0 {
+		cfg.InodePercent = 90
+	}
+	if cfg.ForecastHours == 0 {
+		cfg.ForecastHours = 24
+	}
+	if cfg.ForecastWindow <= 0 {
+		cfg.ForecastWindow = 360
+	}
+	return &diskMonitor{
+		cfg:      cfg,
+		buf:      buffer.NewRingBuffer[types.DiskMetrics](bufLen),
+		points:   make(map[string][]diskPoint),
+		reported: make(map[string]bool),
+	}
+}
+
+func (m *MultiMonitor) watchDisks(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(m.disks.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	m.sampleDisks()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.sampleDisks()
+		}
+	}
+}
+
+// sampleDisks 采集文件系统和磁盘 IO，写入历史并检查阈值和写满预测
+func (m *MultiMonitor) sampleDisks() {
+	d := m.disks
+	now := time.Now()
+	sample := types.DiskMetrics{Timestamp: now, Mounts: d.collectUsage(), Disks: d.collectIO(now)}
+
+	// 首次采样时从历史存储补齐趋势窗口，代理重启后无需重新积累
+	window := time.Duration(d.cfg.ForecastWindow) * time.Minute
+	d.mu.Lock()
+	needLoad := !d.loaded
+	d.loaded = true
+	d.mu.Unlock()
+	if needLoad && d.cfg.ForecastHours > 0 {
+		hist, _ := m.history.QueryDisk(history.Query{From: now.Add(-window), To: now})
+		d.mu.Lock()
+		for _, h := range hist {
+			for _, u := range h.Mounts {
+				d.points[u.Mount] = append(d.points[u.Mount], diskPoint{h.Timestamp, float64(u.Used)})
+			}
+		}
+		d.mu.Unlock()
+	}
+
+	d.mu.Lock()
+	for i := range sample.Mounts {
+		u := &sample.Mounts[i]
+		points := append(d.points[u.Mount], diskPoint{now, float64(u.Used)})
+		cut := 0
+		for cut < len(points) && now.Sub(points[cut].t) > window {
+			cut++
+		}
+		d.points[u.Mount] = points[cut:]
+		d.forecast(u, d.points[u.Mount], now)
+	}
+	// 已卸载的挂载点不再保留样本
+	for mount := range d.points {
+		if !hasMount(sample.Mounts, mount) {
+			delete(d.points, mount)
+		}
+	}
+	d.buf.Push(sample)
+	events := d.evaluate(sample)
+	d.mu.Unlock()
+
+	m.history.WriteDisk(sample)
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+}
+
+func hasMount(mounts []types.DiskUsage, mount string) bool {
+	for _, u := range mounts {
+		if u.Mount == mount {
+			return true
+		}
+	}
+	return false
+}
+
+// collectUsage 按包含/排除规则采集各挂载点的空间和 inode 使用
+func (d *diskMonitor) collectUsage() []types.DiskUsage {
+	parts, err := disk.Partitions(false)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	result := []types.DiskUsage{}
+	for _, p := range parts {
+		if seen[p.Mountpoint] || diskSkipFSTypes[p.Fstype] || !d.includeMount(p.Mountpoint) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+		u, err := disk.Usage(p.Mountpoint)
+		if err != nil || u.Total == 0 {
+			continue
+		}
+		result = append(result, types.DiskUsage{
+			Mount: p.Mountpoint, Device: p.Device, FSType: p.Fstype,
+			Total: u.Total, Used: u.Used, Free: u.Free, UsedPercent: u.UsedPercent,
+			InodesTotal: u.InodesTotal, InodesUsed: u.InodesUsed, InodesFree: u.InodesFree, InodesPercent: u.InodesUsedPercent,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Mount < result[j].Mount })
+	return result
+}
+
+func (d *diskMonitor) includeMount(mount string) bool {
+	for _, p := range d.cfg.Exclude {
+		if ok, _ := filepath.Match(p, mount); ok {
+			return false
+		}
+	}
+	if len(d.cfg.Include) == 0 {
+		return true
+	}
+	for _, p := range d.cfg.Include {
+		if ok, _ := filepath.Match(p, mount); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// collectIO 计算各磁盘两次采样间的吞吐、平均延迟和利用率（回环和内存盘除外）
+func (d *diskMonitor) collectIO(now time.Time) []types.DiskIOStats {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	prev, elapsed := d.io, now.Sub(d.ioTime).Seconds()
+	d.io, d.ioTime = counters, now
+	if prev == nil || elapsed <= 0 {
+		return nil
+	}
+
+	var result []types.DiskIOStats
+	for name, c := range counters {
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+			continue
+		}
+		p, ok := prev[name]
+		if !ok || c.ReadCount < p.ReadCount || c.WriteCount < p.WriteCount {
+			continue // 新设备或计数器重置
+		}
+		reads, writes := float64(c.ReadCount-p.ReadCount), float64(c.WriteCount-p.WriteCount)
+		st := types.DiskIOStats{
+			Device:    name,
+			ReadRate:  float64(c.ReadBytes-p.ReadBytes) / elapsed,
+			WriteRate: float64(c.WriteBytes-p.WriteBytes) / elapsed,
+			ReadOps:   reads / elapsed,
+			WriteOps:  writes / elapsed,
+			Util:      float64(c.IoTime-p.IoTime) / (elapsed * 1000) * 100,
+		}
+		if reads > 0 {
+			st.ReadLatency = float64(c.ReadTime-p.ReadTime) / reads
+		}
+		if writes > 0 {
+			st.WriteLatency = float64(c.WriteTime-p.WriteTime) / writes
+		}
+		if st.Util > 100 {
+			st.Util = 100
+		}
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Device < result[j].Device })
+	return result
+}
+
+// forecast 拟合已用空间的增长趋势，增长显著时估算写满的剩余小时数（调用方持有 d.mu）
+func (d *diskMonitor) forecast(u *types.DiskUsage, points []diskPoint, now time.Time) {
+	if d.cfg.ForecastHours < 0 || len(points) < diskMinSamples {
+		return
+	}
+	xs := make([]float64, len(points))
+	ys := make([]float64, len(points))
+	for i, p := range points {
+		xs[i] = p.t.Sub(now).Hours()
+		ys[i] = p.used
+	}
+	fit, ok := stats.Fit(xs, ys)
+	if !ok || fit.Slope <= 0 || fit.Confidence < 0.95 {
+		return
+	}
+	u.GrowthPerHour = fit.Slope
+	// 剩余空间按 free 计算（不含 root 保留块）
+	u.FullInHours = float64(u.Free) / fit.Slope
+}
+
+// evaluate 检查空间、inode 阈值和写满预测，返回需要产生的事件（调用方持有 d.mu）
+func (d *diskMonitor) evaluate(sample types.DiskMetrics) []types.Event {
+	var events []types.Event
+	raise := func(key string, bad bool, u types.DiskUsage, typ, msg, recoveredTyp, recoveredMsg string) {
+		details := map[string]interface{}{
+			"mount": u.Mount, "device": u.Device, "used_percent": u.UsedPercent, "free": u.Free, "inodes_percent": u.InodesPercent,
+		}
+		if u.FullInHours > 0 {
+			details["full_in_hours"] = u.FullInHours
+			details["growth_per_hour"] = u.GrowthPerHour
+		}
+		switch {
+		case bad && !d.reported[key]:
+			d.reported[key] = true
+			events = append(events, types.Event{Timestamp: sample.Timestamp, Type: typ, Name: "disk:" + u.Mount, Message: msg, Details: details})
+		case !bad && d.reported[key]:
+			delete(d.reported, key)
+			if recoveredTyp != "" {
+				events = append(events, types.Event{Timestamp: sample.Timestamp, Type: recoveredTyp, Name: "disk:" + u.Mount, Message: recoveredMsg, Details: details})
+			}
+		}
+	}
+
+	for _, u := range sample.Mounts {
+		raise("space:"+u.Mount, u.UsedPercent >= d.cfg.UsedPercent, u,
+			"disk_space", fmt.Sprintf("%s 空间使用率 %.1f%%，超过 %.0f%%（剩余 %.2f GB）", u.Mount, u.UsedPercent, d.cfg.UsedPercent, float64(u.Free)/1024/1024/1024),
+			"disk_space_recovered", fmt.Sprintf("%s 空间使用率降至 %.1f%%", u.Mount, u.UsedPercent))
+		raise("inodes:"+u.Mount, u.InodesTotal > 0 && u.InodesPercent >= d.cfg.InodePercent, u,
+			"disk_inodes", fmt.Sprintf("%s inode 使用率 %.1f%%，超过 %.0f%%（剩余 %d）", u.Mount, u.InodesPercent, d.cfg.InodePercent, u.InodesFree),
+			"disk_inodes_recovered", fmt.Sprintf("%s inode 使用率降至 %.1f%%", u.Mount, u.InodesPercent))
+		raise("forecast:"+u.Mount, u.FullInHours > 0 && u.FullInHours <= d.cfg.ForecastHours, u,
+			"disk_full_forecast", fmt.Sprintf("%s 按当前增长 %.1f MB/小时 预计 %.1f 小时后写满", u.Mount, u.GrowthPerHour/1024/1024, u.FullInHours),
+			"", "")
+	}
+	// 已卸载的挂载点清除告警状态
+	for key := range d.reported {
+		_, mount, _ := strings.Cut(key, ":")
+		if !hasMount(sample.Mounts, mount) {
+			delete(d.reported, key)
+		}
+	}
+	return events
+}
+
+// GetDiskMetrics 获取最近 n 次磁盘采样，未启用时返回错误
+func (m *MultiMonitor) GetDiskMetrics(n int) ([]types.DiskMetrics, error) {
+	if m.disks == nil {
+		return nil, fmt.Errorf("disk monitoring not enabled")
+	}
+	m.disks.mu.Lock()
+	defer m.disks.mu.Unlock()
+	return m.disks.buf.GetRecent(n), nil
+}
@@ -0,0 +1,281 @@
+package monitor
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// forensicsTriggers 触发现场采集的事件类型
+var forensicsTriggers = map[string]bool{
+	"exit":          true,
+	"cpu_threshold": true,
+	"mem_threshold": true,
+	"hung":          true,
+	"hung_task":     true,
+}
+
+// forensicsRecorder 崩溃现场记录器：在退出/超限事件时保存现场包（tar.gz + 清单）
+type forensicsRecorder struct {
+	cfg  types.ForensicsConfig
+	mu   sync.Mutex
+	last map[string]time.Time // 目标名称/事件类型 -> 最近一次采集时间
+}
+
+func newForensicsRecorder(cfg types.ForensicsConfig, logDir string) *forensicsRecorder {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(logDir, "forensics")
+	}
+	if cfg.Minutes <= 0 {
+		cfg.Minutes = 5
+	}
+	if cfg.Events <= 0 {
+		cfg.Events = 50
+	}
+	if cfg.TopN <= 0 {
+		cfg.TopN = 20
+	}
+	if cfg.RetentionDays <= 0 {
+		cfg.RetentionDays = 30
+	}
+	if cfg.MaxPerTarget <= 0 {
+		cfg.MaxPerTarget = 20
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = 60
+	}
+	os.MkdirAll(cfg.Dir, 0755)
+	return &forensicsRecorder{cfg: cfg, last: make(map[string]time.Time)}
+}
+
+// allow 同一目标的同类事件在最小间隔内只采集一次，避免超限风暴产生大量现场包
+func (f *forensicsRecorder) allow(name, trigger string, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := name + "/" + trigger
+	if now.Sub(f.last[key]) < time.Duration(f.cfg.MinInterval)*time.Second {
+		return false
+	}
+	f.last[key] = now
+	return true
+}
+
+// captureForensics 在事件记录前快照内存中的指标、事件和 /proc 信息（调用方随后可能立即终止进程），
+// 现场包在后台写入，不阻塞采样和托管路径。主动停止（details.planned）的退出不采集。现场包 ID 写入事件 details.forensics。
+func (m *MultiMonitor) captureForensics(evt *types.Event) {
+	f := m.forensics
+	if f == nil || !forensicsTriggers[evt.Type] || detailPlanned(evt.Details) || !f.allow(evt.Name, evt.Type, evt.Timestamp) {
+		return
+	}
+
+	id := fmt.Sprintf("%s_%d_%s_%s", safeFileName(evt.Name), evt.PID, evt.Timestamp.Format("20060102_150405"), evt.Type)
+
+	var metrics []types.ProcessMetrics
+	since := evt.Timestamp.Add(-time.Duration(f.cfg.Minutes) * time.Minute)
+	m.mu.RLock()
+	buf := m.metricsBuffers[evt.PID]
+	m.mu.RUnlock()
+	if buf != nil {
+		for _, met := range buf.GetAll() {
+			if !met.Timestamp.Before(since) {
+				metrics = append(metrics, met)
+			}
+		}
+	}
+	events := append(m.eventsBuffer.GetRecent(f.cfg.Events), *evt)
+
+	if evt.Details == nil {
+		evt.Details = make(map[string]interface{})
+	}
+	evt.Details["forensics"] = id
+
+	bundle := types.ForensicsBundle{
+		ID:        id,
+		Name:      evt.Name,
+		PID:       evt.PID,
+		Trigger:   evt.Type,
+		Message:   evt.Message,
+		Timestamp: evt.Timestamp,
+	}
+	// 退出事件时进程已不存在（PID 可能已被复用），不采集进程详情
+	var procFiles map[string][]byte
+	if evt.Type != "exit" && m.provider.IsAlive(evt.PID) {
+		procFiles = captureProcFiles(evt.PID)
+	}
+	go func() {
+		if err := m.writeForensics(bundle, metrics, events, procFiles); err != nil {
+			log.Printf("[WARN] 保存现场包失败 %s: %v", id, err)
+			return
+		}
+		log.Printf("[INFO] 已保存现场包: %s", id)
+		f.cleanup(bundle.Name)
+	}()
+}
+
+func detailPlanned(details map[string]interface{}) bool {
+	planned, _ := details["planned"].(bool)
+	return planned
+}
+
+// writeForensics 写入现场包：先写临时文件再 rename，清单单独保存便于列表查询
+func (m *MultiMonitor) writeForensics(bundle types.ForensicsBundle, metrics []types.ProcessMetrics, events []types.Event, procFiles map[string][]byte) error {
+	f := m.forensics
+	files := map[string][]byte{}
+	addJSON := func(name string, v any) {
+		if data, err := json.MarshalIndent(v, "", "  "); err == nil {
+			files[name] = data
+		}
+	}
+	addJSON("metrics.json", metrics)
+	addJSON("events.json", events)
+
+	if procs, err := m.provider.ListAllProcesses(); err == nil {
+		n := f.cfg.TopN
+		if n > len(procs) {
+			n = len(procs)
+		}
+		sort.Slice(procs, func(i, j int) bool { return procs[i].CPUPct > procs[j].CPUPct })
+		addJSON("top_cpu.json", procs[:n])
+		sort.Slice(procs, func(i, j int) bool { return procs[i].RSSBytes > procs[j].RSSBytes })
+		addJSON("top_mem.json", procs[:n])
+	}
+	if sys, err := m.provider.GetSystemMetrics(); err == nil {
+		addJSON("system.json", sys)
+	}
+	for name, data := range procFiles {
+		files["proc/"+name] = data
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bundle.Files = append([]string{"manifest.json"}, names...)
+	manifest, _ := json.MarshalIndent(bundle, "", "  ")
+
+	path := filepath.Join(f.cfg.Dir, bundle.ID+".tar.gz")
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	writeEntry := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: bundle.ID + "/" + name, Mode: 0644, Size: int64(len(data)), ModTime: bundle.Timestamp}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	err = writeEntry("manifest.json", manifest)
+	for _, name := range names {
+		if err != nil {
+			break
+		}
+		err = writeEntry(name, files[name])
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		bundle.Size = info.Size()
+	}
+	manifest, _ = json.MarshalIndent(bundle, "", "  ")
+	return os.WriteFile(filepath.Join(f.cfg.Dir, bundle.ID+".json"), manifest, 0644)
+}
+
+// list 读取所有现场包清单（按时间倒序）
+func (f *forensicsRecorder) list() []types.ForensicsBundle {
+	result := []types.ForensicsBundle{}
+	matches, _ := filepath.Glob(filepath.Join(f.cfg.Dir, "*.json"))
+	for _, p := range matches {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var b types.ForensicsBundle
+		if json.Unmarshal(data, &b) == nil && b.ID != "" {
+			result = append(result, b)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp.After(result[j].Timestamp) })
+	return result
+}
+
+// cleanup 删除超过保留天数的现场包，并限制单个目标的现场包数量
+func (f *forensicsRecorder) cleanup(name string) {
+	cutoff := time.Now().AddDate(0, 0, -f.cfg.RetentionDays)
+	kept := 0
+	for _, b := range f.list() {
+		if b.Name == name {
+			kept++
+		}
+		if b.Timestamp.Before(cutoff) || (b.Name == name && kept > f.cfg.MaxPerTarget) {
+			f.remove(b.ID)
+		}
+	}
+}
+
+func (f *forensicsRecorder) remove(id string) {
+	os.Remove(filepath.Join(f.cfg.Dir, id+".tar.gz"))
+	os.Remove(filepath.Join(f.cfg.Dir, id+".json"))
+}
+
+// ListForensics 列出现场包（name 为空时返回全部）
+func (m *MultiMonitor) ListForensics(name string) []types.ForensicsBundle {
+	if m.forensics == nil {
+		return []types.ForensicsBundle{}
+	}
+	result := []types.ForensicsBundle{}
+	for _, b := range m.forensics.list() {
+		if name == "" || b.Name == name {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// ForensicsPath 获取现场包文件路径
+func (m *MultiMonitor) ForensicsPath(id string) (string, error) {
+	if m.forensics == nil {
+		return "", fmt.Errorf("forensics disabled")
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return "", fmt.Errorf("invalid bundle id")
+	}
+	path := filepath.Join(m.forensics.cfg.Dir, id+".tar.gz")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("bundle %s not found", id)
+	}
+	return path, nil
+}
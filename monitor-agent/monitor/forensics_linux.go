@@ -0,0 +1,68 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// captureProcFiles 读取 /proc/<pid> 下的进程现场：状态、资源限制、内存映射、打开的文件和线程
+func captureProcFiles(pid int32) map[string][]byte {
+	dir := fmt.Sprintf("/proc/%d", pid)
+	files := make(map[string][]byte)
+	for _, name := range []string{"status", "limits", "maps", "stat", "io", "wchan"} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			files[name] = data
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		files["cmdline"] = bytes.ReplaceAll(bytes.TrimRight(data, "\x00"), []byte{0}, []byte{' '})
+	}
+
+	// 打开的文件：fd -> 目标
+	if entries, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		sort.Slice(entries, func(i, j int) bool {
+			a, _ := strconv.Atoi(entries[i].Name())
+			b, _ := strconv.Atoi(entries[j].Name())
+			return a < b
+		})
+		var sb strings.Builder
+		for _, e := range entries {
+			link, err := os.Readlink(filepath.Join(dir, "fd", e.Name()))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&sb, "%s\t%s\n", e.Name(), link)
+		}
+		files["fds.txt"] = []byte(sb.String())
+	}
+
+	// 线程：tid、名称、状态、等待通道
+	if entries, err := os.ReadDir(filepath.Join(dir, "task")); err == nil {
+		var sb strings.Builder
+		sb.WriteString("tid\tname\tstate\twchan\n")
+		for _, e := range entries {
+			taskDir := filepath.Join(dir, "task", e.Name())
+			comm, _ := os.ReadFile(filepath.Join(taskDir, "comm"))
+			wchan, _ := os.ReadFile(filepath.Join(taskDir, "wchan"))
+			state := ""
+			if status, err := os.ReadFile(filepath.Join(taskDir, "status")); err == nil {
+				for _, line := range strings.Split(string(status), "\n") {
+					if strings.HasPrefix(line, "State:") {
+						state = strings.TrimSpace(strings.TrimPrefix(line, "State:"))
+						break
+					}
+				}
+			}
+			fmt.Fprintf(&sb, "%s\t%s\t%s\t%s\n", e.Name(), strings.TrimSpace(string(comm)), state, strings.TrimSpace(string(wchan)))
+		}
+		files["threads.txt"] = []byte(sb.String())
+	}
+	return files
+}
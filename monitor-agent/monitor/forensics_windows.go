@@ -0,0 +1,63 @@
+//go:build windows
+
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// captureProcFiles Windows 下没有 /proc，通过 gopsutil 采集进程基本信息、内存、线程和打开的文件
+func captureProcFiles(pid int32) map[string][]byte {
+	files := make(map[string][]byte)
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return files
+	}
+
+	status := map[string]interface{}{"pid": pid}
+	if v, err := proc.Name(); err == nil {
+		status["name"] = v
+	}
+	if v, err := proc.Exe(); err == nil {
+		status["exe"] = v
+	}
+	if v, err := proc.Cmdline(); err == nil {
+		status["cmdline"] = v
+	}
+	if v, err := proc.Username(); err == nil {
+		status["username"] = v
+	}
+	if v, err := proc.MemoryInfo(); err == nil {
+		status["memory"] = v
+	}
+	if v, err := proc.NumThreads(); err == nil {
+		status["num_threads"] = v
+	}
+	if v, err := proc.Times(); err == nil {
+		status["cpu_times"] = v
+	}
+	if v, err := proc.CreateTime(); err == nil {
+		status["create_time"] = v
+	}
+	if data, err := json.MarshalIndent(status, "", "  "); err == nil {
+		files["status.json"] = data
+	}
+
+	if list, err := proc.OpenFiles(); err == nil {
+		var sb strings.Builder
+		for _, f := range list {
+			fmt.Fprintf(&sb, "%d\t%s\n", f.Fd, f.Path)
+		}
+		files["fds.txt"] = []byte(sb.String())
+	}
+	if threads, err := proc.Threads(); err == nil {
+		if data, err := json.MarshalIndent(threads, "", "  "); err == nil {
+			files["threads.json"] = data
+		}
+	}
+	return files
+}
@@ -0,0 +1,167 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"monitor-agent/types"
+)
+
+const freshnessReadLimit = 1 << 20
+
+// freshnessState 单项新鲜度检查状态
+type freshnessState struct {
+	stale     bool      // 当前处于过期状态
+	lastAlarm time.Time // 最近一次上报 file_stale 的时间
+	// 内容匹配缓存：文件未变化时不重复读取
+	contentKey string
+	contentOK  bool
+}
+
+// validateFreshness 检查新鲜度检查配置
+func validateFreshness(checks []types.FreshnessCheck) error {
+	for _, c := range checks {
+		if c.Path == "" {
+			return fmt.Errorf("freshness path required")
+		}
+		if _, err := filepath.Match(c.Path, ""); err != nil {
+			return fmt.Errorf("invalid freshness path %q: %v", c.Path, err)
+		}
+		if c.MaxAge <= 0 && c.MinSize <= 0 && c.Content == "" {
+			return fmt.Errorf("freshness check for %s has no condition", c.Path)
+		}
+		if _, err := regexp.Compile(c.Content); err != nil {
+			return fmt.Errorf("invalid freshness content for %s: %v", c.Path, err)
+		}
+	}
+	return nil
+}
+
+// checkFreshness 检查目标的输出文件：过期时产生 file_stale 事件并按重启配置重启，
+// 仍未恢复时每隔 max_age（未配置时 60 秒）再次上报；恢复时产生 file_fresh 事件
+func (m *MultiMonitor) checkFreshness(state *targetState, target types.MonitorTarget) {
+	if len(target.Freshness) == 0 {
+		return
+	}
+	now := time.Now()
+	var events []types.Event
+	restart := false
+
+	for i, c := range target.Freshness {
+		m.mu.Lock()
+		if len(state.freshness) != len(target.Freshness) {
+			state.freshness = make([]freshnessState, len(target.Freshness))
+		}
+		fs := state.freshness[i]
+		m.mu.Unlock()
+
+		path, reason, details := evalFreshness(c, &fs, now)
+
+		realarm := time.Duration(c.MaxAge) * time.Second
+		if realarm <= 0 {
+			realarm = time.Minute
+		}
+		details["path"] = path
+		switch {
+		case reason != "" && (!fs.stale || now.Sub(fs.lastAlarm) >= realarm):
+			fs.stale, fs.lastAlarm = true, now
+			details["reason"] = reason
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "file_stale",
+				PID:       target.PID,
+				Name:      target.Name,
+				Message:   fmt.Sprintf("输出文件 %s %s", path, reason),
+				Details:   details,
+			})
+			restart = true
+		case reason == "" && fs.stale:
+			fs.stale = false
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "file_fresh",
+				PID:       target.PID,
+				Name:      target.Name,
+				Message:   fmt.Sprintf("输出文件 %s 已恢复更新", path),
+				Details:   details,
+			})
+		}
+
+		m.mu.Lock()
+		if i < len(state.freshness) {
+			state.freshness[i] = fs
+		}
+		m.mu.Unlock()
+	}
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+	// 与 CPU/内存阈值相同：配置了重启命令（或为托管进程）时重启
+	if restart && (target.RestartCmd != "" || target.Supervise != nil) {
+		m.tryRestart(target.PID, "file_stale")
+	}
+}
+
+// evalFreshness 判断单项检查，返回实际检查的文件和过期原因（为空表示正常）
+func evalFreshness(c types.FreshnessCheck, fs *freshnessState, now time.Time) (string, string, map[string]interface{}) {
+	details := map[string]interface{}{"max_age": c.MaxAge}
+
+	// 通配符取最近修改的文件
+	matches, _ := filepath.Glob(c.Path)
+	var path string
+	var info os.FileInfo
+	for _, p := range matches {
+		fi, err := os.Stat(p)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		if info == nil || fi.ModTime().After(info.ModTime()) {
+			path, info = p, fi
+		}
+	}
+	if info == nil {
+		return c.Path, "不存在", details
+	}
+
+	age := now.Sub(info.ModTime())
+	details["age"] = int(age.Seconds())
+	details["size"] = info.Size()
+	if c.MaxAge > 0 && age > time.Duration(c.MaxAge)*time.Second {
+		return path, fmt.Sprintf("已 %d 秒未更新（上限 %d 秒）", int(age.Seconds()), c.MaxAge), details
+	}
+	if c.MinSize > 0 && info.Size() < c.MinSize {
+		return path, fmt.Sprintf("大小 %d 字节，小于 %d 字节", info.Size(), c.MinSize), details
+	}
+	if c.Content != "" {
+		key := fmt.Sprintf("%s|%d|%d", path, info.Size(), info.ModTime().UnixNano())
+		if key != fs.contentKey {
+			fs.contentKey, fs.contentOK = key, matchFileContent(path, c.Content)
+		}
+		if !fs.contentOK {
+			return path, fmt.Sprintf("内容不匹配 %q", c.Content), details
+		}
+	}
+	return path, "", details
+}
+
+func matchFileContent(path, pattern string) bool {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, freshnessReadLimit))
+	if err != nil {
+		return false
+	}
+	return re.Match(data)
+}
@@ -0,0 +1,346 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"monitor-agent/history"
+	"monitor-agent/types"
+)
+
+// groupAlarmWindow 成员最近的告警事件计入分组健康状态的时间窗口
+const groupAlarmWindow = 5 * time.Minute
+
+// groupAlarmEvents 计为 warning 的告警事件
+var groupAlarmEvents = map[string]bool{
+	"cpu_threshold":    true,
+	"mem_threshold":    true,
+	"leak_suspected":   true,
+	"anomaly":          true,
+	"restart":          true,
+	"segfault":         true,
+	"hung_task":        true,
+	"listen_missing":   true,
+	"peer_lost":        true,
+	"close_wait_leak":  true,
+	"file_stale":       true,
+	"heartbeat_missed": true,
+	"custom_threshold": true,
+}
+
+var healthRank = map[string]int{"ok": 0, "warning": 1, "critical": 2, "down": 3}
+
+// AddGroup 添加应用分组（同名分组覆盖）
+func (m *MultiMonitor) AddGroup(group types.AppGroup) error {
+	if group.Name == "" {
+		return fmt.Errorf("group name required")
+	}
+	if len(group.Members) == 0 {
+		return fmt.Errorf("group %s has no members", group.Name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.groups == nil {
+		m.groups = make(map[string]types.AppGroup)
+	}
+	m.groups[group.Name] = group
+	log.Printf("[INFO] Added group: %s (%s)", group.Name, strings.Join(group.Members, ","))
+	return nil
+}
+
+// RemoveGroup 移除应用分组（不影响成员）
+func (m *MultiMonitor) RemoveGroup(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.groups[name]; !ok {
+		return fmt.Errorf("group %s not found", name)
+	}
+	delete(m.groups, name)
+	log.Printf("[INFO] Removed group: %s", name)
+	return nil
+}
+
+// groupMembersLocked 分组成员对应的监控目标（按 PID 排序），未监控的成员名称单独返回（调用方持有锁）
+func (m *MultiMonitor) groupMembersLocked(name string) (types.AppGroup, []*targetState, []string, error) {
+	group, ok := m.groups[name]
+	if !ok {
+		return group, nil, nil, fmt.Errorf("group %s not found", name)
+	}
+	var states []*targetState
+	var missing []string
+	seen := make(map[int32]bool)
+	for _, ref := range group.Members {
+		found := false
+		for pid, state := range m.targets {
+			if depMatches(state.target, ref) {
+				found = true
+				if !seen[pid] {
+					seen[pid] = true
+					states = append(states, state)
+				}
+			}
+		}
+		if !found {
+			missing = append(missing, ref)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].target.PID < states[j].target.PID })
+	return group, states, missing, nil
+}
+
+// GetGroups 获取所有分组的汇总状态（按名称排序）
+func (m *MultiMonitor) GetGroups() []types.GroupStatus {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.groups))
+	for name := range m.groups {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	result := make([]types.GroupStatus, 0, len(names))
+	for _, name := range names {
+		if st, err := m.GetGroupStatus(name); err == nil {
+			result = append(result, *st)
+		}
+	}
+	return result
+}
+
+// GetGroupStatus 汇总分组健康状态：全部成员停止为 down，有成员停止或挂死为 critical，
+// 有成员超阈值、降级或近期有告警为 warning；CPU/内存为成员之和
+func (m *MultiMonitor) GetGroupStatus(name string) (*types.GroupStatus, error) {
+	// 近期告警（按目标名称）
+	alarms := make(map[string]string)
+	cutoff := time.Now().Add(-groupAlarmWindow)
+	for _, evt := range m.eventsBuffer.GetAll() {
+		if !groupAlarmEvents[evt.Type] || evt.Timestamp.Before(cutoff) {
+			continue
+		}
+		// 人工启动产生的 restart 事件不算告警
+		if reason, _ := evt.Details["reason"].(string); evt.Type == "restart" && (reason == "start" || reason == "group_start") {
+			continue
+		}
+		alarms[evt.Name] = evt.Type
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	group, states, missing, err := m.groupMembersLocked(name)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &types.GroupStatus{Group: group, Health: "ok", Members: make([]types.GroupMemberStatus, 0, len(states)+len(missing))}
+	worst := ""
+	for _, ref := range missing {
+		st.Members = append(st.Members, types.GroupMemberStatus{Ref: ref, Health: "down", Reason: "未监控"})
+		st.Total++
+		worst = worseHealth(worst, "critical")
+	}
+	for _, state := range states {
+		t := state.target
+		ms := types.GroupMemberStatus{Ref: depKey(t), PID: t.PID, Name: t.Name, Health: "ok"}
+		if t.Disabled {
+			ms.Health, ms.Reason = "disabled", "已暂停监控"
+			st.Members = append(st.Members, ms)
+			continue
+		}
+		st.Total++
+
+		alive := false
+		if state.lastMetric != nil {
+			alive = state.lastMetric.Alive
+			if alive {
+				ms.CPUPct, ms.RSSBytes = state.lastMetric.CPUPct, state.lastMetric.RSSBytes
+			}
+		} else {
+			alive = m.provider.IsAlive(t.PID)
+		}
+		switch {
+		case !alive:
+			ms.Health, ms.Reason = "down", "进程未运行"
+			if state.held {
+				ms.Reason = "已人工停止"
+			}
+		case state.hang != nil && state.hang.hung:
+			ms.Health, ms.Reason = "critical", "疑似挂死"
+		case state.dep != nil && len(state.dep.degradedBy) > 0:
+			ms.Health, ms.Reason = "warning", "上游 "+strings.Join(state.dep.degradedBy, ",")+" 未就绪"
+		case state.cpuExceedCnt > 0 || state.memExceedCnt > 0:
+			ms.Health, ms.Reason = "warning", "超过阈值"
+		case alarms[t.Name] != "":
+			ms.Health, ms.Reason = "warning", "近期告警 "+alarms[t.Name]
+		}
+		if alive {
+			st.Alive++
+		}
+		st.CPUPct += ms.CPUPct
+		st.RSSBytes += ms.RSSBytes
+		// 单个成员停止时分组为 critical，全部停止时为 down
+		if ms.Health == "down" {
+			worst = worseHealth(worst, "critical")
+		} else {
+			worst = worseHealth(worst, ms.Health)
+		}
+		st.Members = append(st.Members, ms)
+	}
+
+	if worst != "" {
+		st.Health = worst
+	}
+	if st.Total > 0 && st.Alive == 0 {
+		st.Health = "down"
+	}
+	return st, nil
+}
+
+func worseHealth(a, b string) string {
+	if a == "" || healthRank[b] > healthRank[a] {
+		return b
+	}
+	return a
+}
+
+// SetGroupEnabled 暂停/恢复分组全部成员的监控
+func (m *MultiMonitor) SetGroupEnabled(name string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, states, _, err := m.groupMembersLocked(name)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		state.target.Disabled = !enabled
+		// 恢复监控时重新开始超限计数
+		state.cpuExceedCnt, state.memExceedCnt = 0, 0
+	}
+	log.Printf("[INFO] Group %s monitoring enabled=%v (%d targets)", name, enabled, len(states))
+	return nil
+}
+
+// StopGroup 人工停止分组全部成员：托管进程终止后等待启动，非托管进程直接结束且不自动重启
+func (m *MultiMonitor) StopGroup(name string) error {
+	m.mu.RLock()
+	_, states, _, err := m.groupMembersLocked(name)
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	// 按依赖逆序停止，下游先停
+	levels := m.targetLevels()
+	sort.SliceStable(states, func(i, j int) bool { return levels[states[i].target.PID] > levels[states[j].target.PID] })
+
+	var failed []string
+	for _, state := range states {
+		if state.sup != nil {
+			m.holdSupervised(state)
+			continue
+		}
+		m.mu.Lock()
+		state.held = true
+		pid := state.target.PID
+		m.mu.Unlock()
+		if m.provider.IsAlive(pid) {
+			if err := m.provider.KillProcess(pid); err != nil {
+				failed = append(failed, fmt.Sprintf("%s(%d): %v", state.target.Name, pid, err))
+			}
+		}
+	}
+	log.Printf("[INFO] Group %s stopped (%d targets)", name, len(states))
+	if len(failed) > 0 {
+		return fmt.Errorf("stop failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// StartGroup 按依赖顺序启动分组中未运行的成员：托管进程重新拉起，非托管进程执行重启命令
+func (m *MultiMonitor) StartGroup(name string) error {
+	m.mu.RLock()
+	_, states, _, err := m.groupMembersLocked(name)
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	levels := m.targetLevels()
+	sort.SliceStable(states, func(i, j int) bool { return levels[states[i].target.PID] < levels[states[j].target.PID] })
+
+	var failed []string
+	for _, state := range states {
+		m.mu.Lock()
+		target := state.target
+		held := state.held
+		m.mu.Unlock()
+
+		if state.sup != nil {
+			if held {
+				m.resumeSupervised(state)
+			} else if !m.provider.IsAlive(target.PID) {
+				failed = append(failed, fmt.Sprintf("%s: 托管已结束", target.Name))
+			}
+			continue
+		}
+		if m.provider.IsAlive(target.PID) {
+			m.mu.Lock()
+			state.held = false
+			m.mu.Unlock()
+			continue
+		}
+		if target.RestartCmd == "" {
+			failed = append(failed, fmt.Sprintf("%s: 未配置重启命令", target.Name))
+			continue
+		}
+		if m.integrityBlocksRestart(state, "group_start") {
+			failed = append(failed, fmt.Sprintf("%s: 完整性校验不一致", target.Name))
+			continue
+		}
+		m.mu.Lock()
+		state.held = false
+		state.lastRestart = time.Now()
+		state.restartCount++
+		count := state.restartCount
+		m.mu.Unlock()
+		m.runRestartCmd(target.PID, target.Name, target.RestartCmd, "group_start", count)
+	}
+	log.Printf("[INFO] Group %s started (%d targets)", name, len(states))
+	if len(failed) > 0 {
+		return fmt.Errorf("start failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// targetLevels 目标 PID 对应的依赖层级
+func (m *MultiMonitor) targetLevels() map[int32]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	nodes := m.dependencyNodesLocked(0)
+	levels := dependencyLevels(nodes)
+	result := make(map[int32]int, len(nodes))
+	for i, n := range nodes {
+		result[n.PID] = levels[i]
+	}
+	return result
+}
+
+// GroupEvents 查询分组成员的历史事件（含以分组名记录的事件），q.Name 被忽略
+func (m *MultiMonitor) GroupEvents(name string, q history.Query) ([]types.Event, error) {
+	m.mu.RLock()
+	group, states, missing, err := m.groupMembersLocked(name)
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{group.Name}
+	names = append(names, missing...)
+	for _, state := range states {
+		names = append(names, state.target.Name)
+	}
+	q.Name = ""
+	q.Names = uniqueSorted(names)
+	return m.history.QueryEvents(q)
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"monitor-agent/types"
+)
+
+// hangState 挂死检测状态
+type hangState struct {
+	last          *types.ProcessCounters
+	lastProgress  time.Time // 计数最近一次推进的时间
+	badState      string    // 当前异常状态（blocked/stop）
+	badStateSince time.Time
+	hung          bool // 已报告挂死，恢复前不重复报告
+}
+
+// checkHang 基于原始计数判断进程是否挂死：
+// 1. 持续处于 D（不可中断睡眠）/T（停止）状态
+// 2. 预期忙碌时段内，CPU 时间、IO 字节和上下文切换均停止推进
+func (m *MultiMonitor) checkHang(state *targetState, target types.MonitorTarget) {
+	cfg := target.Hang
+	if cfg == nil || (cfg.StateDuration <= 0 && cfg.StallDuration <= 0) {
+		return
+	}
+	counters, err := m.provider.GetCounters(target.PID)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	hs := state.hang
+	if hs == nil {
+		hs = &hangState{lastProgress: now}
+		state.hang = hs
+	}
+
+	if hs.last == nil || counters.CPUTime > hs.last.CPUTime || counters.ReadBytes != hs.last.ReadBytes ||
+		counters.WriteBytes != hs.last.WriteBytes || counters.CtxSwitches != hs.last.CtxSwitches {
+		hs.lastProgress = now
+	}
+	hs.last = counters
+
+	if counters.Status == process.Blocked || counters.Status == process.Stop {
+		if hs.badState != counters.Status {
+			hs.badState = counters.Status
+			hs.badStateSince = now
+		}
+	} else {
+		hs.badState = ""
+	}
+
+	var reason string
+	details := map[string]interface{}{"status": counters.Status}
+	if cfg.StateDuration > 0 && hs.badState != "" && now.Sub(hs.badStateSince) >= time.Duration(cfg.StateDuration)*time.Second {
+		reason = fmt.Sprintf("持续处于 %s 状态 %d 秒", hs.badState, int(now.Sub(hs.badStateSince).Seconds()))
+		details["reason"] = "state"
+		details["duration"] = int(now.Sub(hs.badStateSince).Seconds())
+	} else if cfg.StallDuration > 0 && inBusyWindow(cfg.BusyWindows, now) && now.Sub(hs.lastProgress) >= time.Duration(cfg.StallDuration)*time.Second {
+		reason = fmt.Sprintf("CPU 时间/IO/上下文切换 %d 秒无推进", int(now.Sub(hs.lastProgress).Seconds()))
+		details["reason"] = "stall"
+		details["duration"] = int(now.Sub(hs.lastProgress).Seconds())
+		details["cpu_time"] = counters.CPUTime
+		details["ctx_switches"] = counters.CtxSwitches
+	}
+
+	wasHung := hs.hung
+	hs.hung = reason != ""
+	m.mu.Unlock()
+
+	if reason == "" {
+		if wasHung {
+			m.addEvent(types.Event{
+				Timestamp: now,
+				Type:      "hung_recovered",
+				PID:       target.PID,
+				Name:      target.Name,
+				Message:   "进程已恢复推进",
+			})
+		}
+		return
+	}
+	if wasHung {
+		return
+	}
+
+	m.addEvent(types.Event{
+		Timestamp: now,
+		Type:      "hung",
+		PID:       target.PID,
+		Name:      target.Name,
+		Message:   "进程疑似挂死: " + reason,
+		Details:   details,
+	})
+
+	if !cfg.Restart {
+		return
+	}
+	if target.Supervise != nil {
+		m.tryRestart(target.PID, "hung")
+	} else if target.RestartCmd != "" {
+		// 挂死进程不会自行退出，确定重启时先结束旧进程再执行重启命令
+		m.restartTarget(target.PID, "hung", true)
+	}
+}
+
+// inBusyWindow 判断当前是否处于预期忙碌时段，未配置时始终返回 true
+func inBusyWindow(windows []string, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	cur := now.Hour()*60 + now.Minute()
+	for _, w := range windows {
+		parts := strings.SplitN(w, "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		start, err1 := parseClock(parts[0])
+		end, err2 := parseClock(parts[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if start <= end {
+			if cur >= start && cur < end {
+				return true
+			}
+		} else if cur >= start || cur < end { // 跨零点，如 22:00-06:00
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 解析 HH:MM 为当天分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,307 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// heartbeatUnknownLimit 记录的未绑定心跳应用数上限
+const heartbeatUnknownLimit = 256
+
+// heartbeatState 目标心跳状态
+type heartbeatState struct {
+	since     time.Time // 开始等待心跳的时间（添加目标或进程重启），超时从此时起算
+	last      time.Time
+	seq       uint64
+	received  uint64
+	lost      uint64
+	resets    uint64
+	status    map[string]interface{}
+	custom    map[string]float64 // 数值型状态字段
+	missed    bool
+	lastAlarm time.Time
+}
+
+// heartbeat 一次心跳
+type heartbeat struct {
+	App    string                 `json:"app"`
+	Seq    uint64                 `json:"seq"`
+	Status map[string]interface{} `json:"status,omitempty"`
+}
+
+// parseHeartbeat 解析心跳：JSON {"app":"x","seq":1,"status":{...}}，或文本 "app seq key=value ..."
+func parseHeartbeat(data []byte) (*heartbeat, error) {
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, "{") {
+		var hb heartbeat
+		if err := json.Unmarshal([]byte(text), &hb); err != nil {
+			return nil, err
+		}
+		if hb.App == "" {
+			return nil, fmt.Errorf("app required")
+		}
+		return &hb, nil
+	}
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid heartbeat %q", text)
+	}
+	seq, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seq %q", fields[1])
+	}
+	hb := &heartbeat{App: fields[0], Seq: seq}
+	for _, kv := range fields[2:] {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if hb.Status == nil {
+			hb.Status = make(map[string]interface{})
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			hb.Status[k] = f
+		} else {
+			hb.Status[k] = v
+		}
+	}
+	return hb, nil
+}
+
+// heartbeatApp 目标的心跳应用 ID
+func heartbeatApp(t types.MonitorTarget) string {
+	if t.Heartbeat != nil && t.Heartbeat.AppID != "" {
+		return t.Heartbeat.AppID
+	}
+	return depKey(t)
+}
+
+// receiveHeartbeat 记录心跳到绑定的目标，返回绑定的目标数
+func (m *MultiMonitor) receiveHeartbeat(hb *heartbeat) int {
+	now := time.Now()
+	var events []types.Event
+	bound := 0
+
+	m.mu.Lock()
+	for _, state := range m.targets {
+		t := state.target
+		if t.Heartbeat == nil || heartbeatApp(t) != hb.App {
+			continue
+		}
+		bound++
+		hs := state.heartbeat
+		if hs == nil {
+			hs = &heartbeatState{since: now}
+			state.heartbeat = hs
+		}
+		if hs.received > 0 {
+			switch {
+			case hb.Seq < hs.seq:
+				hs.resets++
+			case hb.Seq > hs.seq+1:
+				hs.lost += hb.Seq - hs.seq - 1
+			}
+		}
+		hs.seq, hs.last = hb.Seq, now
+		hs.received++
+		if hb.Status != nil {
+			hs.status = hb.Status
+			hs.custom = make(map[string]float64)
+			for k, v := range hb.Status {
+				if f, ok := v.(float64); ok {
+					hs.custom[k] = f
+				}
+			}
+		}
+		if hs.missed {
+			hs.missed = false
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "heartbeat_restored",
+				PID:       t.PID,
+				Name:      t.Name,
+				Message:   fmt.Sprintf("已恢复收到应用 %s 的心跳 (seq=%d)", hb.App, hb.Seq),
+				Details:   map[string]interface{}{"app_id": hb.App, "seq": hb.Seq},
+			})
+		}
+	}
+	// 应用 ID 来自外部输入，记录的未绑定应用数有上限，达到上限后不再逐个记录
+	if bound == 0 && !m.unknownApps[hb.App] && len(m.unknownApps) < heartbeatUnknownLimit {
+		if m.unknownApps == nil {
+			m.unknownApps = make(map[string]bool)
+		}
+		m.unknownApps[hb.App] = true
+		log.Printf("[WARN] 心跳: 应用 %s 未绑定监控目标", hb.App)
+		if len(m.unknownApps) == heartbeatUnknownLimit {
+			log.Printf("[WARN] 心跳: 未绑定监控目标的应用已达 %d 个，之后不再记录", heartbeatUnknownLimit)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+	return bound
+}
+
+// checkHeartbeat 超时未收到心跳时产生 heartbeat_missed 事件并按重启配置重启，仍未恢复时每隔 timeout 秒再次上报
+func (m *MultiMonitor) checkHeartbeat(state *targetState, target types.MonitorTarget) {
+	if target.Heartbeat == nil {
+		return
+	}
+	timeout := time.Duration(target.Heartbeat.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	hs := state.heartbeat
+	if hs == nil {
+		hs = &heartbeatState{since: now}
+		state.heartbeat = hs
+	}
+	ref := hs.last
+	if hs.since.After(ref) {
+		ref = hs.since
+	}
+	if now.Sub(ref) < timeout || (hs.missed && now.Sub(hs.lastAlarm) < timeout) {
+		m.mu.Unlock()
+		return
+	}
+	hs.missed, hs.lastAlarm = true, now
+	details := map[string]interface{}{"app_id": heartbeatApp(target), "timeout": int(timeout.Seconds()), "seq": hs.seq}
+	msg := fmt.Sprintf("%d 秒未收到应用 %s 的心跳", int(now.Sub(ref).Seconds()), heartbeatApp(target))
+	if !hs.last.IsZero() {
+		details["last_seen"] = hs.last
+	}
+	m.mu.Unlock()
+
+	m.addEvent(types.Event{
+		Timestamp: now,
+		Type:      "heartbeat_missed",
+		PID:       target.PID,
+		Name:      target.Name,
+		Message:   msg,
+		Details:   details,
+	})
+	if target.RestartCmd != "" || target.Supervise != nil {
+		m.tryRestart(target.PID, "heartbeat_missed")
+	}
+}
+
+// heartbeatCustom 心跳数值字段，作为自定义指标随采样记录
+func (m *MultiMonitor) heartbeatCustom(state *targetState) map[string]float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if state.heartbeat == nil || len(state.heartbeat.custom) == 0 {
+		return nil
+	}
+	custom := make(map[string]float64, len(state.heartbeat.custom))
+	for k, v := range state.heartbeat.custom {
+		custom[k] = v
+	}
+	return custom
+}
+
+// startHeartbeatListeners 启动 UDP / HTTP 心跳接收，stop 关闭时停止监听
+func (m *MultiMonitor) startHeartbeatListeners(cfg types.HeartbeatConfig, stop <-chan struct{}) {
+	if cfg.UDPAddr != "" {
+		conn, err := net.ListenPacket("udp", cfg.UDPAddr)
+		if err != nil {
+			log.Printf("[ERROR] 心跳: UDP 监听 %s 失败: %v", cfg.UDPAddr, err)
+		} else {
+			log.Printf("[INFO] 心跳: UDP 监听 %s", cfg.UDPAddr)
+			go func() {
+				<-stop
+				conn.Close()
+			}()
+			go m.serveHeartbeatUDP(conn)
+		}
+	}
+	if cfg.HTTPAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/heartbeat", m.handleHeartbeatHTTP)
+		srv := &http.Server{Addr: cfg.HTTPAddr, Handler: mux, ReadTimeout: 10 * time.Second}
+		ln, err := net.Listen("tcp", cfg.HTTPAddr)
+		if err != nil {
+			log.Printf("[ERROR] 心跳: HTTP 监听 %s 失败: %v", cfg.HTTPAddr, err)
+			return
+		}
+		log.Printf("[INFO] 心跳: HTTP 监听 %s", cfg.HTTPAddr)
+		go func() {
+			<-stop
+			srv.Close()
+		}()
+		go srv.Serve(ln)
+	}
+}
+
+func (m *MultiMonitor) serveHeartbeatUDP(conn net.PacketConn) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		hb, err := parseHeartbeat(buf[:n])
+		if err != nil {
+			continue
+		}
+		m.receiveHeartbeat(hb)
+	}
+}
+
+// handleHeartbeatHTTP POST /heartbeat，请求体格式同 UDP
+func (m *MultiMonitor) handleHeartbeatHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hb, err := parseHeartbeat(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if m.receiveHeartbeat(hb) == 0 {
+		http.Error(w, "app not bound to any target", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetHeartbeats 获取配置了心跳的目标状态（按 PID 排序）
+func (m *MultiMonitor) GetHeartbeats() []types.HeartbeatStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := []types.HeartbeatStatus{}
+	for pid, state := range m.targets {
+		t := state.target
+		if t.Heartbeat == nil {
+			continue
+		}
+		st := types.HeartbeatStatus{PID: pid, Name: t.Name, AppID: heartbeatApp(t)}
+		if hs := state.heartbeat; hs != nil {
+			st.LastSeen, st.Seq, st.Received, st.Lost, st.Resets, st.Missed, st.Status =
+				hs.last, hs.seq, hs.received, hs.lost, hs.resets, hs.missed, hs.status
+		}
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PID < result[j].PID })
+	return result
+}
@@ -0,0 +1,240 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// integrityState 完整性校验状态
+type integrityState struct {
+	exe        string            // 记录时的可执行文件路径
+	files      map[string]string // 路径 -> 记录的 SHA-256
+	current    map[string]string // 已上报不一致的文件 -> 当时计算的哈希（缺失为空）
+	recordedAt time.Time
+	checkedAt  time.Time
+}
+
+// integrityExe 目标的可执行文件路径：托管进程为启动命令，其余从进程信息获取
+func (m *MultiMonitor) integrityExe(target types.MonitorTarget) (string, error) {
+	if target.Supervise != nil {
+		exe, err := exec.LookPath(target.Supervise.Command)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(exe)
+	}
+	return m.provider.GetExe(target.PID)
+}
+
+// recordIntegrity 计算并记录可执行文件及额外文件的哈希，无法读取的文件跳过；
+// exe 为空时从目标获取（已记录过的目标沿用原路径，进程已退出时也可重新记录）
+func (m *MultiMonitor) recordIntegrity(target types.MonitorTarget, exe string) *integrityState {
+	integ := &integrityState{files: make(map[string]string), current: make(map[string]string), recordedAt: time.Now()}
+	if exe == "" {
+		var err error
+		if exe, err = m.integrityExe(target); err != nil {
+			log.Printf("[WARN] 完整性校验: 无法获取 %s 的可执行文件路径: %v", target.Name, err)
+		}
+	}
+	integ.exe = exe
+	paths := target.Integrity.Files
+	if exe != "" {
+		paths = append([]string{exe}, paths...)
+	}
+	for _, path := range paths {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			log.Printf("[WARN] 完整性校验: 无法读取 %s: %v", path, err)
+			continue
+		}
+		integ.files[path] = sum
+	}
+	integ.checkedAt = integ.recordedAt
+	return integ
+}
+
+// mergeIntegrity 配置更新后的记录：已记录的文件保留原哈希，避免更新配置时接受被替换的文件
+func mergeIntegrity(old, integ *integrityState) *integrityState {
+	if old == nil {
+		return integ
+	}
+	for path := range integ.files {
+		if sum, ok := old.files[path]; ok {
+			integ.files[path] = sum
+		}
+		if cur, ok := old.current[path]; ok {
+			integ.current[path] = cur
+		}
+	}
+	integ.recordedAt, integ.checkedAt = old.recordedAt, old.checkedAt
+	return integ
+}
+
+// checkIntegrity 按间隔定期复核
+func (m *MultiMonitor) checkIntegrity(state *targetState, target types.MonitorTarget) {
+	if target.Integrity == nil {
+		return
+	}
+	interval := target.Integrity.Interval
+	if interval <= 0 {
+		interval = 300
+	}
+	m.mu.RLock()
+	due := state.integrity != nil && time.Since(state.integrity.checkedAt) >= time.Duration(interval)*time.Second
+	m.mu.RUnlock()
+	if due {
+		m.verifyIntegrity(state, "periodic")
+	}
+}
+
+// verifyIntegrity 复核记录的哈希：不一致的文件上报 integrity_violation（同一哈希只报一次），
+// 恢复一致时上报 integrity_restored；返回是否存在不一致
+func (m *MultiMonitor) verifyIntegrity(state *targetState, trigger string) bool {
+	m.mu.RLock()
+	integ := state.integrity
+	if integ == nil {
+		m.mu.RUnlock()
+		return false
+	}
+	recorded := make(map[string]string, len(integ.files))
+	for path, sum := range integ.files {
+		recorded[path] = sum
+	}
+	target := state.target
+	m.mu.RUnlock()
+
+	current := make(map[string]string, len(recorded))
+	for path := range recorded {
+		current[path], _ = fileSHA256(path)
+	}
+
+	now := time.Now()
+	var events []types.Event
+	violated := false
+	m.mu.Lock()
+	integ.checkedAt = now
+	for path, sum := range recorded {
+		cur := current[path]
+		prev, reported := integ.current[path]
+		if cur == sum {
+			if reported {
+				delete(integ.current, path)
+				events = append(events, types.Event{
+					Timestamp: now,
+					Type:      "integrity_restored",
+					PID:       target.PID,
+					Name:      target.Name,
+					Message:   fmt.Sprintf("文件 %s 已与记录的 SHA-256 一致", path),
+					Details:   map[string]interface{}{"file": path, "sha256": sum},
+				})
+			}
+			continue
+		}
+		violated = true
+		if reported && prev == cur {
+			continue
+		}
+		integ.current[path] = cur
+		msg := fmt.Sprintf("文件 %s 与记录的 SHA-256 不一致", path)
+		if cur == "" {
+			msg = fmt.Sprintf("文件 %s 缺失或无法读取", path)
+		}
+		events = append(events, types.Event{
+			Timestamp: now,
+			Type:      "integrity_violation",
+			PID:       target.PID,
+			Name:      target.Name,
+			Message:   msg,
+			Details: map[string]interface{}{
+				"file":          path,
+				"expected":      sum,
+				"actual":        cur,
+				"trigger":       trigger,
+				"block_restart": target.Integrity != nil && target.Integrity.BlockRestart,
+			},
+		})
+	}
+	m.mu.Unlock()
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+	return violated
+}
+
+// integrityBlocksRestart 重启前复核，不一致且配置了 block_restart 时返回 true
+func (m *MultiMonitor) integrityBlocksRestart(state *targetState, reason string) bool {
+	m.mu.RLock()
+	spec := state.target.Integrity
+	pid, name := state.target.PID, state.target.Name
+	m.mu.RUnlock()
+	if spec == nil || !m.verifyIntegrity(state, "restart") {
+		return false
+	}
+	if !spec.BlockRestart {
+		log.Printf("[WARN] %s (PID=%d) 完整性校验不一致，仍按配置重启 (原因:%s)", name, pid, reason)
+		return false
+	}
+	log.Printf("[WARN] %s (PID=%d) 完整性校验不一致，已阻止重启 (原因:%s)", name, pid, reason)
+	return true
+}
+
+// GetIntegrity 获取目标完整性校验状态（按 PID 排序），pid 为 0 时返回全部
+func (m *MultiMonitor) GetIntegrity(pid int32) []types.IntegrityStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := []types.IntegrityStatus{}
+	for p, state := range m.targets {
+		integ := state.integrity
+		if integ == nil || (pid != 0 && p != pid) {
+			continue
+		}
+		st := types.IntegrityStatus{PID: p, Name: state.target.Name, RecordedAt: integ.recordedAt, CheckedAt: integ.checkedAt, Files: []types.IntegrityFile{}}
+		for path, sum := range integ.files {
+			f := types.IntegrityFile{Path: path, SHA256: sum, OK: true}
+			if cur, bad := integ.current[path]; bad {
+				f.Current, f.OK = cur, false
+				st.Violated = true
+			}
+			st.Files = append(st.Files, f)
+		}
+		sort.Slice(st.Files, func(i, j int) bool { return st.Files[i].Path < st.Files[j].Path })
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PID < result[j].PID })
+	return result
+}
+
+// AcceptIntegrity 确认文件变更（如正常升级），重新记录目标全部文件的哈希
+func (m *MultiMonitor) AcceptIntegrity(pid int32) error {
+	m.mu.RLock()
+	state, ok := m.targets[pid]
+	var target types.MonitorTarget
+	exe := ""
+	if ok {
+		target = state.target
+		if state.integrity != nil {
+			exe = state.integrity.exe
+		}
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("target PID %d not found", pid)
+	}
+	if target.Integrity == nil {
+		return fmt.Errorf("target PID %d has no integrity check", pid)
+	}
+	integ := m.recordIntegrity(target, exe)
+
+	m.mu.Lock()
+	state.integrity = integ
+	m.mu.Unlock()
+	log.Printf("[INFO] 完整性校验: %s (PID=%d) 已重新记录 %d 个文件的哈希", target.Name, pid, len(integ.files))
+	return nil
+}
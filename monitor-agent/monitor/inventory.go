@@ -0,0 +1,406 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// inventoryStore 进程清单：基线持久化到 LogDir/inventory.json，基线外的进程记录在内存中供审核
+type inventoryStore struct {
+	cfg  types.InventoryConfig
+	path string
+
+	mu       sync.Mutex
+	baseline map[string]*types.InventoryEntry // key: exe|sha256|user
+	unknown  map[string]*types.InventoryEntry
+	learn    bool                // 基线文件不存在，首次扫描时学习
+	hashes   map[string]fileHash // 可执行文件哈希缓存
+	alerted  map[int32]string    // 已告警的 PID -> key
+}
+
+type fileHash struct {
+	size int64
+	mod  time.Time
+	sum  string
+}
+
+func newInventoryStore(cfg types.InventoryConfig, logDir string) *inventoryStore {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30
+	}
+	if cfg.UnknownRetention <= 0 {
+		cfg.UnknownRetention = 24
+	}
+	inv := &inventoryStore{
+		cfg:      cfg,
+		path:     filepath.Join(logDir, "inventory.json"),
+		baseline: make(map[string]*types.InventoryEntry),
+		unknown:  make(map[string]*types.InventoryEntry),
+		hashes:   make(map[string]fileHash),
+		alerted:  make(map[int32]string),
+	}
+	data, err := os.ReadFile(inv.path)
+	if err != nil {
+		inv.learn = true
+		return inv
+	}
+	var entries []*types.InventoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("[WARN] 进程清单基线解析失败 %s: %v", inv.path, err)
+		return inv
+	}
+	for _, e := range entries {
+		e.PIDs = nil
+		inv.baseline[inventoryKey(e.Exe, e.SHA256, e.User)] = e
+	}
+	return inv
+}
+
+func inventoryKey(exe, sum, user string) string {
+	return exe + "|" + sum + "|" + user
+}
+
+func inventoryID(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:6])
+}
+
+// saveLocked 写入临时文件后 rename（调用方持有 inv.mu）
+func (inv *inventoryStore) saveLocked() {
+	entries := make([]*types.InventoryEntry, 0, len(inv.baseline))
+	for _, e := range inv.baseline {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Exe < entries[j].Exe })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := inv.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("[WARN] 保存进程清单基线失败: %v", err)
+		return
+	}
+	os.Rename(tmp, inv.path)
+}
+
+// hashFile 计算可执行文件 SHA-256，按大小和修改时间缓存（调用方持有 inv.mu）
+func (inv *inventoryStore) hashFile(exe string) string {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return ""
+	}
+	if c, ok := inv.hashes[exe]; ok && c.size == info.Size() && c.mod.Equal(info.ModTime()) {
+		return c.sum
+	}
+	sum, err := fileSHA256(exe)
+	if err != nil {
+		return ""
+	}
+	inv.hashes[exe] = fileHash{size: info.Size(), mod: info.ModTime(), sum: sum}
+	return sum
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// matchAny 可执行文件路径或进程名匹配任一通配符
+func matchAny(patterns []string, exe, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, exe); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MultiMonitor) watchInventory(stop <-chan struct{}) {
+	m.scanInventory()
+	ticker := time.NewTicker(time.Duration(m.inventory.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.scanInventory()
+		}
+	}
+}
+
+// scanInventory 对比当前进程与基线：禁止的进程告警（可自动结束），基线外的进程告警并记录待审核
+func (m *MultiMonitor) scanInventory() {
+	inv := m.inventory
+	procs, err := m.provider.ListAllProcesses()
+	if err != nil {
+		log.Printf("[WARN] 进程清单: 获取进程列表失败: %v", err)
+		return
+	}
+	self := int32(os.Getpid())
+	now := time.Now()
+
+	m.mu.RLock()
+	monitored := make(map[int32]bool, len(m.targets))
+	for pid := range m.targets {
+		monitored[pid] = true
+	}
+	m.mu.RUnlock()
+
+	var events []types.Event
+	var kills []types.ProcessInfo
+
+	inv.mu.Lock()
+	alive := make(map[int32]bool, len(procs))
+	for _, e := range inv.baseline {
+		e.PIDs = nil
+	}
+	for _, e := range inv.unknown {
+		e.PIDs = nil
+	}
+
+	for _, p := range procs {
+		alive[p.PID] = true
+		// 内核线程或无权读取的进程没有可执行文件路径，只按进程名检查禁止列表
+		keyExe := p.Exe
+		if p.Exe == "" {
+			if inv.learn || !matchAny(inv.cfg.Deny, "", p.Name) {
+				continue
+			}
+			keyExe = "name:" + p.Name
+		}
+		sum := inv.hashFile(p.Exe)
+		key := inventoryKey(keyExe, sum, p.Username)
+
+		if inv.learn {
+			if e, ok := inv.baseline[key]; ok {
+				e.PIDs = append(e.PIDs, p.PID)
+				continue
+			}
+			inv.baseline[key] = &types.InventoryEntry{
+				ID: inventoryID(key), Exe: p.Exe, SHA256: sum, User: p.Username, Name: p.Name,
+				Status: "baseline", FirstSeen: now, LastSeen: now, PIDs: []int32{p.PID},
+			}
+			continue
+		}
+
+		denied := matchAny(inv.cfg.Deny, p.Exe, p.Name)
+		if !denied {
+			if e, ok := inv.baseline[key]; ok {
+				e.LastSeen = now
+				e.PIDs = append(e.PIDs, p.PID)
+				continue
+			}
+			if matchAny(inv.cfg.Allow, p.Exe, "") {
+				continue
+			}
+		}
+
+		e, ok := inv.unknown[key]
+		if !ok {
+			e = &types.InventoryEntry{
+				ID: inventoryID(key), Exe: p.Exe, SHA256: sum, User: p.Username, Name: p.Name,
+				Status: "unknown", Reason: inv.unknownReason(p.Exe, sum), FirstSeen: now,
+			}
+			inv.unknown[key] = e
+		}
+		if denied {
+			e.Status = "denied"
+		}
+		e.LastSeen = now
+		e.PIDs = append(e.PIDs, p.PID)
+
+		if inv.alerted[p.PID] == key {
+			continue
+		}
+		inv.alerted[p.PID] = key
+		details := map[string]interface{}{"exe": p.Exe, "sha256": sum, "user": p.Username, "cmdline": p.Cmdline, "inventory_id": e.ID}
+		if denied {
+			kill := inv.cfg.KillDenied && p.PID != self && !monitored[p.PID]
+			details["killed"] = kill
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "denied_process",
+				PID:       p.PID,
+				Name:      p.Name,
+				Message:   fmt.Sprintf("禁止运行的进程 %s (%s, 用户 %s)", p.Name, p.Exe, p.Username),
+				Details:   details,
+			})
+			if kill {
+				kills = append(kills, p)
+			}
+			continue
+		}
+		details["reason"] = e.Reason
+		events = append(events, types.Event{
+			Timestamp: now,
+			Type:      "unauthorized_process",
+			PID:       p.PID,
+			Name:      p.Name,
+			Message:   fmt.Sprintf("基线外的进程 %s (%s, 用户 %s, %s)", p.Name, p.Exe, p.Username, unknownReasonText(e.Reason)),
+			Details:   details,
+		})
+	}
+
+	if inv.learn {
+		inv.learn = false
+		inv.saveLocked()
+		log.Printf("[INFO] 进程清单: 已学习 %d 个可执行文件作为基线", len(inv.baseline))
+	}
+	for pid := range inv.alerted {
+		if !alive[pid] {
+			delete(inv.alerted, pid)
+		}
+	}
+	// 已不再运行的基线外条目保留一段时间供审核，之后清除（短生命周期程序不会无限累积）
+	retention := time.Duration(inv.cfg.UnknownRetention) * time.Hour
+	for key, e := range inv.unknown {
+		if len(e.PIDs) == 0 && now.Sub(e.LastSeen) > retention {
+			delete(inv.unknown, key)
+		}
+	}
+	inv.mu.Unlock()
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+	for _, p := range kills {
+		if err := m.provider.KillProcess(p.PID); err != nil {
+			log.Printf("[ERROR] 结束禁止的进程 %s (PID=%d) 失败: %v", p.Name, p.PID, err)
+		} else {
+			log.Printf("[INFO] 已结束禁止的进程 %s (PID=%d)", p.Name, p.PID)
+		}
+	}
+}
+
+// unknownReason 基线外进程的原因：同路径文件哈希变化 / 同文件运行用户变化 / 新程序（调用方持有 inv.mu）
+func (inv *inventoryStore) unknownReason(exe, sum string) string {
+	reason := "new"
+	for _, e := range inv.baseline {
+		if e.Exe != exe {
+			continue
+		}
+		if e.SHA256 != sum {
+			reason = "hash_changed"
+		} else {
+			return "user_changed"
+		}
+	}
+	return reason
+}
+
+func unknownReasonText(reason string) string {
+	switch reason {
+	case "hash_changed":
+		return "可执行文件与基线哈希不一致"
+	case "user_changed":
+		return "运行用户与基线不一致"
+	}
+	return "新程序"
+}
+
+// ListInventory 获取进程清单，status 为 baseline / unknown / denied，为空返回全部
+func (m *MultiMonitor) ListInventory(status string) ([]types.InventoryEntry, error) {
+	inv := m.inventory
+	if inv == nil {
+		return nil, fmt.Errorf("inventory not enabled")
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	result := []types.InventoryEntry{}
+	for _, set := range []map[string]*types.InventoryEntry{inv.baseline, inv.unknown} {
+		for _, e := range set {
+			if status == "" || e.Status == status {
+				result = append(result, *e)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Status != result[j].Status {
+			return result[i].Status < result[j].Status
+		}
+		return result[i].Exe < result[j].Exe
+	})
+	return result, nil
+}
+
+// PromoteInventory 将基线外的条目加入基线，返回加入的条目数
+func (m *MultiMonitor) PromoteInventory(ids []string) (int, error) {
+	inv := m.inventory
+	if inv == nil {
+		return 0, fmt.Errorf("inventory not enabled")
+	}
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	n := 0
+	for key, e := range inv.unknown {
+		if !want[e.ID] {
+			continue
+		}
+		delete(inv.unknown, key)
+		e.Status, e.Reason = "baseline", ""
+		inv.baseline[key] = e
+		n++
+		log.Printf("[INFO] 进程清单: %s (%s, 用户 %s) 加入基线", e.Name, e.Exe, e.User)
+	}
+	if n > 0 {
+		inv.saveLocked()
+	}
+	return n, nil
+}
+
+// RemoveInventory 从基线移除条目（之后再运行时按基线外进程告警），返回移除的条目数
+func (m *MultiMonitor) RemoveInventory(ids []string) (int, error) {
+	inv := m.inventory
+	if inv == nil {
+		return 0, fmt.Errorf("inventory not enabled")
+	}
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	n := 0
+	for key, e := range inv.baseline {
+		if want[e.ID] {
+			delete(inv.baseline, key)
+			n++
+		}
+	}
+	if n > 0 {
+		inv.saveLocked()
+	}
+	return n, nil
+}
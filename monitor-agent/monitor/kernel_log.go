@@ -0,0 +1,207 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// kernelEvent 从内核日志解析出的进程异常
+type kernelEvent struct {
+	Type    string // oom_kill / segfault / hung_task
+	PID     int32  // 内核日志中的 PID（段错误、挂起任务为线程 ID）
+	Comm    string // 进程名（内核截断为 15 字符）
+	Signal  string
+	Time    time.Time
+	Details map[string]interface{}
+}
+
+// kernelCauseWindow 内核事件与随后的退出事件关联的最大间隔
+const kernelCauseWindow = time.Minute
+
+var (
+	// Out of memory: Killed process 1234 (app) total-vm:123kB, anon-rss:45kB, file-rss:0kB, ...
+	reOOMKilled = regexp.MustCompile(`Killed process (\d+) \(([^)]*)\)(.*)`)
+	reOOMField  = regexp.MustCompile(`([a-z-]+):(\d+)kB`)
+	// app[1234]: segfault at 0 ip 000055d0c0a0b1c2 sp 00007ffd2c1e8a10 error 4 in app[55d0c0a00000+2000]
+	reSegfault = regexp.MustCompile(`(\S+)\[(\d+)\]: segfault at ([0-9a-fA-Fx]+) ip ([0-9a-fA-Fx]+) sp ([0-9a-fA-Fx]+) error (\d+)(?: in (\S+))?`)
+	// traps: app[1234] general protection fault ip:55d0c0a0b1c2 sp:7ffd2c1e8a10 error:0 in app[55d0c0a00000+2000]
+	reTrap = regexp.MustCompile(`traps: (\S+)\[(\d+)\] (.+?) ip:([0-9a-fA-F]+) sp:([0-9a-fA-F]+) error:(\S+)(?: in (\S+))?`)
+	// INFO: task app:1234 blocked for more than 120 seconds.
+	reHungTask = regexp.MustCompile(`task (\S+):(\d+) blocked for more than (\d+) seconds`)
+	// 行首的 kmsg 前缀（pri,seq,ts,flags;）、syslog 前缀（... kernel: ）和时间戳（[ 123.456]）
+	reKmsgPrefix = regexp.MustCompile(`^\d+,\d+,\d+,[^;]*;`)
+	reTimestamp  = regexp.MustCompile(`^\[\s*\d+\.\d+\]\s*`)
+)
+
+// parseKernelLine 解析一行内核日志，支持 /dev/kmsg 原始格式、dmesg 和 syslog（kern.log）格式
+func parseKernelLine(line string) *kernelEvent {
+	line = strings.TrimSpace(line)
+	line = reKmsgPrefix.ReplaceAllString(line, "")
+	if i := strings.Index(line, "kernel: "); i >= 0 {
+		line = line[i+len("kernel: "):]
+	}
+	line = reTimestamp.ReplaceAllString(line, "")
+
+	if m := reOOMKilled.FindStringSubmatch(line); m != nil {
+		pid, _ := strconv.ParseInt(m[1], 10, 32)
+		details := map[string]interface{}{"cgroup": strings.Contains(line, "Memory cgroup")}
+		for _, f := range reOOMField.FindAllStringSubmatch(m[3], -1) {
+			v, _ := strconv.ParseUint(f[2], 10, 64)
+			details[strings.ReplaceAll(f[1], "-", "_")+"_kb"] = v
+		}
+		return &kernelEvent{Type: "oom_kill", PID: int32(pid), Comm: m[2], Signal: "SIGKILL", Details: details}
+	}
+	if m := reSegfault.FindStringSubmatch(line); m != nil {
+		pid, _ := strconv.ParseInt(m[2], 10, 32)
+		details := map[string]interface{}{"fault_addr": m[3], "ip": m[4], "sp": m[5], "error_code": m[6]}
+		if m[7] != "" {
+			details["module"] = m[7]
+		}
+		return &kernelEvent{Type: "segfault", PID: int32(pid), Comm: m[1], Signal: "SIGSEGV", Details: details}
+	}
+	if m := reTrap.FindStringSubmatch(line); m != nil {
+		pid, _ := strconv.ParseInt(m[2], 10, 32)
+		details := map[string]interface{}{"trap": m[3], "ip": m[4], "sp": m[5], "error_code": m[6]}
+		if m[7] != "" {
+			details["module"] = m[7]
+		}
+		signal := "SIGSEGV"
+		switch {
+		case strings.Contains(m[3], "invalid opcode"):
+			signal = "SIGILL"
+		case strings.Contains(m[3], "divide error"):
+			signal = "SIGFPE"
+		case strings.Contains(m[3], "int3"):
+			signal = "SIGTRAP"
+		}
+		return &kernelEvent{Type: "segfault", PID: int32(pid), Comm: m[1], Signal: signal, Details: details}
+	}
+	if m := reHungTask.FindStringSubmatch(line); m != nil {
+		pid, _ := strconv.ParseInt(m[2], 10, 32)
+		secs, _ := strconv.Atoi(m[3])
+		return &kernelEvent{Type: "hung_task", PID: int32(pid), Comm: m[1], Details: map[string]interface{}{"blocked_seconds": secs}}
+	}
+	return nil
+}
+
+// handleKernelLine 解析内核日志行，关联到监控目标后产生事件，并记录为随后退出事件的原因
+func (m *MultiMonitor) handleKernelLine(line string) {
+	ke := parseKernelLine(line)
+	if ke == nil {
+		return
+	}
+	ke.Time = time.Now()
+	ke.Details["kernel_pid"] = ke.PID
+	ke.Details["comm"] = ke.Comm
+	if ke.Signal != "" {
+		ke.Details["signal"] = ke.Signal
+	}
+
+	tgid := threadGroupID(ke.PID)
+
+	m.mu.Lock()
+	var state *targetState
+	var byName []*targetState
+	for _, s := range m.targets {
+		if s.target.PID == ke.PID || (tgid > 0 && s.target.PID == tgid) {
+			state = s
+			break
+		}
+		if commMatches(ke.Comm, s.target.Name) {
+			byName = append(byName, s)
+		}
+	}
+	// PID 未命中且进程已退出（无法查到所属进程）时按进程名关联，名称不唯一时不关联
+	if state == nil && tgid == 0 && len(byName) == 1 {
+		state = byName[0]
+		ke.Details["matched_by"] = "name"
+	}
+	if state == nil {
+		m.mu.Unlock()
+		log.Printf("[INFO] 内核日志: %s %s[%d]（非监控目标）", ke.Type, ke.Comm, ke.PID)
+		return
+	}
+	if ke.Type != "hung_task" {
+		state.kernelCause = ke
+	}
+	target := state.target
+	m.mu.Unlock()
+
+	m.addEvent(types.Event{
+		Timestamp: ke.Time,
+		Type:      ke.Type,
+		PID:       target.PID,
+		Name:      target.Name,
+		Message:   kernelEventMessage(ke),
+		Details:   ke.Details,
+	})
+}
+
+func kernelEventMessage(ke *kernelEvent) string {
+	switch ke.Type {
+	case "oom_kill":
+		msg := "进程被内核 OOM killer 终止"
+		if ke.Details["cgroup"] == true {
+			msg = "进程因内存 cgroup 超限被 OOM killer 终止"
+		}
+		if rss, ok := ke.Details["anon_rss_kb"].(uint64); ok {
+			msg += fmt.Sprintf("（anon-rss %d MB）", rss/1024)
+		}
+		return msg
+	case "segfault":
+		if trap, ok := ke.Details["trap"].(string); ok {
+			return fmt.Sprintf("进程异常 %s (%s) ip=%s", trap, ke.Signal, ke.Details["ip"])
+		}
+		msg := fmt.Sprintf("进程段错误 (SIGSEGV) 访问地址 %s ip=%s", ke.Details["fault_addr"], ke.Details["ip"])
+		if mod, ok := ke.Details["module"].(string); ok {
+			msg += " in " + mod
+		}
+		return msg
+	case "hung_task":
+		return fmt.Sprintf("内核报告任务 %s:%d 阻塞超过 %d 秒", ke.Comm, ke.PID, ke.Details["blocked_seconds"])
+	}
+	return ke.Type
+}
+
+// commMatches 内核中的进程名最长 15 字符，截断后与目标名称比较
+func commMatches(comm, name string) bool {
+	if comm == "" {
+		return false
+	}
+	if len(name) > 15 {
+		name = name[:15]
+	}
+	return comm == name || comm == strings.TrimSuffix(name, ".exe")
+}
+
+// enrichExitCause 退出事件发生前不久有内核记录的终止原因（OOM/段错误）时，补充到退出事件
+func (m *MultiMonitor) enrichExitCause(evt *types.Event) {
+	m.mu.Lock()
+	state, ok := m.targets[evt.PID]
+	var ke *kernelEvent
+	if ok && state.kernelCause != nil {
+		ke = state.kernelCause
+		state.kernelCause = nil
+	}
+	m.mu.Unlock()
+	if ke == nil || evt.Timestamp.Sub(ke.Time) > kernelCauseWindow {
+		return
+	}
+
+	if evt.Details == nil {
+		evt.Details = make(map[string]interface{})
+	}
+	evt.Details["cause"] = ke.Type
+	for k, v := range ke.Details {
+		if _, exists := evt.Details[k]; !exists {
+			evt.Details[k] = v
+		}
+	}
+	evt.Message += "（原因: " + kernelEventMessage(ke) + "）"
+}
@@ -0,0 +1,169 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var errKernelLogRotated = errors.New("kernel log rotated")
+
+// watchKernelLog 持续读取内核日志（/dev/kmsg 或 kern.log 等普通文件），只处理启动后的新消息
+func (m *MultiMonitor) watchKernelLog(path string, stop <-chan struct{}) {
+	followKernelLog(path, m.handleKernelLine, stop)
+}
+
+// followKernelLog 读取内核日志并逐行回调 handle，出错后重新打开，直到 stop 关闭
+func followKernelLog(path string, handle func(line string), stop <-chan struct{}) {
+	fromStart := false
+	for {
+		var err error
+		if isCharDevice(path) {
+			err = readKmsg(path, handle, stop)
+		} else {
+			err = tailKernelFile(path, fromStart, handle, stop)
+			// 仅轮转后的新文件从头读取；文件不存在或无权限等错误后重新打开时仍从末尾开始，避免重放旧消息
+			fromStart = errors.Is(err, errKernelLogRotated)
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if errors.Is(err, errKernelLogRotated) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[WARN] 读取内核日志 %s 失败: %v", path, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+func isCharDevice(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readKmsg 读取 /dev/kmsg，每次 read 返回一条记录；停止时关闭文件以中断阻塞读取
+func readKmsg(path string, handle func(line string), stop <-chan struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		f.Close()
+	}()
+
+	// 跳过已有的缓冲记录
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	buf := make([]byte, 8192)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			// 读取速度跟不上时记录被覆盖，返回 EPIPE，继续读取即可
+			if errors.Is(err, syscall.EPIPE) {
+				continue
+			}
+			return err
+		}
+		// 记录格式：pri,seq,ts,flags;message\n 后跟以空格开头的附加字段行
+		record := string(buf[:n])
+		if i := strings.IndexByte(record, '\n'); i >= 0 {
+			record = record[:i]
+		}
+		handle(record)
+	}
+}
+
+// tailKernelFile 跟踪普通文件（如 /var/log/kern.log），处理截断与轮转
+func tailKernelFile(path string, fromStart bool, handle func(line string), stop <-chan struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	var offset int64
+	if !fromStart {
+		if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+
+	reader := bufio.NewReader(f)
+	var partial string
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == nil {
+			handle(partial + line)
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		partial += line
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		cur, err := os.Stat(path)
+		if err != nil || !os.SameFile(info, cur) {
+			return errKernelLogRotated
+		}
+		if cur.Size() < offset {
+			// 文件被截断，从头读取
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			offset, partial = 0, ""
+			reader.Reset(f)
+		}
+	}
+}
+
+// threadGroupID 查询线程所属进程 ID（进程已退出时返回 0）
+func threadGroupID(tid int32) int32 {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", tid))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Tgid:") {
+			v, _ := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "Tgid:")), 10, 32)
+			return int32(v)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThreadGroupID(t *testing.T) {
+	pid := int32(os.Getpid())
+	if got := threadGroupID(pid); got != pid {
+		t.Errorf("threadGroupID(self) = %d, want %d", got, pid)
+	}
+	if got := threadGroupID(-1); got != 0 {
+		t.Errorf("threadGroupID(-1) = %d, want 0", got)
+	}
+}
+
+// appendFile 追加内容到文件
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollowKernelLogFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kern.log")
+	if err := os.WriteFile(path, []byte("old message before start\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan string, 16)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		followKernelLog(path, func(line string) { lines <- strings.TrimSpace(line) }, stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+	time.Sleep(300 * time.Millisecond) // 等待打开文件并定位到末尾
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("got line %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %q", want)
+		}
+	}
+
+	// 启动前已有的内容不处理；未写完的行等到换行后合并
+	appendFile(t, path, "line one\nline t")
+	expect("line one")
+	time.Sleep(1500 * time.Millisecond)
+	appendFile(t, path, "wo\n")
+	expect("line two")
+
+	// 截断后从头读取
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "after truncate\n")
+	expect("after truncate")
+
+	// 轮转：原文件改名，新文件从头读取
+	next := path + ".new"
+	if err := os.WriteFile(next, []byte("after rotate\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(next, path); err != nil {
+		t.Fatal(err)
+	}
+	expect("after rotate")
+	appendFile(t, path, "rotated append\n")
+	expect("rotated append")
+
+	select {
+	case line := <-lines:
+		t.Errorf("unexpected line %q", line)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
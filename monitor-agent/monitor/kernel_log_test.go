@@ -0,0 +1,138 @@
+package monitor
+
+import "testing"
+
+func TestParseKernelLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		typ     string
+		pid     int32
+		comm    string
+		signal  string
+		details map[string]interface{}
+	}{
+		{
+			name:   "oom kmsg",
+			line:   "3,1234,5678901234,-;Out of memory: Killed process 4321 (java) total-vm:8123456kB, anon-rss:4123456kB, file-rss:0kB, shmem-rss:0kB, UID:1000 pgtables:9000kB oom_score_adj:0",
+			typ:    "oom_kill",
+			pid:    4321,
+			comm:   "java",
+			signal: "SIGKILL",
+			details: map[string]interface{}{
+				"cgroup": false, "total_vm_kb": uint64(8123456), "anon_rss_kb": uint64(4123456), "file_rss_kb": uint64(0), "shmem_rss_kb": uint64(0),
+			},
+		},
+		{
+			name:    "oom cgroup syslog",
+			line:    "Oct 18 10:00:01 scada01 kernel: [12345.678901] Memory cgroup out of memory: Killed process 2222 (worker) total-vm:102400kB, anon-rss:51200kB, file-rss:4kB, shmem-rss:0kB",
+			typ:     "oom_kill",
+			pid:     2222,
+			comm:    "worker",
+			signal:  "SIGKILL",
+			details: map[string]interface{}{"cgroup": true, "anon_rss_kb": uint64(51200)},
+		},
+		{
+			name:   "segfault dmesg",
+			line:   "[ 8123.456789] fe_comm[3456]: segfault at 0 ip 000055d0c0a0b1c2 sp 00007ffd2c1e8a10 error 4 in fe_comm[55d0c0a00000+2000]",
+			typ:    "segfault",
+			pid:    3456,
+			comm:   "fe_comm",
+			signal: "SIGSEGV",
+			details: map[string]interface{}{
+				"fault_addr": "0", "ip": "000055d0c0a0b1c2", "sp": "00007ffd2c1e8a10", "error_code": "4", "module": "fe_comm[55d0c0a00000+2000]",
+			},
+		},
+		{
+			// 线程的段错误：内核日志中是线程 ID 和线程名
+			name:    "segfault thread",
+			line:    "kernel: rtu-poll-3[3461]: segfault at 7f3a2c000010 ip 00007f3a4b2c1d20 sp 00007f3a2bffe9c0 error 6",
+			typ:     "segfault",
+			pid:     3461,
+			comm:    "rtu-poll-3",
+			signal:  "SIGSEGV",
+			details: map[string]interface{}{"fault_addr": "7f3a2c000010", "error_code": "6"},
+		},
+		{
+			name:   "general protection",
+			line:   "[ 5021.000001] traps: app[1234] general protection fault ip:55d0c0a0b1c2 sp:7ffd2c1e8a10 error:0 in app[55d0c0a00000+2000]",
+			typ:    "segfault",
+			pid:    1234,
+			comm:   "app",
+			signal: "SIGSEGV",
+			details: map[string]interface{}{
+				"trap": "general protection fault", "ip": "55d0c0a0b1c2", "sp": "7ffd2c1e8a10", "error_code": "0", "module": "app[55d0c0a00000+2000]",
+			},
+		},
+		{
+			name:    "divide error",
+			line:    "traps: calc[777] trap divide error ip:401136 sp:7ffe3b1c9f40 error:0 in calc[401000+1000]",
+			typ:     "segfault",
+			pid:     777,
+			comm:    "calc",
+			signal:  "SIGFPE",
+			details: map[string]interface{}{"trap": "trap divide error"},
+		},
+		{
+			name:    "invalid opcode",
+			line:    "traps: decoder[88] trap invalid opcode ip:7f0c1a2b3c4d sp:7ffc0e1f2a30 error:0 in libav.so[7f0c1a000000+400000]",
+			typ:     "segfault",
+			pid:     88,
+			comm:    "decoder",
+			signal:  "SIGILL",
+			details: map[string]interface{}{"module": "libav.so[7f0c1a000000+400000]"},
+		},
+		{
+			name:    "hung task",
+			line:    "6,2001,9123456789,-;INFO: task scada_db:2345 blocked for more than 120 seconds.",
+			typ:     "hung_task",
+			pid:     2345,
+			comm:    "scada_db",
+			details: map[string]interface{}{"blocked_seconds": 120},
+		},
+	}
+	for _, tt := range tests {
+		ke := parseKernelLine(tt.line)
+		if ke == nil {
+			t.Errorf("%s: not parsed", tt.name)
+			continue
+		}
+		if ke.Type != tt.typ || ke.PID != tt.pid || ke.Comm != tt.comm || ke.Signal != tt.signal {
+			t.Errorf("%s: got type=%s pid=%d comm=%q signal=%q, want type=%s pid=%d comm=%q signal=%q",
+				tt.name, ke.Type, ke.PID, ke.Comm, ke.Signal, tt.typ, tt.pid, tt.comm, tt.signal)
+		}
+		for k, want := range tt.details {
+			if got := ke.Details[k]; got != want {
+				t.Errorf("%s: details[%s] = %v (%T), want %v (%T)", tt.name, k, got, got, want, want)
+			}
+		}
+	}
+
+	for _, line := range []string{
+		"[    0.000000] Linux version 5.15.0-91-generic",
+		"Oct 18 10:00:01 scada01 kernel: EXT4-fs (sda1): mounted filesystem with ordered data mode",
+		"",
+	} {
+		if ke := parseKernelLine(line); ke != nil {
+			t.Errorf("parseKernelLine(%q) = %+v, want nil", line, ke)
+		}
+	}
+}
+
+func TestCommMatches(t *testing.T) {
+	tests := []struct {
+		comm, name string
+		want       bool
+	}{
+		{"fe_comm", "fe_comm", true},
+		{"scada_historian", "scada_historian_srv", true}, // 内核截断为 15 字符
+		{"app", "app.exe", true},
+		{"app", "app2", false},
+		{"", "app", false},
+	}
+	for _, tt := range tests {
+		if got := commMatches(tt.comm, tt.name); got != tt.want {
+			t.Errorf("commMatches(%q, %q) = %v, want %v", tt.comm, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package monitor
+
+import "log"
+
+// watchKernelLog Windows 下没有内核日志，崩溃原因需从事件查看器获取
+func (m *MultiMonitor) watchKernelLog(path string, stop <-chan struct{}) {
+	log.Printf("[WARN] Windows 不支持内核日志监视，忽略 %s", path)
+}
+
+func threadGroupID(tid int32) int32 {
+	return 0
+}
@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"monitor-agent/history"
+	"monitor-agent/stats"
+	"monitor-agent/types"
+)
+
+// leakPoint 按分钟降采样的样本
+type leakPoint struct {
+	t       time.Time
+	rss     float64
+	fds     float64
+	threads float64
+}
+
+// leakState 泄漏趋势分析状态
+type leakState struct {
+	points       []leakPoint
+	loaded       bool // 是否已开始从历史存储加载
+	loading      bool // 历史样本加载中（后台），完成前不做分析
+	lastAnalysis time.Time
+	forecast     *types.LeakForecast
+	reported     map[string]bool // 已报告的指标，趋势消失前不重复报告
+}
+
+const leakSampleStep = time.Minute
+
+func leakDefaults(cfg types.LeakCheck) types.LeakCheck {
+	if cfg.Window <= 0 {
+		cfg.Window = 360
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 300
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 30
+	}
+	if cfg.Confidence <= 0 || cfg.Confidence >= 1 {
+		cfg.Confidence = 0.99
+	}
+	return cfg
+}
+
+// checkLeak 记录降采样样本，并按分析周期拟合 RSS/句柄/线程的增长趋势
+func (m *MultiMonitor) checkLeak(state *targetState, target types.MonitorTarget, metric types.ProcessMetrics) {
+	if target.Leak == nil {
+		return
+	}
+	cfg := leakDefaults(*target.Leak)
+	window := time.Duration(cfg.Window) * time.Minute
+	now := metric.Timestamp
+
+	m.mu.Lock()
+	ls := state.leak
+	if ls == nil {
+		ls = &leakState{reported: make(map[string]bool)}
+		state.leak = ls
+	}
+	// 首次分析时在后台从历史存储补齐窗口内的样本，代理重启后无需重新积累，也不阻塞采样
+	if !ls.loaded {
+		ls.loaded, ls.loading = true, true
+		go m.loadLeakHistory(ls, target.PID, now.Add(-window), now)
+	}
+	ls.appendPoint(metric)
+	// 丢弃窗口外样本
+	cut := 0
+	for cut < len(ls.points) && now.Sub(ls.points[cut].t) > window {
+		cut++
+	}
+	ls.points = ls.points[cut:]
+	if ls.loading || now.Sub(ls.lastAnalysis) < time.Duration(cfg.Interval)*time.Second {
+		m.mu.Unlock()
+		return
+	}
+	ls.lastAnalysis = now
+	points := append([]leakPoint(nil), ls.points...)
+	m.mu.Unlock()
+
+	forecast := &types.LeakForecast{PID: target.PID, Name: target.Name, UpdatedAt: now}
+	series := []struct {
+		name      string
+		threshold float64
+		minGrowth float64
+		value     func(p leakPoint) float64
+	}{
+		{"rss", float64(target.MemThreshold), cfg.MinRSSGrowth, func(p leakPoint) float64 { return p.rss }},
+		{"fds", cfg.FDThreshold, 0, func(p leakPoint) float64 { return p.fds }},
+		{"threads", cfg.ThreadThreshold, 0, func(p leakPoint) float64 { return p.threads }},
+	}
+	for _, ser := range series {
+		xs := make([]float64, len(points))
+		ys := make([]float64, len(points))
+		for i, p := range points {
+			xs[i] = p.t.Sub(now).Hours()
+			ys[i] = ser.value(p)
+		}
+		trend := types.TrendForecast{Metric: ser.name, Samples: len(points), Threshold: ser.threshold}
+		if fit, ok := stats.Fit(xs, ys); ok {
+			trend.Current = fit.At(0)
+			trend.SlopePerHour = fit.Slope
+			trend.Confidence = fit.Confidence
+			trend.R2 = fit.R2
+			if fit.Slope > 0 && ser.threshold > trend.Current {
+				trend.HoursToThreshold = (ser.threshold - trend.Current) / fit.Slope
+			}
+			trend.Suspected = len(points) >= cfg.MinSamples && fit.Slope > 0 &&
+				fit.Slope >= ser.minGrowth && fit.Confidence >= cfg.Confidence
+		}
+		forecast.Trends = append(forecast.Trends, trend)
+	}
+
+	var newly []types.TrendForecast
+	m.mu.Lock()
+	ls.forecast = forecast
+	for _, trend := range forecast.Trends {
+		if trend.Suspected && !ls.reported[trend.Metric] {
+			newly = append(newly, trend)
+		}
+		ls.reported[trend.Metric] = trend.Suspected
+	}
+	m.mu.Unlock()
+
+	for _, trend := range newly {
+		msg := fmt.Sprintf("%s 持续增长 %s/小时 (置信度 %.1f%%, 样本 %d)", trend.Metric, formatTrendValue(trend.Metric, trend.SlopePerHour), trend.Confidence*100, trend.Samples)
+		if trend.HoursToThreshold > 0 {
+			msg += fmt.Sprintf("，预计 %.1f 小时后达到阈值", trend.HoursToThreshold)
+		}
+		m.addEvent(types.Event{
+			Timestamp: now,
+			Type:      "leak_suspected",
+			PID:       target.PID,
+			Name:      target.Name,
+			Message:   "疑似泄漏: " + msg,
+			Details: map[string]interface{}{
+				"metric":             trend.Metric,
+				"growth_per_hour":    trend.SlopePerHour,
+				"confidence":         trend.Confidence,
+				"r2":                 trend.R2,
+				"hours_to_threshold": trend.HoursToThreshold,
+			},
+		})
+	}
+}
+
+// loadLeakHistory 逐条读取历史样本并降采样，合并到采样协程已记录的样本之前
+func (m *MultiMonitor) loadLeakHistory(ls *leakState, pid int32, from, to time.Time) {
+	hist := &leakState{}
+	m.history.ScanMetrics(history.Query{PID: pid, From: from, To: to}, func(h types.ProcessMetrics) {
+		if h.Alive {
+			hist.appendPoint(h)
+		}
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(ls.points) > 0 {
+		cut := 0
+		for cut < len(hist.points) && hist.points[cut].t.Before(ls.points[0].t) {
+			cut++
+		}
+		hist.points = hist.points[:cut]
+	}
+	ls.points = append(hist.points, ls.points...)
+	ls.loading = false
+}
+
+// appendPoint 按分钟降采样追加样本
+func (ls *leakState) appendPoint(metric types.ProcessMetrics) {
+	if n := len(ls.points); n > 0 && metric.Timestamp.Sub(ls.points[n-1].t) < leakSampleStep {
+		return
+	}
+	ls.points = append(ls.points, leakPoint{
+		t:       metric.Timestamp,
+		rss:     float64(metric.RSSBytes),
+		fds:     float64(metric.NumFDs),
+		threads: float64(metric.NumThreads),
+	})
+}
+
+func formatTrendValue(metric string, v float64) string {
+	if metric == "rss" {
+		return fmt.Sprintf("%.2f MB", v/1024/1024)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// GetLeakForecast 获取指定目标的泄漏预测（尚未分析时返回 nil）
+func (m *MultiMonitor) GetLeakForecast(pid int32) *types.LeakForecast {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, ok := m.targets[pid]
+	if !ok || state.leak == nil {
+		return nil
+	}
+	return state.leak.forecast
+}
+
+// GetAllLeakForecasts 获取所有已分析目标的泄漏预测
+func (m *MultiMonitor) GetAllLeakForecasts() []types.LeakForecast {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := []types.LeakForecast{}
+	for _, state := range m.targets {
+		if state.leak != nil && state.leak.forecast != nil {
+			result = append(result, *state.leak.forecast)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PID < result[j].PID })
+	return result
+}
@@ -0,0 +1,386 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"monitor-agent/types"
+)
+
+const (
+	logPollInterval = time.Second
+	logReadLimit    = 1 << 20  // 每个文件每轮最多读取的字节数
+	logLineLimit    = 64 << 10 // 超长行截断
+	logEventLine    = 512      // 事件中保留的行长度
+	logTailCheck    = 64       // 截断识别比对的字节数
+)
+
+// logState 目标日志监视状态（PID 变化时保留，继续跟踪同一组文件）
+type logState struct {
+	mu      sync.Mutex
+	cfg     types.LogWatch
+	rules   []*logRuleState
+	files   map[string]*tailFile
+	started bool // 已完成首轮扫描，之后出现的文件从头读取
+}
+
+type logRuleState struct {
+	rule        types.LogRule
+	re          *regexp.Regexp
+	matches     uint64
+	suppressed  uint64
+	windowStart time.Time
+	windowCount int
+	pending     uint64 // 上次事件之后被频率限制的次数
+	lastMatch   time.Time
+	lastLine    string
+}
+
+// tailFile 跟踪中的文件：每轮重新打开读取，按文件标识判断滚动，按大小判断截断
+type tailFile struct {
+	path    string
+	info    os.FileInfo
+	offset  int64
+	partial []byte // 末尾未完成的行
+	tail    []byte // 已读取内容的最后若干字节，用于识别截断后又写入超过原长度的文件
+}
+
+// logMatch 一次规则匹配
+type logMatch struct {
+	rule       types.LogRule
+	file       string
+	offset     int64
+	line       string
+	suppressed uint64 // 上次事件之后被频率限制的次数
+}
+
+// validateLogWatch 检查日志监视配置
+func validateLogWatch(cfg *types.LogWatch) error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Paths) == 0 || len(cfg.Rules) == 0 {
+		return fmt.Errorf("logs requires paths and rules")
+	}
+	for _, p := range cfg.Paths {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid log path %q: %v", p, err)
+		}
+	}
+	for _, r := range cfg.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("log rule name required")
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern for log rule %s: %v", r.Name, err)
+		}
+		switch r.Severity {
+		case "", "info", "warning", "critical":
+		default:
+			return fmt.Errorf("invalid severity %q for log rule %s", r.Severity, r.Name)
+		}
+	}
+	return nil
+}
+
+// configure 按配置编译规则，同名规则保留计数
+func (ls *logState) configure(cfg types.LogWatch) {
+	old := make(map[string]*logRuleState, len(ls.rules))
+	for _, r := range ls.rules {
+		old[r.rule.Name] = r
+	}
+	ls.cfg = cfg
+	ls.rules = ls.rules[:0]
+	for _, rule := range cfg.Rules {
+		if rule.Severity == "" {
+			rule.Severity = "warning"
+		}
+		if rule.RateLimit <= 0 {
+			rule.RateLimit = 10
+		}
+		rs := &logRuleState{rule: rule, re: regexp.MustCompile(rule.Pattern)}
+		if prev, ok := old[rule.Name]; ok {
+			rs.matches, rs.suppressed, rs.lastMatch, rs.lastLine = prev.matches, prev.suppressed, prev.lastMatch, prev.lastLine
+		}
+		ls.rules = append(ls.rules, rs)
+	}
+}
+
+func (m *MultiMonitor) watchLogs(stop <-chan struct{}) {
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.scanLogs()
+		}
+	}
+}
+
+// scanLogs 读取所有配置了日志监视的目标的新增日志
+func (m *MultiMonitor) scanLogs() {
+	type job struct {
+		state  *targetState
+		target types.MonitorTarget
+	}
+	var jobs []job
+	m.mu.Lock()
+	for _, state := range m.targets {
+		if state.target.Logs == nil || state.target.Disabled {
+			continue
+		}
+		if state.logs == nil {
+			state.logs = &logState{files: make(map[string]*tailFile)}
+		}
+		jobs = append(jobs, job{state, state.target})
+	}
+	m.mu.Unlock()
+
+	for _, j := range jobs {
+		matches := j.state.logs.poll(*j.target.Logs)
+		restart := false
+		for _, mt := range matches {
+			details := map[string]interface{}{
+				"rule":     mt.rule.Name,
+				"severity": mt.rule.Severity,
+				"file":     mt.file,
+				"offset":   mt.offset,
+				"line":     mt.line,
+			}
+			if mt.suppressed > 0 {
+				details["suppressed"] = mt.suppressed
+			}
+			m.addEvent(types.Event{
+				Timestamp: time.Now(),
+				Type:      "log_match",
+				PID:       j.target.PID,
+				Name:      j.target.Name,
+				Message:   fmt.Sprintf("[%s] 日志规则 %s 匹配: %s", mt.rule.Severity, mt.rule.Name, mt.line),
+				Details:   details,
+			})
+			restart = restart || mt.rule.Restart
+		}
+		if restart && (j.target.RestartCmd != "" || j.target.Supervise != nil) {
+			m.tryRestart(j.target.PID, "log_match")
+		}
+	}
+}
+
+// poll 展开路径通配符并读取各文件新增内容，返回需要产生事件的匹配
+func (ls *logState) poll(cfg types.LogWatch) []logMatch {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.rules == nil || !reflect.DeepEqual(ls.cfg, cfg) {
+		ls.configure(cfg)
+	}
+
+	infos := make(map[string]os.FileInfo)
+	for _, pattern := range cfg.Paths {
+		paths, _ := filepath.Glob(pattern)
+		for _, p := range paths {
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				infos[p] = info
+			}
+		}
+	}
+	paths := make([]string, 0, len(infos))
+	for p := range infos {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// 按文件标识对应已跟踪的文件：路径不变的直接沿用；滚动改名（如 app.log -> app.log.1）后
+	// 在新路径继续从原偏移读取，不会把已读过的内容当作新文件重新读取
+	files := make(map[string]*tailFile, len(infos))
+	kept := make(map[*tailFile]bool)
+	for _, p := range paths {
+		if tf, ok := ls.files[p]; ok && os.SameFile(tf.info, infos[p]) {
+			files[p], kept[tf] = tf, true
+		}
+	}
+	for _, p := range paths {
+		if files[p] != nil {
+			continue
+		}
+		for _, tf := range ls.files {
+			if !kept[tf] && os.SameFile(tf.info, infos[p]) {
+				tf.path = p
+				files[p], kept[tf] = tf, true
+				break
+			}
+		}
+	}
+	for _, p := range paths {
+		if files[p] != nil {
+			continue
+		}
+		tf := &tailFile{path: p, info: infos[p]}
+		// 启动时已存在的文件默认从末尾开始；之后出现的文件（含滚动后新建的同名文件）从头读取
+		if !ls.started && !cfg.FromStart {
+			tf.offset = infos[p].Size()
+		}
+		files[p] = tf
+	}
+	// 不再匹配的文件（已删除或改名到通配符之外）停止跟踪
+	ls.files = files
+
+	var matches []logMatch
+	for _, p := range paths {
+		tf, info := files[p], infos[p]
+		if info.Size() < tf.offset {
+			// 文件被截断，从头读取
+			tf.offset, tf.partial, tf.tail = 0, nil, nil
+		}
+		tf.info = info
+		matches = append(matches, ls.readFile(tf)...)
+	}
+	ls.started = true
+	return matches
+}
+
+// readFile 读取文件新增内容并逐行匹配（调用方持有 ls.mu）
+func (ls *logState) readFile(tf *tailFile) []logMatch {
+	if tf.info.Size() <= tf.offset {
+		return nil
+	}
+	f, err := os.Open(tf.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if len(tf.tail) > 0 {
+		check := make([]byte, len(tf.tail))
+		if _, err := f.ReadAt(check, tf.offset-int64(len(check))); err != nil || !bytes.Equal(check, tf.tail) {
+			tf.offset, tf.partial, tf.tail = 0, nil, nil
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(io.NewSectionReader(f, tf.offset, tf.info.Size()-tf.offset), logReadLimit))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	// 行起始位置 = 当前偏移 - 上轮遗留的未完成部分
+	lineStart := tf.offset - int64(len(tf.partial))
+	tf.offset += int64(len(data))
+	if len(data) >= logTailCheck {
+		tf.tail = append(tf.tail[:0], data[len(data)-logTailCheck:]...)
+	} else {
+		tf.tail = append(tf.tail, data...)
+		if len(tf.tail) > logTailCheck {
+			tf.tail = tf.tail[len(tf.tail)-logTailCheck:]
+		}
+	}
+	buf := append(tf.partial, data...)
+	tf.partial = nil
+
+	var matches []logMatch
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimRight(buf[:i], "\r")
+		matches = append(matches, ls.matchLine(tf.path, lineStart, line)...)
+		lineStart += int64(i + 1)
+		buf = buf[i+1:]
+	}
+	if len(buf) > logLineLimit {
+		// 超长行：按截断处理，避免无限缓存
+		matches = append(matches, ls.matchLine(tf.path, lineStart, buf[:runeCut(buf, logLineLimit)])...)
+		buf = nil
+	}
+	if len(buf) > 0 {
+		tf.partial = append([]byte(nil), buf...)
+	}
+	return matches
+}
+
+// runeCut 返回不超过 n 的截断位置，不切开多字节字符（中文日志每个字符占 3 字节）
+func runeCut(b []byte, n int) int {
+	if len(b) <= n {
+		return len(b)
+	}
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return n
+}
+
+// matchLine 按规则匹配一行，更新计数并按每分钟频率限制决定是否产生事件（调用方持有 ls.mu）
+func (ls *logState) matchLine(path string, offset int64, line []byte) []logMatch {
+	var matches []logMatch
+	now := time.Now()
+	for _, r := range ls.rules {
+		if !r.re.Match(line) {
+			continue
+		}
+		text := string(line[:runeCut(line, logEventLine)])
+		r.matches++
+		r.lastMatch, r.lastLine = now, text
+		if now.Sub(r.windowStart) >= time.Minute {
+			r.windowStart, r.windowCount = now, 0
+		}
+		if r.windowCount >= r.rule.RateLimit {
+			r.suppressed++
+			r.pending++
+			continue
+		}
+		r.windowCount++
+		matches = append(matches, logMatch{rule: r.rule, file: path, offset: offset, line: text, suppressed: r.pending})
+		r.pending = 0
+	}
+	return matches
+}
+
+// counts 各规则累计匹配次数
+func (ls *logState) counts() map[string]uint64 {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	result := make(map[string]uint64, len(ls.rules))
+	for _, r := range ls.rules {
+		result[r.rule.Name] = r.matches
+	}
+	return result
+}
+
+// GetLogWatch 获取日志监视状态（按 PID 排序），pid 为 0 时返回全部
+func (m *MultiMonitor) GetLogWatch(pid int32) []types.LogWatchStatus {
+	m.mu.RLock()
+	states := make(map[*logState]types.LogWatchStatus)
+	for p, state := range m.targets {
+		if state.logs != nil && (pid == 0 || p == pid) {
+			states[state.logs] = types.LogWatchStatus{PID: p, Name: state.target.Name, Files: []types.LogFileStatus{}, Rules: []types.LogRuleStatus{}}
+		}
+	}
+	m.mu.RUnlock()
+
+	result := []types.LogWatchStatus{}
+	for ls, st := range states {
+		ls.mu.Lock()
+		for path, tf := range ls.files {
+			st.Files = append(st.Files, types.LogFileStatus{Path: path, Offset: tf.offset})
+		}
+		for _, r := range ls.rules {
+			st.Rules = append(st.Rules, types.LogRuleStatus{
+				Name: r.rule.Name, Severity: r.rule.Severity, Matches: r.matches, Suppressed: r.suppressed,
+				LastMatch: r.lastMatch, LastLine: r.lastLine,
+			})
+		}
+		ls.mu.Unlock()
+		sort.Slice(st.Files, func(i, j int) bool { return st.Files[i].Path < st.Files[j].Path })
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PID < result[j].PID })
+	return result
+}
@@ -6,12 +6,15 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"monitor-agent/buffer"
+	"monitor-agent/history"
 	"monitor-agent/provider"
 	"monitor-agent/types"
 )
@@ -23,20 +26,57 @@ type MultiMonitor struct {
 	targets        map[int32]*targetState // PID -> 状态
 	metricsBuffers map[int32]*buffer.RingBuffer[types.ProcessMetrics]
 	eventsBuffer   *buffer.RingBuffer[types.Event]
+	systemBuffer   *buffer.RingBuffer[types.SystemMetrics]
 	config         types.MultiMonitorConfig
 	running        bool
 	stopCh         chan struct{}
 	logFile        *os.File
+	history        *history.Store
+	baselines      *baselineStore
+	forensics      *forensicsRecorder // 未启用时为 nil
+	cores          *coreWatcher       // 未启用时为 nil
+	inventory      *inventoryStore    // 未启用时为 nil
+	disks          *diskMonitor       // 未启用时为 nil
+	sensors        *sensorMonitor     // 未启用时为 nil
+	unknownApps    map[string]bool    // 未绑定目标的心跳应用（只记录一次日志）
+	redundancy     map[string]*redundancyGroup
+	checks         map[string]*execCheck // 外部检查
+	checkSlots     chan struct{}         // 外部检查并发上限
+	pendingStarts  []types.MonitorTarget // 等待上游就绪后启动的托管目标
+	groups         map[string]types.AppGroup
+	discovery      map[string]*discoveryRule
+	sinks          []Sink
+}
+
+// Sink 指标/事件订阅者（MQTT 发布、外发导出等），回调中不应阻塞
+type Sink interface {
+	// OnMetric 每次采样后回调
+	OnMetric(target types.MonitorTarget, metric types.ProcessMetrics)
+	// OnEvent 产生事件时回调
+	OnEvent(evt types.Event)
 }
 
 type targetState struct {
-	target        types.MonitorTarget
-	cpuExceedCnt  int
-	memExceedCnt  int
-	lastRestart   time.Time
-	lastMetric    *types.ProcessMetrics
-	exitReported  bool // 是否已报告退出事件
-	restartCount  int  // 重启次数统计
+	target       types.MonitorTarget
+	cpuExceedCnt int
+	memExceedCnt int
+	lastRestart  time.Time
+	lastMetric   *types.ProcessMetrics
+	exitReported bool        // 是否已报告退出事件
+	restartCount int         // 重启次数统计
+	sup          *supervisor // 托管进程（仅托管模式）
+	hang         *hangState  // 挂死检测状态
+	leak         *leakState  // 泄漏趋势分析状态
+	baseline     *baselineState
+	kernelCause  *kernelEvent     // 内核日志记录的最近一次终止原因（OOM/段错误）
+	dep          *dependencyState // 依赖状态（有上游或被依赖时创建）
+	held         bool             // 人工停止（分组停止），启动前不自动重启
+	integrity    *integrityState  // 完整性校验记录
+	sockets      *socketState     // 套接字检查状态
+	logs         *logState        // 日志监视状态
+	freshness    []freshnessState // 输出文件新鲜度检查状态（与 target.Freshness 对应）
+	heartbeat    *heartbeatState  // 应用心跳状态
+	custom       *customState     // 应用推送的自定义指标
 }
 
 func NewMultiMonitor(cfg types.MultiMonitorConfig, prov provider.ProcProvider) (*MultiMonitor, error) {
@@ -49,10 +89,21 @@ func NewMultiMonitor(cfg types.MultiMonitorConfig, prov provider.ProcProvider) (
 	if cfg.EventsBufferLen <= 0 {
 		cfg.EventsBufferLen = 100
 	}
+	if cfg.SystemInterval <= 0 {
+		cfg.SystemInterval = 5
+	}
 	if cfg.LogDir == "" {
 		cfg.LogDir = "logs"
 	}
 	os.MkdirAll(cfg.LogDir, 0755)
+	if cfg.HistoryDir == "" {
+		cfg.HistoryDir = filepath.Join(cfg.LogDir, "history")
+	}
+
+	hist, err := history.Open(cfg.HistoryDir, cfg.HistoryRetention)
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建日志文件
 	logPath := fmt.Sprintf("%s/multi_monitor_%s.jsonl", cfg.LogDir, time.Now().Format("20060102_150405"))
@@ -66,9 +117,24 @@ func NewMultiMonitor(cfg types.MultiMonitorConfig, prov provider.ProcProvider) (
 		targets:        make(map[int32]*targetState),
 		metricsBuffers: make(map[int32]*buffer.RingBuffer[types.ProcessMetrics]),
 		eventsBuffer:   buffer.NewRingBuffer[types.Event](cfg.EventsBufferLen),
+		systemBuffer:   buffer.NewRingBuffer[types.SystemMetrics](cfg.MetricsBufferLen),
 		config:         cfg,
 		stopCh:         make(chan struct{}),
 		logFile:        logFile,
+		history:        hist,
+		baselines:      loadBaselineStore(filepath.Join(cfg.LogDir, "baselines.json")),
+		forensics:      newForensicsRecorder(cfg.Forensics, cfg.LogDir),
+		cores:          newCoreWatcher(cfg.CoreDumps, cfg.LogDir),
+		inventory:      newInventoryStore(cfg.Inventory, cfg.LogDir),
+		disks:          newDiskMonitor(cfg.Disk, cfg.MetricsBufferLen),
+		sensors:        newSensorMonitor(cfg.Sensors),
+	}
+	if cfg.Checks.MaxConcurrent <= 0 {
+		cfg.Checks.MaxConcurrent = 4
+	}
+	m.checkSlots = make(chan struct{}, cfg.Checks.MaxConcurrent)
+	if cfg.Checks.File != "" {
+		m.loadChecks(cfg.Checks.File)
 	}
 
 	return m, nil
@@ -76,12 +142,34 @@ func NewMultiMonitor(cfg types.MultiMonitorConfig, prov provider.ProcProvider) (
 
 // AddTarget 添加监控目标
 func (m *MultiMonitor) AddTarget(target types.MonitorTarget) error {
+	if err := validateLogWatch(target.Logs); err != nil {
+		return err
+	}
+	if err := validateFreshness(target.Freshness); err != nil {
+		return err
+	}
+	if target.Supervise != nil {
+		if len(target.DependsOn) > 0 {
+			return m.deferSupervisedStart(target)
+		}
+		return m.addSupervisedTarget(target)
+	}
+
+	// 记录文件哈希（计算较慢，加锁前完成）
+	var integ *integrityState
+	if target.Integrity != nil {
+		integ = m.recordIntegrity(target, "")
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if _, exists := m.targets[target.PID]; exists {
 		return fmt.Errorf("target PID %d already monitored", target.PID)
 	}
+	if err := m.validateDependenciesLocked(target, 0); err != nil {
+		return err
+	}
 
 	// 验证进程存在
 	if !m.provider.IsAlive(target.PID) {
@@ -96,7 +184,7 @@ func (m *MultiMonitor) AddTarget(target types.MonitorTarget) error {
 		initialMetric = met
 	}
 
-	state := &targetState{target: target, lastMetric: initialMetric}
+	state := &targetState{target: target, lastMetric: initialMetric, integrity: integ}
 	m.targets[target.PID] = state
 	
 	buf := buffer.NewRingBuffer[types.ProcessMetrics](m.config.MetricsBufferLen)
@@ -109,10 +197,21 @@ func (m *MultiMonitor) AddTarget(target types.MonitorTarget) error {
 	return nil
 }
 
+// AddSink 注册指标/事件订阅者
+func (m *MultiMonitor) AddSink(sink Sink) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sinks = append(m.sinks, sink)
+}
+
 // RemoveTarget 移除监控目标
 func (m *MultiMonitor) RemoveTarget(pid int32) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	// 托管进程由代理持有，移除目标时一并停止
+	if state, ok := m.targets[pid]; ok {
+		m.stopSupervisedLocked(state)
+	}
 	delete(m.targets, pid)
 	delete(m.metricsBuffers, pid)
 	log.Printf("[INFO] Removed monitor target: PID=%d", pid)
@@ -122,13 +221,34 @@ func (m *MultiMonitor) RemoveTarget(pid int32) {
 func (m *MultiMonitor) RemoveAllTargets() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	for _, state := range m.targets {
+		m.stopSupervisedLocked(state)
+	}
 	m.targets = make(map[int32]*targetState)
 	m.metricsBuffers = make(map[int32]*buffer.RingBuffer[types.ProcessMetrics])
+	m.pendingStarts = nil
 	log.Printf("[INFO] Removed all monitor targets")
 }
 
 // UpdateTarget 更新监控目标配置
 func (m *MultiMonitor) UpdateTarget(target types.MonitorTarget) error {
+	if err := validateLogWatch(target.Logs); err != nil {
+		return err
+	}
+	if err := validateFreshness(target.Freshness); err != nil {
+		return err
+	}
+	var integ *integrityState
+	if target.Integrity != nil {
+		m.mu.RLock()
+		exe := ""
+		if state, ok := m.targets[target.PID]; ok && state.integrity != nil {
+			exe = state.integrity.exe
+		}
+		m.mu.RUnlock()
+		integ = m.recordIntegrity(target, exe)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
@@ -136,9 +256,20 @@ func (m *MultiMonitor) UpdateTarget(target types.MonitorTarget) error {
 	if !exists {
 		return fmt.Errorf("target PID %d not found", target.PID)
 	}
+	if err := m.validateDependenciesLocked(target, target.PID); err != nil {
+		return err
+	}
 	
-	// 保留原有状态，只更新配置
+	// 保留原有状态，只更新配置；托管参数在进程启动时确定，不可修改
+	if state.sup != nil {
+		target.Supervise = state.target.Supervise
+	}
 	state.target = target
+	if integ == nil {
+		state.integrity = nil
+	} else {
+		state.integrity = mergeIntegrity(state.integrity, integ)
+	}
 	log.Printf("[INFO] Updated monitor target: PID=%d Name=%s AutoRestart=%v CPUThreshold=%.2f", 
 		target.PID, target.Name, target.AutoRestart, target.CPUThreshold)
 	return nil
@@ -154,12 +285,16 @@ func (m *MultiMonitor) GetTargetStats(pid int32) map[string]interface{} {
 		return nil
 	}
 	
-	return map[string]interface{}{
+	stats := map[string]interface{}{
 		"restart_count":   state.restartCount,
 		"last_restart":    state.lastRestart,
 		"cpu_exceed_cnt":  state.cpuExceedCnt,
 		"mem_exceed_cnt":  state.memExceedCnt,
 	}
+	if state.dep != nil && len(state.dep.degradedBy) > 0 {
+		stats["degraded_by"] = state.dep.degradedBy
+	}
+	return stats
 }
 
 // GetTargets 获取所有监控目标（按 PID 排序）
@@ -198,9 +333,31 @@ func (m *MultiMonitor) Start() {
 			m.logFile = f
 		}
 	}
+	stopCh := m.stopCh
 	m.mu.Unlock()
 
 	go m.loop()
+	if m.config.KernelLog != "" {
+		go m.watchKernelLog(m.config.KernelLog, stopCh)
+	}
+	if m.cores != nil {
+		go m.watchCoreDumps(stopCh)
+	}
+	go m.watchDependencies(stopCh)
+	go m.watchDiscovery(stopCh)
+	go m.watchLogs(stopCh)
+	go m.watchSystem(stopCh)
+	if m.inventory != nil {
+		go m.watchInventory(stopCh)
+	}
+	if m.disks != nil {
+		go m.watchDisks(stopCh)
+	}
+	if m.sensors != nil {
+		go m.watchSensors(stopCh)
+	}
+	m.startHeartbeatListeners(m.config.Heartbeat, stopCh)
+	m.startCustomListeners(m.config.CustomMetrics, stopCh)
 	log.Printf("[INFO] MultiMonitor started")
 }
 
@@ -218,6 +375,7 @@ func (m *MultiMonitor) Stop() {
 		m.logFile.Close()
 		m.logFile = nil
 	}
+	m.baselines.save(true)
 	log.Printf("[INFO] MultiMonitor stopped")
 }
 
@@ -246,6 +404,7 @@ func (m *MultiMonitor) collectAll() {
 	for _, pid := range pids {
 		m.collectOne(pid)
 	}
+	m.baselines.save(false)
 }
 
 func (m *MultiMonitor) collectOne(pid int32) {
@@ -257,9 +416,18 @@ func (m *MultiMonitor) collectOne(pid int32) {
 	}
 	buf := m.metricsBuffers[pid]
 	target := state.target
+	reattach := state.sup == nil && state.exitReported && !state.held && !state.lastRestart.IsZero()
 	m.mu.Unlock()
 
+	if target.Disabled {
+		return
+	}
+
 	alive := m.provider.IsAlive(pid)
+	// 重启命令启动了新进程：迁移到新 PID，下一轮按新 PID 采集
+	if !alive && reattach && target.RestartCmd != "" && m.reattachTarget(&target) != 0 {
+		return
+	}
 	metric := types.ProcessMetrics{
 		Timestamp: time.Now(),
 		PID:       pid,
@@ -298,8 +466,8 @@ func (m *MultiMonitor) collectOne(pid int32) {
 				}
 				m.addEvent(evt)
 				
-				// 如果配置了重启命令，执行重启
-				if target.RestartCmd != "" {
+				// 如果配置了重启命令（或为托管进程），执行重启
+				if target.RestartCmd != "" || target.Supervise != nil {
 					m.tryRestart(pid, "cpu_threshold")
 				}
 				
@@ -334,7 +502,7 @@ func (m *MultiMonitor) collectOne(pid int32) {
 				}
 				m.addEvent(evt)
 				
-				if target.RestartCmd != "" {
+				if target.RestartCmd != "" || target.Supervise != nil {
 					m.tryRestart(pid, "mem_threshold")
 				}
 				
@@ -347,18 +515,46 @@ func (m *MultiMonitor) collectOne(pid int32) {
 			state.memExceedCnt = 0
 			m.mu.Unlock()
 		}
+
+		// 挂死检测
+		m.checkHang(state, target)
+		m.checkIntegrity(state, target)
+		m.checkSockets(state, target, &metric)
+		m.checkFreshness(state, target)
+		m.checkHeartbeat(state, target)
+	}
+	// 日志规则累计匹配次数随指标记录
+	m.mu.RLock()
+	logs := state.logs
+	m.mu.RUnlock()
+	if logs != nil && target.Logs != nil {
+		metric.LogMatches = logs.counts()
+	}
+	metric.Custom = m.collectCustom(state, metric.Timestamp)
+	if alive {
+		m.checkCustomThresholds(state, target, metric)
 	}
 
 	buf.Push(metric)
 	m.mu.Lock()
 	state.lastMetric = &metric
-	exitReported := state.exitReported
-	autoRestart := target.AutoRestart
+	// 托管进程的退出由 superviseLoop 记录（含准确的退出码/信号）
+	exitReported := state.exitReported || state.sup != nil
+	autoRestart := target.AutoRestart && !state.held
+	held := state.held
 	restartCmd := target.RestartCmd
 	m.mu.Unlock()
 
 	// 写入日志
 	m.writeLog(metric)
+	m.history.WriteMetric(metric)
+	if alive {
+		m.checkLeak(state, target, metric)
+		m.checkBaseline(state, target, metric)
+	}
+	for _, sink := range m.getSinks() {
+		sink.OnMetric(target, metric)
+	}
 
 	// 检查规则：只在首次检测到退出时报告
 	if !alive && !exitReported {
@@ -373,6 +569,9 @@ func (m *MultiMonitor) collectOne(pid int32) {
 			Name:      target.Name,
 			Message:   "进程已退出",
 		}
+		if held {
+			evt.Details = map[string]interface{}{"planned": true}
+		}
 		m.addEvent(evt)
 		
 		// 自动重启
@@ -384,6 +583,12 @@ func (m *MultiMonitor) collectOne(pid int32) {
 
 // tryRestart 尝试重启进程
 func (m *MultiMonitor) tryRestart(pid int32, reason string) {
+	m.restartTarget(pid, reason, false)
+}
+
+// restartTarget 检查上游、冷却和完整性后重启进程；kill 为 true 时确定重启后先结束旧进程
+// （挂死等不会自行退出的情况，被拒绝重启时不结束进程）
+func (m *MultiMonitor) restartTarget(pid int32, reason string, kill bool) {
 	m.mu.Lock()
 	state, exists := m.targets[pid]
 	if !exists {
@@ -396,6 +601,17 @@ func (m *MultiMonitor) tryRestart(pid int32, reason string) {
 	if cooldown <= 0 {
 		cooldown = 30 // 默认30秒冷却
 	}
+
+	// 上游未就绪：退出后的重启推迟到上游就绪，其余原因的重启直接放弃
+	if state.dep != nil && len(state.dep.degradedBy) > 0 {
+		down := strings.Join(state.dep.degradedBy, ",")
+		if reason == "exit" {
+			state.dep.waitRestart = reason
+		}
+		m.mu.Unlock()
+		log.Printf("[INFO] 上游 %s 未就绪，推迟重启 PID=%d (原因:%s)", down, pid, reason)
+		return
+	}
 	
 	// 检查冷却时间
 	if time.Since(state.lastRestart) < time.Duration(cooldown)*time.Second {
@@ -403,15 +619,44 @@ func (m *MultiMonitor) tryRestart(pid int32, reason string) {
 		log.Printf("[INFO] 重启冷却中，跳过重启 PID=%d", pid)
 		return
 	}
+
+	// 重启前复核完整性（计算哈希时不持有锁）
+	if state.integrity != nil {
+		m.mu.Unlock()
+		if m.integrityBlocksRestart(state, reason) {
+			return
+		}
+		m.mu.Lock()
+	}
+
+	// 托管进程：终止后由 superviseLoop 重新拉起
+	if state.sup != nil {
+		m.mu.Unlock()
+		m.requestSupervisedRestart(state, reason)
+		return
+	}
 	
 	state.lastRestart = time.Now()
 	state.restartCount++
 	restartCount := state.restartCount
-	restartCmd := target.RestartCmd
-	targetName := target.Name
 	m.mu.Unlock()
 	
-	// 执行重启命令
+	if kill {
+		if err := m.provider.KillProcess(pid); err != nil {
+			m.addEvent(types.Event{
+				Timestamp: time.Now(),
+				Type:      reason,
+				PID:       pid,
+				Name:      target.Name,
+				Message:   fmt.Sprintf("结束旧进程失败: %v", err),
+			})
+		}
+	}
+	m.runRestartCmd(pid, target.Name, target.RestartCmd, reason, restartCount)
+}
+
+// runRestartCmd 异步执行重启命令并记录 restart 事件
+func (m *MultiMonitor) runRestartCmd(pid int32, targetName, restartCmd, reason string, restartCount int) {
 	go func() {
 		var cmd *exec.Cmd
 		var cmdStr string
@@ -440,6 +685,7 @@ func (m *MultiMonitor) tryRestart(pid int32, reason string) {
 			Type:      "restart",
 			PID:       pid,
 			Name:      targetName,
+			Details:   map[string]interface{}{"reason": reason, "success": err == nil},
 		}
 		
 		if err != nil {
@@ -462,9 +708,37 @@ func (m *MultiMonitor) writeLog(v any) {
 }
 
 func (m *MultiMonitor) addEvent(evt types.Event) {
+	// 上游未就绪期间下游的告警类事件只记录日志，由 degraded 事件统一说明
+	if down := m.isDegraded(evt.PID); len(down) > 0 {
+		if suppressedWhenDegraded[evt.Type] {
+			log.Printf("[INFO] 上游 %s 未就绪，抑制事件 %s: %s (pid=%d)", strings.Join(down, ","), evt.Type, evt.Message, evt.PID)
+			return
+		}
+		if evt.Details == nil {
+			evt.Details = make(map[string]interface{})
+		}
+		evt.Details["degraded_by"] = down
+	}
+	if evt.Type == "exit" {
+		m.enrichExitCause(&evt)
+		if m.cores != nil {
+			m.cores.noteExit(evt.PID, evt.Name)
+		}
+	}
+	m.captureForensics(&evt)
 	m.eventsBuffer.Push(evt)
 	m.writeLog(evt)
+	m.history.WriteEvent(evt)
 	log.Printf("[EVENT] %s: %s (pid=%d)", evt.Type, evt.Message, evt.PID)
+	for _, sink := range m.getSinks() {
+		sink.OnEvent(evt)
+	}
+}
+
+func (m *MultiMonitor) getSinks() []Sink {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sinks
 }
 
 // GetMetrics 获取指定进程的最近指标
@@ -508,7 +782,43 @@ func (m *MultiMonitor) ListAllProcesses() ([]types.ProcessInfo, error) {
 	return m.provider.ListAllProcesses()
 }
 
-// GetSystemMetrics 获取系统指标
+// History 获取历史存储
+func (m *MultiMonitor) History() *history.Store {
+	return m.history
+}
+
+// GetSystemMetrics 获取系统指标（启用传感器监控时附带最近一次传感器读数）
 func (m *MultiMonitor) GetSystemMetrics() (*types.SystemMetrics, error) {
-	return m.provider.GetSystemMetrics()
+	sm, err := m.provider.GetSystemMetrics()
+	if err != nil {
+		return nil, err
+	}
+	if m.sensors != nil {
+		sm.Sensors, _ = m.GetSensors()
+	}
+	return sm, nil
+}
+
+// GetSystemSeries 获取最近 n 次记录的系统指标
+func (m *MultiMonitor) GetSystemSeries(n int) []types.SystemMetrics {
+	return m.systemBuffer.GetRecent(n)
+}
+
+// watchSystem 按 SystemInterval 记录系统指标到环形缓冲和历史存储
+func (m *MultiMonitor) watchSystem(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(m.config.SystemInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sm, err := m.GetSystemMetrics()
+			if err != nil {
+				continue
+			}
+			m.systemBuffer.Push(*sm)
+			m.history.WriteSystem(*sm)
+		}
+	}
 }
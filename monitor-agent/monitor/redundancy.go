@@ -0,0 +1,513 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// redundancyGroup 冗余组运行状态
+type redundancyGroup struct {
+	cfg  types.RedundancyGroup
+	opMu sync.Mutex // 串行化探测与切换操作（命令执行期间持有）
+	stop chan struct{}
+
+	mu           sync.Mutex
+	active       string
+	members      []types.RedundancyMemberStatus
+	pending      *types.SwitchoverRecord // 已执行升主命令，等待成为主用
+	downSince    time.Time               // 无主用的起始时间
+	downReported bool
+	dualReported bool
+	failedAt     map[string]time.Time // 成员最近一次升主失败时间
+	switchovers  int
+	last         *types.SwitchoverRecord
+}
+
+func redundancyDefaults(cfg types.RedundancyGroup) types.RedundancyGroup {
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = 5
+	}
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = 10
+	}
+	if cfg.FailoverDelay <= 0 {
+		cfg.FailoverDelay = 3
+	}
+	if cfg.PromoteTimeout <= 0 {
+		cfg.PromoteTimeout = 30
+	}
+	return cfg
+}
+
+// AddRedundancyGroup 添加冗余组并开始探测
+func (m *MultiMonitor) AddRedundancyGroup(cfg types.RedundancyGroup) error {
+	if cfg.Name == "" {
+		return fmt.Errorf("group name required")
+	}
+	if len(cfg.Members) < 2 {
+		return fmt.Errorf("redundancy group needs at least 2 members")
+	}
+	names := make(map[string]bool)
+	for _, mem := range cfg.Members {
+		if mem.Name == "" || mem.Target == "" || mem.PromoteCmd == "" {
+			return fmt.Errorf("member requires name, target and promote_cmd")
+		}
+		if names[mem.Name] {
+			return fmt.Errorf("duplicate member %s", mem.Name)
+		}
+		names[mem.Name] = true
+	}
+	cfg = redundancyDefaults(cfg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.redundancy == nil {
+		m.redundancy = make(map[string]*redundancyGroup)
+	}
+	if _, exists := m.redundancy[cfg.Name]; exists {
+		return fmt.Errorf("redundancy group %s already exists", cfg.Name)
+	}
+	g := &redundancyGroup{cfg: cfg, stop: make(chan struct{}), failedAt: make(map[string]time.Time)}
+	m.redundancy[cfg.Name] = g
+	go m.redundancyLoop(g)
+	log.Printf("[INFO] Added redundancy group: %s (%d members)", cfg.Name, len(cfg.Members))
+	return nil
+}
+
+// RemoveRedundancyGroup 移除冗余组（不影响成员进程）
+func (m *MultiMonitor) RemoveRedundancyGroup(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.redundancy[name]
+	if !ok {
+		return fmt.Errorf("redundancy group %s not found", name)
+	}
+	close(g.stop)
+	delete(m.redundancy, name)
+	log.Printf("[INFO] Removed redundancy group: %s", name)
+	return nil
+}
+
+// GetRedundancyStatus 获取所有冗余组状态（按名称排序）
+func (m *MultiMonitor) GetRedundancyStatus() []types.RedundancyStatus {
+	m.mu.RLock()
+	groups := make([]*redundancyGroup, 0, len(m.redundancy))
+	for _, g := range m.redundancy {
+		groups = append(groups, g)
+	}
+	m.mu.RUnlock()
+
+	result := make([]types.RedundancyStatus, 0, len(groups))
+	for _, g := range groups {
+		g.mu.Lock()
+		st := types.RedundancyStatus{
+			Group:          g.cfg,
+			Active:         g.active,
+			Members:        append([]types.RedundancyMemberStatus(nil), g.members...),
+			Switchovers:    g.switchovers,
+			LastSwitchover: g.last,
+		}
+		if g.pending != nil {
+			st.Switching = g.pending.To
+		}
+		g.mu.Unlock()
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Group.Name < result[j].Group.Name })
+	return result
+}
+
+// SwitchoverRedundancy 手动主备切换，to 为空时切换到优先级顺序中的下一个成员
+func (m *MultiMonitor) SwitchoverRedundancy(name, to string) error {
+	m.mu.RLock()
+	g, ok := m.redundancy[name]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("redundancy group %s not found", name)
+	}
+
+	g.opMu.Lock()
+	defer g.opMu.Unlock()
+
+	g.mu.Lock()
+	from := g.active
+	pending := g.pending != nil
+	g.mu.Unlock()
+	if pending {
+		return fmt.Errorf("switchover in progress")
+	}
+	if to == "" {
+		to = g.nextMember(from)
+	}
+	if g.member(to) == nil {
+		return fmt.Errorf("member %s not found", to)
+	}
+	if to == from {
+		return fmt.Errorf("member %s is already active", to)
+	}
+
+	rec := m.promoteMember(g, from, to, "manual", time.Now())
+	if rec.Error != "" {
+		return fmt.Errorf("%s", rec.Error)
+	}
+	return nil
+}
+
+func (m *MultiMonitor) redundancyLoop(g *redundancyGroup) {
+	ticker := time.NewTicker(time.Duration(g.cfg.ProbeInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+			if m.IsRunning() {
+				m.evaluateRedundancy(g)
+			}
+		}
+	}
+}
+
+// evaluateRedundancy 探测成员状态：无主用时按优先级切换，多主时保留当前主用并降级其余成员
+func (m *MultiMonitor) evaluateRedundancy(g *redundancyGroup) {
+	g.opMu.Lock()
+	defer g.opMu.Unlock()
+
+	statuses := make([]types.RedundancyMemberStatus, len(g.cfg.Members))
+	var actives []string
+	for i, mem := range g.cfg.Members {
+		statuses[i] = m.probeMember(g, mem)
+		if statuses[i].Active {
+			actives = append(actives, mem.Name)
+		}
+	}
+	now := time.Now()
+
+	g.mu.Lock()
+	g.members = statuses
+	pending := g.pending
+	if pending != nil {
+		if containsString(actives, pending.To) {
+			g.mu.Unlock()
+			pending.CompletedAt = now
+			m.finishSwitchover(g, pending, true)
+			return
+		}
+		if now.Sub(pending.PromotedAt) > time.Duration(g.cfg.PromoteTimeout)*time.Second {
+			g.mu.Unlock()
+			pending.Error = fmt.Sprintf("成员 %s 在 %d 秒内未成为主用", pending.To, g.cfg.PromoteTimeout)
+			m.finishSwitchover(g, pending, false)
+			return
+		}
+		g.mu.Unlock()
+		return
+	}
+
+	switch {
+	case len(actives) == 1:
+		prev := g.active
+		g.active = actives[0]
+		g.downSince = time.Time{}
+		g.downReported = false
+		g.dualReported = false
+		g.mu.Unlock()
+		// 非代理发起的切换（如成员自行接管）
+		if prev != "" && prev != actives[0] {
+			m.finishSwitchover(g, &types.SwitchoverRecord{
+				From: prev, To: actives[0], Reason: "external", DetectedAt: now, PromotedAt: now, CompletedAt: now,
+			}, true)
+		}
+
+	case len(actives) > 1:
+		keep := g.active
+		if !containsString(actives, keep) {
+			keep = actives[0] // 按优先级保留
+		}
+		g.active = keep
+		reported := g.dualReported
+		g.dualReported = true
+		g.mu.Unlock()
+		var demoted []string
+		for _, name := range actives {
+			if name != keep {
+				m.demoteMember(g, name)
+				demoted = append(demoted, name)
+			}
+		}
+		if reported {
+			return // 降级尚未生效，不重复上报
+		}
+		m.addEvent(types.Event{
+			Timestamp: now,
+			Type:      "dual_active",
+			PID:       g.memberPID(keep),
+			Name:      g.cfg.Name,
+			Message:   fmt.Sprintf("冗余组 %s 多个成员同时主用 (%s)，保留 %s，降级 %s", g.cfg.Name, strings.Join(actives, ","), keep, strings.Join(demoted, ",")),
+			Details:   map[string]interface{}{"active": actives, "kept": keep, "demoted": demoted},
+		})
+
+	default:
+		g.dualReported = false
+		if g.downSince.IsZero() {
+			g.downSince = now
+		}
+		detectedAt := g.downSince
+		from := g.active
+		if now.Sub(detectedAt) < time.Duration(g.cfg.FailoverDelay)*time.Second {
+			g.mu.Unlock()
+			return
+		}
+		to := g.candidate(from, now)
+		if to == "" {
+			reported := g.downReported
+			g.downReported = true
+			g.mu.Unlock()
+			if !reported {
+				m.addEvent(types.Event{
+					Timestamp: now,
+					Type:      "redundancy_down",
+					Name:      g.cfg.Name,
+					Message:   fmt.Sprintf("冗余组 %s 无主用成员且没有可切换的备用成员", g.cfg.Name),
+				})
+			}
+			return
+		}
+		g.mu.Unlock()
+		m.promoteMember(g, from, to, "failover", detectedAt)
+	}
+}
+
+// probeMember 探测成员：有探测命令时以退出码判定主用，否则以进程存活判定
+func (m *MultiMonitor) probeMember(g *redundancyGroup, mem types.RedundancyMember) types.RedundancyMemberStatus {
+	st := types.RedundancyMemberStatus{Name: mem.Name, Target: mem.Target}
+
+	m.mu.RLock()
+	var target *types.MonitorTarget
+	supervised := false
+	for _, state := range m.targets {
+		if state.target.Alias == mem.Target || state.target.Name == mem.Target {
+			t := state.target
+			target, supervised = &t, state.sup != nil
+			break
+		}
+	}
+	m.mu.RUnlock()
+
+	if target != nil {
+		st.PID = target.PID
+		st.Alive = m.provider.IsAlive(target.PID)
+		// 升主命令启动了新进程：重新关联到未被监控的同名进程
+		if !st.Alive && !supervised {
+			if pid := m.reattachTarget(target); pid != 0 {
+				st.PID, st.Alive = pid, true
+			}
+		}
+	}
+
+	if mem.ProbeCmd == "" {
+		st.Active = st.Alive
+		return st
+	}
+	code, _, err := runCommand(mem.ProbeCmd, time.Duration(g.cfg.ProbeTimeout)*time.Second)
+	st.Active = err == nil && code == 0
+	return st
+}
+
+// reattachTarget 目标进程已退出时，查找唯一一个未被监控的同名进程并迁移监控状态
+func (m *MultiMonitor) reattachTarget(target *types.MonitorTarget) int32 {
+	pids, err := m.provider.FindAllPIDsByName(target.Name)
+	if err != nil {
+		return 0
+	}
+	m.mu.Lock()
+	var candidates []int32
+	for _, pid := range pids {
+		if _, monitored := m.targets[pid]; !monitored {
+			candidates = append(candidates, pid)
+		}
+	}
+	if _, ok := m.targets[target.PID]; !ok || len(candidates) != 1 {
+		m.mu.Unlock()
+		return 0
+	}
+	m.rekeyTargetLocked(target.PID, candidates[0])
+	m.mu.Unlock()
+
+	// 记录 PID 变化，可用性报告据此把新旧进程视为同一目标
+	m.addEvent(types.Event{
+		Timestamp: time.Now(),
+		Type:      "reattach",
+		PID:       candidates[0],
+		Name:      target.Name,
+		Message:   fmt.Sprintf("重新关联到新进程 PID=%d (原 PID=%d)", candidates[0], target.PID),
+		Details:   map[string]interface{}{"old_pid": target.PID, "new_pid": candidates[0]},
+	})
+	return candidates[0]
+}
+
+// promoteMember 执行升主命令（先降级原主用：手动切换总是降级；故障切换执行配置的降级命令，
+// 未配置时原主用进程仍存活（如卡死、链路异常）则结束进程，避免两个成员同时运行），成功后等待探测确认
+func (m *MultiMonitor) promoteMember(g *redundancyGroup, from, to, reason string, detectedAt time.Time) *types.SwitchoverRecord {
+	if from != "" {
+		if mem := g.member(from); mem != nil {
+			pid := g.memberPID(from)
+			if mem.DemoteCmd != "" || reason == "manual" || (pid != 0 && m.provider.IsAlive(pid)) {
+				m.demoteMember(g, from)
+			}
+		}
+	}
+
+	rec := &types.SwitchoverRecord{From: from, To: to, Reason: reason, DetectedAt: detectedAt, PromotedAt: time.Now()}
+	log.Printf("[INFO] 冗余组 %s 升主: %s -> %s (原因:%s)", g.cfg.Name, from, to, reason)
+	code, out, err := runCommand(g.member(to).PromoteCmd, time.Duration(g.cfg.ProbeTimeout)*time.Second)
+	if err != nil || code != 0 {
+		if err == nil {
+			err = fmt.Errorf("exit code %d", code)
+		}
+		rec.Error = fmt.Sprintf("升主命令失败: %v %s", err, out)
+		m.finishSwitchover(g, rec, false)
+		return rec
+	}
+
+	g.mu.Lock()
+	g.pending = rec
+	g.mu.Unlock()
+	return rec
+}
+
+// demoteMember 降级成员：执行降级命令，未配置时结束进程
+func (m *MultiMonitor) demoteMember(g *redundancyGroup, name string) {
+	mem := g.member(name)
+	if mem == nil {
+		return
+	}
+	if mem.DemoteCmd != "" {
+		if code, out, err := runCommand(mem.DemoteCmd, time.Duration(g.cfg.ProbeTimeout)*time.Second); err != nil || code != 0 {
+			log.Printf("[WARN] 冗余组 %s 成员 %s 降级命令失败: code=%d err=%v %s", g.cfg.Name, name, code, err, out)
+		}
+		return
+	}
+	if pid := g.memberPID(name); pid != 0 {
+		if err := m.provider.KillProcess(pid); err != nil {
+			log.Printf("[WARN] 冗余组 %s 结束成员 %s (PID=%d) 失败: %v", g.cfg.Name, name, pid, err)
+		}
+	}
+}
+
+// finishSwitchover 记录切换结果并产生事件
+func (m *MultiMonitor) finishSwitchover(g *redundancyGroup, rec *types.SwitchoverRecord, success bool) {
+	rec.Success = success
+	end := rec.CompletedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	rec.DurationMs = end.Sub(rec.DetectedAt).Milliseconds()
+
+	g.mu.Lock()
+	g.pending = nil
+	g.last = rec
+	if success {
+		g.active = rec.To
+		g.switchovers++
+		g.downSince = time.Time{}
+		g.downReported = false
+	} else {
+		g.failedAt[rec.To] = time.Now()
+	}
+	g.mu.Unlock()
+
+	details := map[string]interface{}{
+		"group":       g.cfg.Name,
+		"from":        rec.From,
+		"to":          rec.To,
+		"reason":      rec.Reason,
+		"detect_ms":   rec.PromotedAt.Sub(rec.DetectedAt).Milliseconds(),
+		"duration_ms": rec.DurationMs,
+	}
+	evt := types.Event{Timestamp: end, PID: g.memberPID(rec.To), Name: g.cfg.Name, Details: details}
+	if success {
+		evt.Type = "switchover"
+		if !rec.CompletedAt.IsZero() {
+			details["promote_ms"] = rec.CompletedAt.Sub(rec.PromotedAt).Milliseconds()
+		}
+		evt.Message = fmt.Sprintf("冗余组 %s 主备切换 %s → %s（原因:%s，耗时 %d ms）", g.cfg.Name, displayMember(rec.From), rec.To, rec.Reason, rec.DurationMs)
+	} else {
+		evt.Type = "switchover_failed"
+		details["error"] = rec.Error
+		evt.Message = fmt.Sprintf("冗余组 %s 切换到 %s 失败（原因:%s）: %s", g.cfg.Name, rec.To, rec.Reason, rec.Error)
+	}
+	m.addEvent(evt)
+}
+
+func displayMember(name string) string {
+	if name == "" {
+		return "(无)"
+	}
+	return name
+}
+
+// candidate 选择升主成员：按优先级排除原主用（原主用排在最后），跳过近期升主失败的成员（调用方持有 g.mu）
+func (g *redundancyGroup) candidate(from string, now time.Time) string {
+	order := make([]string, 0, len(g.cfg.Members))
+	for _, mem := range g.cfg.Members {
+		if mem.Name != from {
+			order = append(order, mem.Name)
+		}
+	}
+	if from != "" {
+		order = append(order, from)
+	}
+	backoff := 2 * time.Duration(g.cfg.PromoteTimeout) * time.Second
+	for _, name := range order {
+		if failed, ok := g.failedAt[name]; ok && now.Sub(failed) < backoff {
+			continue
+		}
+		return name
+	}
+	return ""
+}
+
+// nextMember 优先级顺序中 from 之后的成员
+func (g *redundancyGroup) nextMember(from string) string {
+	for i, mem := range g.cfg.Members {
+		if mem.Name == from {
+			return g.cfg.Members[(i+1)%len(g.cfg.Members)].Name
+		}
+	}
+	return g.cfg.Members[0].Name
+}
+
+func (g *redundancyGroup) member(name string) *types.RedundancyMember {
+	for i := range g.cfg.Members {
+		if g.cfg.Members[i].Name == name {
+			return &g.cfg.Members[i]
+		}
+	}
+	return nil
+}
+
+func (g *redundancyGroup) memberPID(name string) int32 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, st := range g.members {
+		if st.Name == name {
+			return st.PID
+		}
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// sensorMonitor 硬件传感器采样状态
+type sensorMonitor struct {
+	cfg types.SensorConfig
+
+	mu       sync.Mutex
+	latest   []types.SensorReading
+	alarming map[string]bool // 处于告警中的传感器
+}
+
+// sensorInput hwmon 输入类型：属性前缀 -> 类型、单位、换算系数
+var sensorInputs = []struct {
+	prefix string
+	kind   string
+	unit   string
+	scale  float64
+}{
+	{"temp", "temperature", "°C", 1000}, // 毫摄氏度
+	{"fan", "fan", "RPM", 1},
+	{"in", "voltage", "V", 1000}, // 毫伏
+}
+
+func newSensorMonitor(cfg types.SensorConfig) *sensorMonitor {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.SysfsRoot == "" {
+		cfg.SysfsRoot = "/sys"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10
+	}
+	if cfg.Hysteresis <= 0 {
+		cfg.Hysteresis = 5
+	}
+	return &sensorMonitor{cfg: cfg, alarming: make(map[string]bool)}
+}
+
+func (m *MultiMonitor) watchSensors(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(m.sensors.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	m.sampleSensors()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.sampleSensors()
+		}
+	}
+}
+
+// sampleSensors 读取传感器并按阈值产生 sensor_alarm / sensor_recovered 事件
+func (m *MultiMonitor) sampleSensors() {
+	s := m.sensors
+	readings := readSensors(s.cfg.SysfsRoot)
+	now := time.Now()
+	var events []types.Event
+
+	s.mu.Lock()
+	seen := make(map[string]bool, len(readings))
+	for i := range readings {
+		r := &readings[i]
+		seen[r.Name] = true
+		s.applyLimits(r)
+		r.Alarm = sensorAlarm(*r, s.alarming[r.Name], s.cfg.Hysteresis/100)
+		details := map[string]interface{}{"sensor": r.Name, "kind": r.Kind, "value": r.Value, "unit": r.Unit}
+		if r.Max > 0 {
+			details["max"] = r.Max
+		}
+		if r.Min > 0 {
+			details["min"] = r.Min
+		}
+		switch {
+		case r.Alarm && !s.alarming[r.Name]:
+			s.alarming[r.Name] = true
+			limit := fmt.Sprintf("上限 %g%s", r.Max, r.Unit)
+			if r.Min > 0 && r.Value < r.Min {
+				limit = fmt.Sprintf("下限 %g%s", r.Min, r.Unit)
+			}
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "sensor_alarm",
+				Name:      "sensor:" + r.Name,
+				Message:   fmt.Sprintf("传感器 %s 读数 %.1f%s 超出%s", r.Name, r.Value, r.Unit, limit),
+				Details:   details,
+			})
+		case !r.Alarm && s.alarming[r.Name]:
+			delete(s.alarming, r.Name)
+			events = append(events, types.Event{
+				Timestamp: now,
+				Type:      "sensor_recovered",
+				Name:      "sensor:" + r.Name,
+				Message:   fmt.Sprintf("传感器 %s 读数 %.1f%s 已恢复正常", r.Name, r.Value, r.Unit),
+				Details:   details,
+			})
+		}
+	}
+	// 消失的传感器（如拔出的设备）清除告警状态
+	for name := range s.alarming {
+		if !seen[name] {
+			delete(s.alarming, name)
+		}
+	}
+	s.latest = readings
+	s.mu.Unlock()
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+}
+
+// sensorAlarm 判断读数是否告警：超出上限或低于下限时进入告警，已告警时需回到阈值的 margin 比例以内才恢复，
+// 避免读数在阈值附近波动时反复产生告警/恢复事件
+func sensorAlarm(r types.SensorReading, alarming bool, margin float64) bool {
+	if !alarming {
+		margin = 0
+	}
+	return (r.Max > 0 && r.Value > r.Max*(1-margin)) || (r.Min > 0 && r.Value < r.Min*(1+margin))
+}
+
+// applyLimits 确定生效的阈值：配置的阈值 > TempMax（温度）> 芯片自带限值（调用方持有 s.mu）
+func (s *sensorMonitor) applyLimits(r *types.SensorReading) {
+	for _, th := range s.cfg.Thresholds {
+		if ok, _ := filepath.Match(th.Sensor, r.Name); ok {
+			r.Max, r.Min = th.Max, th.Min
+			return
+		}
+	}
+	if r.Kind == "temperature" && s.cfg.TempMax > 0 {
+		r.Max = s.cfg.TempMax
+	}
+}
+
+// readSensors 读取 root/class/hwmon 和 root/class/thermal 下的传感器（按名称排序），
+// Max/Min 为芯片自带的限值
+func readSensors(root string) []types.SensorReading {
+	var result []types.SensorReading
+
+	chips, _ := filepath.Glob(filepath.Join(root, "class", "hwmon", "hwmon*"))
+	sort.Strings(chips)
+	chipNames := uniqueNames(chips, func(dir string) string {
+		if name := readSysfsString(filepath.Join(dir, "name")); name != "" {
+			return name
+		}
+		return filepath.Base(dir)
+	})
+	for i, dir := range chips {
+		// 部分驱动的属性在 device 子目录下
+		inputs, _ := filepath.Glob(filepath.Join(dir, "*_input"))
+		more, _ := filepath.Glob(filepath.Join(dir, "device", "*_input"))
+		for _, path := range append(inputs, more...) {
+			if r, ok := readHwmonInput(chipNames[i], path); ok {
+				result = append(result, r)
+			}
+		}
+	}
+
+	zones, _ := filepath.Glob(filepath.Join(root, "class", "thermal", "thermal_zone*"))
+	sort.Strings(zones)
+	zoneNames := uniqueNames(zones, func(dir string) string {
+		if typ := readSysfsString(filepath.Join(dir, "type")); typ != "" {
+			return typ
+		}
+		return filepath.Base(dir)
+	})
+	for i, dir := range zones {
+		v, ok := readSysfsFloat(filepath.Join(dir, "temp"))
+		if !ok {
+			continue
+		}
+		r := types.SensorReading{Name: "thermal/" + zoneNames[i], Kind: "temperature", Value: v / 1000, Unit: "°C"}
+		// 使用 critical 触发点作为上限，没有时使用 hot
+		trips, _ := filepath.Glob(filepath.Join(dir, "trip_point_*_type"))
+		for _, tp := range trips {
+			typ := readSysfsString(tp)
+			if typ != "critical" && typ != "hot" {
+				continue
+			}
+			if t, ok := readSysfsFloat(strings.TrimSuffix(tp, "_type") + "_temp"); ok && t > 0 && (r.Max == 0 || typ == "critical") {
+				r.Max = t / 1000
+			}
+		}
+		result = append(result, r)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// uniqueNames 名称重复时（如多块同型号 NVMe）附加目录名区分
+func uniqueNames(dirs []string, name func(dir string) string) []string {
+	names := make([]string, len(dirs))
+	count := make(map[string]int)
+	for i, dir := range dirs {
+		names[i] = name(dir)
+		count[names[i]]++
+	}
+	for i, dir := range dirs {
+		if count[names[i]] > 1 {
+			names[i] += "@" + filepath.Base(dir)
+		}
+	}
+	return names
+}
+
+// readHwmonInput 读取一个 hwmon 输入（tempN_input / fanN_input / inN_input）及其标签和限值
+func readHwmonInput(chip, path string) (types.SensorReading, bool) {
+	attr := strings.TrimSuffix(filepath.Base(path), "_input")
+	for _, in := range sensorInputs {
+		if !strings.HasPrefix(attr, in.prefix) {
+			continue
+		}
+		if _, err := strconv.Atoi(attr[len(in.prefix):]); err != nil {
+			continue
+		}
+		v, ok := readSysfsFloat(path)
+		if !ok {
+			return types.SensorReading{}, false
+		}
+		base := strings.TrimSuffix(path, "_input")
+		label := readSysfsString(base + "_label")
+		if label == "" {
+			label = attr
+		}
+		r := types.SensorReading{Name: chip + "/" + label, Kind: in.kind, Value: v / in.scale, Unit: in.unit}
+		switch in.kind {
+		case "temperature":
+			if crit, ok := readSysfsFloat(base + "_crit"); ok && crit > 0 {
+				r.Max = crit / in.scale
+			} else if max, ok := readSysfsFloat(base + "_max"); ok && max > 0 {
+				r.Max = max / in.scale
+			}
+		default:
+			if min, ok := readSysfsFloat(base + "_min"); ok && min > 0 {
+				r.Min = min / in.scale
+			}
+			if max, ok := readSysfsFloat(base + "_max"); ok && max > 0 {
+				r.Max = max / in.scale
+			}
+		}
+		return r, true
+	}
+	return types.SensorReading{}, false
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readSysfsFloat(path string) (float64, bool) {
+	v, err := strconv.ParseFloat(readSysfsString(path), 64)
+	return v, err == nil
+}
+
+// GetSensors 获取最近一次传感器读数，未启用时返回错误
+func (m *MultiMonitor) GetSensors() ([]types.SensorReading, error) {
+	if m.sensors == nil {
+		return nil, fmt.Errorf("sensor monitoring not enabled")
+	}
+	m.sensors.mu.Lock()
+	defer m.sensors.mu.Unlock()
+	return append([]types.SensorReading{}, m.sensors.latest...), nil
+}
@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"monitor-agent/types"
+)
+
+// writeSysfs 按 路径 -> 内容 在 root 下生成 sysfs 样例文件
+func writeSysfs(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadSensors(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, map[string]string{
+		// 两块同名芯片，名称附加目录名区分
+		"class/hwmon/hwmon0/name":        "coretemp",
+		"class/hwmon/hwmon0/temp1_input": "45000",
+		"class/hwmon/hwmon0/temp1_label": "Package id 0",
+		"class/hwmon/hwmon0/temp1_max":   "80000",
+		"class/hwmon/hwmon0/temp1_crit":  "100000",
+		"class/hwmon/hwmon0/temp2_input": "50000",
+		"class/hwmon/hwmon0/temp2_max":   "85000",
+		"class/hwmon/hwmon1/name":        "coretemp",
+		"class/hwmon/hwmon1/temp1_input": "40000",
+		// 属性在 device 子目录下
+		"class/hwmon/hwmon2/name":                    "nct6775",
+		"class/hwmon/hwmon2/device/fan1_input":       "1200",
+		"class/hwmon/hwmon2/device/fan1_min":         "300",
+		"class/hwmon/hwmon2/device/in0_input":        "1100",
+		"class/hwmon/hwmon2/device/in0_min":          "1000",
+		"class/hwmon/hwmon2/device/in0_max":          "1200",
+		"class/hwmon/hwmon2/device/intrusion0_input": "0",
+		// 没有 name 文件时使用目录名
+		"class/hwmon/hwmon3/temp1_input": "30000",
+		// critical 优先于 hot，其他类型的触发点忽略
+		"class/thermal/thermal_zone0/type":              "x86_pkg_temp",
+		"class/thermal/thermal_zone0/temp":              "55000",
+		"class/thermal/thermal_zone0/trip_point_0_type": "passive",
+		"class/thermal/thermal_zone0/trip_point_0_temp": "70000",
+		"class/thermal/thermal_zone1/type":              "acpitz",
+		"class/thermal/thermal_zone1/temp":              "30000",
+		"class/thermal/thermal_zone1/trip_point_0_type": "critical",
+		"class/thermal/thermal_zone1/trip_point_0_temp": "105000",
+		"class/thermal/thermal_zone1/trip_point_1_type": "hot",
+		"class/thermal/thermal_zone1/trip_point_1_temp": "90000",
+		"class/thermal/thermal_zone2/type":              "acpitz",
+		"class/thermal/thermal_zone2/temp":              "35000",
+		"class/thermal/thermal_zone2/trip_point_0_type": "hot",
+		"class/thermal/thermal_zone2/trip_point_0_temp": "80000",
+	})
+
+	want := []types.SensorReading{
+		{Name: "coretemp@hwmon0/Package id 0", Kind: "temperature", Value: 45, Unit: "°C", Max: 100},
+		{Name: "coretemp@hwmon0/temp2", Kind: "temperature", Value: 50, Unit: "°C", Max: 85},
+		{Name: "coretemp@hwmon1/temp1", Kind: "temperature", Value: 40, Unit: "°C"},
+		{Name: "hwmon3/temp1", Kind: "temperature", Value: 30, Unit: "°C"},
+		{Name: "nct6775/fan1", Kind: "fan", Value: 1200, Unit: "RPM", Min: 300},
+		{Name: "nct6775/in0", Kind: "voltage", Value: 1.1, Unit: "V", Min: 1, Max: 1.2},
+		{Name: "thermal/acpitz@thermal_zone1", Kind: "temperature", Value: 30, Unit: "°C", Max: 105},
+		{Name: "thermal/acpitz@thermal_zone2", Kind: "temperature", Value: 35, Unit: "°C", Max: 80},
+		{Name: "thermal/x86_pkg_temp", Kind: "temperature", Value: 55, Unit: "°C"},
+	}
+	got := readSensors(root)
+	if len(got) != len(want) {
+		t.Fatalf("readSensors returned %d readings, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("reading %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSensorAlarmHysteresis(t *testing.T) {
+	tests := []struct {
+		value    float64
+		alarming bool
+		want     bool
+	}{
+		{89, false, false},
+		{91, false, true},
+		{89, true, true}, // 在余量内，保持告警
+		{86, true, true},
+		{85, true, false},
+	}
+	for _, tt := range tests {
+		r := types.SensorReading{Value: tt.value, Max: 90}
+		if got := sensorAlarm(r, tt.alarming, 0.05); got != tt.want {
+			t.Errorf("sensorAlarm(%g, alarming=%v) = %v, want %v", tt.value, tt.alarming, got, tt.want)
+		}
+	}
+
+	// 下限：低于 300 告警，回到 315 以上恢复
+	fan := types.SensorReading{Value: 310, Min: 300}
+	if !sensorAlarm(fan, true, 0.05) {
+		t.Error("fan at 310 RPM should stay in alarm")
+	}
+	fan.Value = 320
+	if sensorAlarm(fan, true, 0.05) {
+		t.Error("fan at 320 RPM should recover")
+	}
+}
@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// socketState 套接字检查状态
+type socketState struct {
+	fails    map[string]int  // 告警键 -> 连续不满足次数
+	reported map[string]bool // 已上报的告警键，恢复前不重复上报
+}
+
+// socketAlarm 一项套接字检查结果
+type socketAlarm struct {
+	key       string // listen:tcp/502、peer:10.0.0.5:2404、close_wait
+	bad       bool
+	event     string // 告警事件类型
+	recovered string // 恢复事件类型
+	message   string
+	recovery  string
+}
+
+// checkSockets 采集套接字统计写入 metric，检查期望监听、期望连接和 CLOSE_WAIT 泄漏，
+// 连续 fail_count 次不满足时上报，恢复时上报恢复事件
+func (m *MultiMonitor) checkSockets(state *targetState, target types.MonitorTarget, metric *types.ProcessMetrics) {
+	cfg := target.Sockets
+	if cfg == nil {
+		return
+	}
+	stats, err := collectSockets(target.PID)
+	if err != nil {
+		return
+	}
+	metric.Sockets = stats
+
+	var alarms []socketAlarm
+	for _, want := range cfg.ExpectListen {
+		proto, port := parseListenSpec(want)
+		found := false
+		for _, addr := range stats.Listening {
+			if strings.HasPrefix(addr, proto+"/") && strings.HasSuffix(addr, ":"+port) {
+				found = true
+				break
+			}
+		}
+		alarms = append(alarms, socketAlarm{
+			key: "listen:" + proto + "/" + port, bad: !found, event: "listen_missing", recovered: "listen_restored",
+			message:  fmt.Sprintf("未监听期望的端口 %s/%s", proto, port),
+			recovery: fmt.Sprintf("已恢复监听端口 %s/%s", proto, port),
+		})
+	}
+	for _, peer := range cfg.ExpectPeers {
+		alarms = append(alarms, socketAlarm{
+			key: "peer:" + peer, bad: peerConnections(stats.Peers, peer) == 0, event: "peer_lost", recovered: "peer_restored",
+			message:  fmt.Sprintf("与 %s 的连接已断开", peer),
+			recovery: fmt.Sprintf("与 %s 的连接已恢复", peer),
+		})
+	}
+	if cfg.CloseWaitMax > 0 {
+		alarms = append(alarms, socketAlarm{
+			key: "close_wait", bad: stats.CloseWait > cfg.CloseWaitMax, event: "close_wait_leak", recovered: "close_wait_recovered",
+			message:  fmt.Sprintf("CLOSE_WAIT 连接 %d 个，超过 %d（疑似未关闭连接）", stats.CloseWait, cfg.CloseWaitMax),
+			recovery: fmt.Sprintf("CLOSE_WAIT 连接降至 %d 个", stats.CloseWait),
+		})
+	}
+
+	failLimit := cfg.FailCount
+	if failLimit <= 0 {
+		failLimit = 3
+	}
+	now := time.Now()
+	var events []types.Event
+	raised := ""
+
+	m.mu.Lock()
+	ss := state.sockets
+	if ss == nil {
+		ss = &socketState{fails: make(map[string]int), reported: make(map[string]bool)}
+		state.sockets = ss
+	}
+	for _, a := range alarms {
+		details := map[string]interface{}{"check": a.key, "listening": stats.Listening, "established": stats.Established, "close_wait": stats.CloseWait}
+		if !a.bad {
+			ss.fails[a.key] = 0
+			if ss.reported[a.key] {
+				delete(ss.reported, a.key)
+				events = append(events, types.Event{Timestamp: now, Type: a.recovered, PID: target.PID, Name: target.Name, Message: a.recovery, Details: details})
+			}
+			continue
+		}
+		ss.fails[a.key]++
+		if ss.fails[a.key] < failLimit || ss.reported[a.key] {
+			continue
+		}
+		ss.reported[a.key] = true
+		events = append(events, types.Event{Timestamp: now, Type: a.event, PID: target.PID, Name: target.Name, Message: a.message, Details: details})
+		if raised == "" {
+			raised = a.event
+		}
+	}
+	m.mu.Unlock()
+
+	for _, evt := range events {
+		m.addEvent(evt)
+	}
+	if raised != "" && cfg.Restart && (target.RestartCmd != "" || target.Supervise != nil) {
+		m.tryRestart(target.PID, raised)
+	}
+}
+
+// parseListenSpec 解析期望监听："502" 为 TCP，或 "tcp/502"、"udp/161"
+func parseListenSpec(spec string) (proto, port string) {
+	if i := strings.Index(spec, "/"); i >= 0 {
+		return strings.ToLower(spec[:i]), spec[i+1:]
+	}
+	return "tcp", spec
+}
+
+// peerConnections 到期望远端的已建立连接数，peer 不含端口时匹配该地址的任意端口
+func peerConnections(peers map[string]int, peer string) int {
+	if n, ok := peers[peer]; ok {
+		return n
+	}
+	if _, _, err := net.SplitHostPort(peer); err == nil {
+		return 0
+	}
+	total := 0
+	for addr, n := range peers {
+		if host, _, err := net.SplitHostPort(addr); err == nil && host == strings.Trim(peer, "[]") {
+			total += n
+		}
+	}
+	return total
+}
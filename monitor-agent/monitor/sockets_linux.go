@@ -0,0 +1,154 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"monitor-agent/types"
+)
+
+// /proc/net/tcp 状态码
+const (
+	tcpEstablished = "01"
+	tcpTimeWait    = "06"
+	tcpClose       = "07" // UDP 未连接的绑定套接字
+	tcpCloseWait   = "08"
+	tcpListen      = "0A"
+)
+
+// procSocket /proc/net/{tcp,udp}[6] 中的一行
+type procSocket struct {
+	proto  string
+	local  string // ip:port
+	remote string
+	port   int
+	state  string
+	txQ    uint64
+	rxQ    uint64
+	inode  string
+}
+
+// collectSockets 从进程 fd 表取得套接字 inode，再到 /proc/<pid>/net（进程所在网络命名空间的 /proc/net）匹配连接
+func collectSockets(pid int32) (*types.SocketStats, error) {
+	fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil, err
+	}
+	inodes := make(map[string]bool)
+	for _, e := range entries {
+		link, err := os.Readlink(filepath.Join(fdDir, e.Name()))
+		if err == nil && strings.HasPrefix(link, "socket:[") {
+			inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = true
+		}
+	}
+
+	var all []procSocket
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		socks, err := readProcNet(fmt.Sprintf("/proc/%d/net/%s", pid, proto), strings.TrimSuffix(proto, "6"))
+		if err != nil {
+			continue
+		}
+		all = append(all, socks...)
+	}
+
+	stats := &types.SocketStats{Listening: []string{}, Peers: make(map[string]int)}
+	listenPorts := make(map[int]bool)
+	for _, s := range all {
+		if !inodes[s.inode] {
+			continue
+		}
+		switch {
+		case s.proto == "tcp" && s.state == tcpListen, s.proto == "udp" && s.state == tcpClose:
+			stats.Listening = append(stats.Listening, s.proto+"/"+s.local)
+			if s.proto == "tcp" {
+				listenPorts[s.port] = true
+			}
+		case s.proto == "tcp" && s.state == tcpEstablished:
+			stats.Established++
+			stats.Peers[s.remote]++
+			stats.RecvQueue += s.rxQ
+			stats.SendQueue += s.txQ
+			if s.rxQ > stats.MaxRecvQueue {
+				stats.MaxRecvQueue = s.rxQ
+			}
+			if s.txQ > stats.MaxSendQueue {
+				stats.MaxSendQueue = s.txQ
+			}
+		case s.proto == "tcp" && s.state == tcpCloseWait:
+			stats.CloseWait++
+		}
+	}
+	// TIME_WAIT 连接已不属于任何进程，按本地端口归属到监听该端口的目标
+	for _, s := range all {
+		if s.proto == "tcp" && s.state == tcpTimeWait && listenPorts[s.port] {
+			stats.TimeWait++
+		}
+	}
+	return stats, nil
+}
+
+func readProcNet(path, proto string) ([]procSocket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []procSocket
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // 表头
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		local, port, err := parseProcAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		remote, _, err := parseProcAddr(fields[2])
+		if err != nil {
+			continue
+		}
+		s := procSocket{proto: proto, local: local, remote: remote, port: port, state: fields[3], inode: fields[9]}
+		if q := strings.SplitN(fields[4], ":", 2); len(q) == 2 {
+			s.txQ, _ = strconv.ParseUint(q[0], 16, 64)
+			s.rxQ, _ = strconv.ParseUint(q[1], 16, 64)
+		}
+		result = append(result, s)
+	}
+	return result, scanner.Err()
+}
+
+// parseProcAddr 解析 "0100007F:1F90"：地址按 32 位字小端存放
+func parseProcAddr(s string) (string, int, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid address %q", s)
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return "", 0, fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", 0, err
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port))), int(port), nil
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package monitor
+
+import (
+	"net"
+	"strconv"
+	"syscall"
+
+	gnet "github.com/shirou/gopsutil/v3/net"
+	"monitor-agent/types"
+)
+
+// collectSockets Windows 下通过 GetExtendedTcpTable/UdpTable 获取进程连接（不提供收发队列）
+func collectSockets(pid int32) (*types.SocketStats, error) {
+	conns, err := gnet.ConnectionsPid("inet", pid)
+	if err != nil {
+		return nil, err
+	}
+	stats := &types.SocketStats{Listening: []string{}, Peers: make(map[string]int)}
+	listenPorts := make(map[uint32]bool)
+	for _, c := range conns {
+		local := net.JoinHostPort(c.Laddr.IP, strconv.Itoa(int(c.Laddr.Port)))
+		if c.Type == syscall.SOCK_DGRAM {
+			stats.Listening = append(stats.Listening, "udp/"+local)
+			continue
+		}
+		switch c.Status {
+		case "LISTEN":
+			stats.Listening = append(stats.Listening, "tcp/"+local)
+			listenPorts[c.Laddr.Port] = true
+		case "ESTABLISHED":
+			stats.Established++
+			stats.Peers[net.JoinHostPort(c.Raddr.IP, strconv.Itoa(int(c.Raddr.Port)))]++
+		case "CLOSE_WAIT":
+			stats.CloseWait++
+		}
+	}
+	// TIME_WAIT 连接已不属于任何进程，按本地端口归属到监听该端口的目标
+	if len(listenPorts) > 0 {
+		if all, err := gnet.Connections("tcp"); err == nil {
+			for _, c := range all {
+				if c.Status == "TIME_WAIT" && listenPorts[c.Laddr.Port] {
+					stats.TimeWait++
+				}
+			}
+		}
+	}
+	return stats, nil
+}
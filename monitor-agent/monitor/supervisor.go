@@ -0,0 +1,450 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"monitor-agent/buffer"
+	"monitor-agent/logger"
+	"monitor-agent/types"
+)
+
+// supervisor 托管进程：由代理直接启动并持有，可获得准确的退出码/信号
+type supervisor struct {
+	spec          types.SuperviseSpec
+	stdout        *logger.RotatingFile
+	stderr        *logger.RotatingFile
+	cmd           *exec.Cmd
+	exited        chan struct{} // 当前进程退出时关闭
+	stopping      bool          // 主动停止（移除目标/关闭服务），不再重启
+	restartReason string        // 主动请求重启（阈值触发等）的原因
+	resume        chan struct{} // 人工停止后等待启动，启动或停止托管时关闭
+	stop          chan struct{} // 停止托管时关闭，中断重启前的等待
+	done          chan struct{}
+}
+
+// exitInfo 进程退出状态
+type exitInfo struct {
+	Code       int
+	Signal     string
+	CoreDumped bool
+}
+
+func (e exitInfo) String() string {
+	if e.Signal != "" {
+		s := fmt.Sprintf("被信号 %s 终止", e.Signal)
+		if e.CoreDumped {
+			s += " (core dumped)"
+		}
+		return s
+	}
+	return fmt.Sprintf("退出码 %d", e.Code)
+}
+
+func (e exitInfo) failed() bool {
+	return e.Signal != "" || e.Code != 0
+}
+
+// addSupervisedTarget 启动托管进程并加入监控
+func (m *MultiMonitor) addSupervisedTarget(target types.MonitorTarget) error {
+	spec := *target.Supervise
+	if spec.Command == "" {
+		return fmt.Errorf("supervise.command required")
+	}
+	switch spec.RestartPolicy {
+	case "", "always", "on-failure", "never":
+	default:
+		return fmt.Errorf("invalid restart_policy %q", spec.RestartPolicy)
+	}
+	if target.Name == "" {
+		target.Name = filepath.Base(spec.Command)
+	}
+
+	logBase := filepath.Join(m.config.LogDir, "targets", safeFileName(target.Name))
+	stdout, err := logger.NewRotatingFile(logBase+".stdout.log", spec.LogMaxSize, spec.LogMaxFiles)
+	if err != nil {
+		return fmt.Errorf("open stdout log: %w", err)
+	}
+	stderr, err := logger.NewRotatingFile(logBase+".stderr.log", spec.LogMaxSize, spec.LogMaxFiles)
+	if err != nil {
+		stdout.Close()
+		return fmt.Errorf("open stderr log: %w", err)
+	}
+	sup := &supervisor{spec: spec, stdout: stdout, stderr: stderr, stop: make(chan struct{}), done: make(chan struct{})}
+	var integ *integrityState
+	if target.Integrity != nil {
+		integ = m.recordIntegrity(target, "")
+	}
+
+	cmd, err := sup.start()
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		return err
+	}
+	sup.cmd, sup.exited = cmd, make(chan struct{})
+	target.PID = int32(cmd.Process.Pid)
+
+	state := &targetState{target: target, sup: sup, integrity: integ}
+	buf := buffer.NewRingBuffer[types.ProcessMetrics](m.config.MetricsBufferLen)
+	m.mu.Lock()
+	m.targets[target.PID] = state
+	m.metricsBuffers[target.PID] = buf
+	m.mu.Unlock()
+
+	go m.superviseLoop(state)
+
+	log.Printf("[INFO] Started supervised target: PID=%d Name=%s Command=%s", target.PID, target.Name, spec.Command)
+	return nil
+}
+
+// start 直接 exec 启动进程（不经过 shell），stdout/stderr 写入滚动日志
+func (s *supervisor) start() (*exec.Cmd, error) {
+	command, args, err := wrapRlimits(s.spec.Command, s.spec.Args, s.spec.Rlimits)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(command, args...)
+	cmd.Dir = s.spec.Dir
+	cmd.Env = append(os.Environ(), s.spec.Env...)
+	if err := applySysProcAttr(cmd, s.spec); err != nil {
+		return nil, err
+	}
+
+	// 自行创建管道并拷贝，Wait 不会因子进程的后代持有管道而阻塞
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		outR.Close()
+		outW.Close()
+		return nil, err
+	}
+	cmd.Stdout = outW
+	cmd.Stderr = errW
+
+	err = cmd.Start()
+	outW.Close()
+	errW.Close()
+	if err != nil {
+		outR.Close()
+		errR.Close()
+		return nil, fmt.Errorf("start %s: %w", s.spec.Command, err)
+	}
+	go copyAndClose(s.stdout, outR)
+	go copyAndClose(s.stderr, errR)
+	return cmd, nil
+}
+
+func copyAndClose(dst io.Writer, src *os.File) {
+	io.Copy(dst, src)
+	src.Close()
+}
+
+// superviseLoop 等待进程退出，记录准确的退出状态，并按策略重启
+func (m *MultiMonitor) superviseLoop(state *targetState) {
+	sup := state.sup
+	defer close(sup.done)
+	defer sup.stdout.Close()
+	defer sup.stderr.Close()
+
+	for {
+		m.mu.RLock()
+		cmd, exited := sup.cmd, sup.exited
+		m.mu.RUnlock()
+
+		cmd.Wait()
+		close(exited)
+		info := exitStatus(cmd.ProcessState)
+
+		m.mu.Lock()
+		target := state.target
+		pid := target.PID
+		state.exitReported = true
+		stopping := sup.stopping
+		held := state.held
+		reason := sup.restartReason
+		sup.restartReason = ""
+		restarts := state.restartCount
+		m.mu.Unlock()
+
+		details := map[string]interface{}{"exit_code": info.Code, "supervised": true}
+		if stopping || held {
+			details["planned"] = true // 主动停止，不计入不可用时间
+		}
+		if info.Signal != "" {
+			details["signal"] = info.Signal
+			details["core_dumped"] = info.CoreDumped
+		}
+		m.addEvent(types.Event{
+			Timestamp: time.Now(),
+			Type:      "exit",
+			PID:       pid,
+			Name:      target.Name,
+			Message:   "进程已退出: " + info.String(),
+			Details:   details,
+		})
+
+		if stopping {
+			return
+		}
+		if held {
+			if !m.waitResume(state) {
+				return
+			}
+			reason = "start"
+		}
+		if reason == "" {
+			if !shouldRestart(sup.spec.RestartPolicy, target.AutoRestart, info) {
+				return
+			}
+			reason = "exit"
+		}
+		if sup.spec.MaxRestarts > 0 && restarts >= sup.spec.MaxRestarts {
+			m.addEvent(types.Event{
+				Timestamp: time.Now(),
+				Type:      "restart",
+				PID:       pid,
+				Name:      target.Name,
+				Message:   fmt.Sprintf("已达到最大重启次数 %d，停止自动重启", sup.spec.MaxRestarts),
+				Details:   map[string]interface{}{"reason": reason, "success": false},
+			})
+			return
+		}
+
+		if !m.restartSupervised(state, reason) {
+			return
+		}
+	}
+}
+
+// restartSupervised 延迟后重新启动托管进程，失败时退避重试；返回 false 表示已停止托管
+func (m *MultiMonitor) restartSupervised(state *targetState, reason string) bool {
+	sup := state.sup
+	delay := time.Duration(sup.spec.RestartDelay) * time.Second
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	for {
+		select {
+		case <-sup.stop:
+			return false
+		case <-time.After(delay):
+		}
+		if !m.waitUpstreamReady(state) {
+			return false
+		}
+
+		m.mu.Lock()
+		if sup.stopping {
+			m.mu.Unlock()
+			return false
+		}
+		oldPID := state.target.PID
+		name := state.target.Name
+		m.mu.Unlock()
+
+		// 完整性不一致且阻止重启：退避后复核，文件恢复或确认变更后再启动
+		if m.integrityBlocksRestart(state, reason) {
+			if delay < time.Minute {
+				delay *= 2
+			}
+			continue
+		}
+
+		cmd, err := sup.start()
+		if err != nil {
+			m.addEvent(types.Event{
+				Timestamp: time.Now(),
+				Type:      "restart",
+				PID:       oldPID,
+				Name:      name,
+				Message:   fmt.Sprintf("重启失败 (原因:%s): %v", reason, err),
+				Details:   map[string]interface{}{"reason": reason, "success": false},
+			})
+			if delay < time.Minute {
+				delay *= 2
+			}
+			continue
+		}
+
+		newPID := int32(cmd.Process.Pid)
+		m.mu.Lock()
+		sup.cmd, sup.exited = cmd, make(chan struct{})
+		m.rekeyTargetLocked(oldPID, newPID)
+		state.lastRestart = time.Now()
+		state.restartCount++
+		count := state.restartCount
+		m.mu.Unlock()
+
+		m.addEvent(types.Event{
+			Timestamp: time.Now(),
+			Type:      "restart",
+			PID:       newPID,
+			Name:      name,
+			Message:   fmt.Sprintf("已重启托管进程 (原因:%s, 第%d次重启) | 新 PID %d", reason, count, newPID),
+			Details:   map[string]interface{}{"old_pid": oldPID, "new_pid": newPID, "reason": reason, "success": true},
+		})
+		return true
+	}
+}
+
+// rekeyTargetLocked 进程重启后 PID 变化，迁移监控状态和指标缓冲（调用方持有锁）
+func (m *MultiMonitor) rekeyTargetLocked(oldPID, newPID int32) {
+	state, ok := m.targets[oldPID]
+	if !ok {
+		return
+	}
+	delete(m.targets, oldPID)
+	state.target.PID = newPID
+	state.exitReported = false
+	// 新进程重新开始趋势和挂死分析
+	state.hang = nil
+	state.leak = nil
+	state.sockets = nil
+	// 新进程重新开始计算心跳超时
+	if state.heartbeat != nil {
+		state.heartbeat.since = time.Now()
+	}
+	if state.dep != nil {
+		state.dep.ready, state.dep.aliveSince, state.dep.bounced = false, time.Time{}, true
+	}
+	m.targets[newPID] = state
+	if buf, ok := m.metricsBuffers[oldPID]; ok {
+		delete(m.metricsBuffers, oldPID)
+		m.metricsBuffers[newPID] = buf
+	}
+}
+
+// shouldRestart 按重启策略判断；策略为空时沿用 auto_restart
+func shouldRestart(policy string, autoRestart bool, info exitInfo) bool {
+	if policy == "" {
+		if autoRestart {
+			policy = "always"
+		} else {
+			policy = "never"
+		}
+	}
+	switch policy {
+	case "always":
+		return true
+	case "on-failure":
+		return info.failed()
+	}
+	return false
+}
+
+// requestSupervisedRestart 阈值等触发的重启：终止进程，由 superviseLoop 负责拉起
+func (m *MultiMonitor) requestSupervisedRestart(state *targetState, reason string) {
+	m.mu.Lock()
+	sup := state.sup
+	if sup.stopping || sup.restartReason != "" {
+		m.mu.Unlock()
+		return
+	}
+	sup.restartReason = reason
+	cmd, exited := sup.cmd, sup.exited
+	m.mu.Unlock()
+	go terminateProcess(cmd, exited, stopTimeout(sup.spec))
+}
+
+// holdSupervised 人工停止托管进程：终止后不按策略重启，等待 resumeSupervised
+func (m *MultiMonitor) holdSupervised(state *targetState) {
+	m.mu.Lock()
+	sup := state.sup
+	if sup.stopping || state.held {
+		m.mu.Unlock()
+		return
+	}
+	state.held = true
+	sup.resume = make(chan struct{})
+	cmd, exited := sup.cmd, sup.exited
+	m.mu.Unlock()
+	go terminateProcess(cmd, exited, stopTimeout(sup.spec))
+}
+
+// resumeSupervised 启动人工停止的托管进程
+func (m *MultiMonitor) resumeSupervised(state *targetState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state.held = false
+	if sup := state.sup; sup.resume != nil {
+		close(sup.resume)
+		sup.resume = nil
+	}
+}
+
+// waitResume 等待人工启动；返回 false 表示已停止托管
+func (m *MultiMonitor) waitResume(state *targetState) bool {
+	m.mu.RLock()
+	resume := state.sup.resume
+	m.mu.RUnlock()
+	if resume != nil {
+		<-resume
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !state.sup.stopping
+}
+
+// stopSupervisedLocked 停止托管进程且不再重启（调用方持有锁）
+func (m *MultiMonitor) stopSupervisedLocked(state *targetState) {
+	sup := state.sup
+	if sup == nil || sup.stopping {
+		return
+	}
+	sup.stopping = true
+	close(sup.stop)
+	if sup.resume != nil {
+		close(sup.resume)
+		sup.resume = nil
+	}
+	go terminateProcess(sup.cmd, sup.exited, stopTimeout(sup.spec))
+}
+
+// StopSupervised 停止所有托管进程并等待退出（服务关闭时调用）
+func (m *MultiMonitor) StopSupervised() {
+	m.mu.Lock()
+	var waits []chan struct{}
+	for _, state := range m.targets {
+		if state.sup != nil {
+			m.stopSupervisedLocked(state)
+			waits = append(waits, state.sup.done)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, done := range waits {
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+func stopTimeout(spec types.SuperviseSpec) time.Duration {
+	if spec.StopTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(spec.StopTimeout) * time.Second
+}
+
+// safeFileName 目标名转为安全的文件名
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,135 @@
+//go:build linux
+
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"monitor-agent/types"
+)
+
+var rlimitResources = map[string]int{
+	"nofile":  unix.RLIMIT_NOFILE,
+	"core":    unix.RLIMIT_CORE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"as":      unix.RLIMIT_AS,
+	"data":    unix.RLIMIT_DATA,
+	"stack":   unix.RLIMIT_STACK,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"memlock": unix.RLIMIT_MEMLOCK,
+	"cpu":     unix.RLIMIT_CPU,
+}
+
+// applySysProcAttr 设置独立进程组以及运行用户/组（附加组设为该用户的附加组，不继承代理的）
+func applySysProcAttr(cmd *exec.Cmd, spec types.SuperviseSpec) error {
+	for name := range spec.Rlimits {
+		if _, ok := rlimitResources[name]; !ok {
+			return fmt.Errorf("unknown rlimit %q", name)
+		}
+	}
+	attr := &syscall.SysProcAttr{Setpgid: true}
+	if spec.User != "" || spec.Group != "" {
+		cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+		if spec.User != "" {
+			u, err := user.Lookup(spec.User)
+			if err != nil {
+				return fmt.Errorf("lookup user %s: %w", spec.User, err)
+			}
+			uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+			gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+			cred.Uid, cred.Gid = uint32(uid), uint32(gid)
+			ids, err := u.GroupIds()
+			if err != nil {
+				return fmt.Errorf("lookup groups of %s: %w", spec.User, err)
+			}
+			for _, id := range ids {
+				if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+					cred.Groups = append(cred.Groups, uint32(g))
+				}
+			}
+		}
+		if spec.Group != "" {
+			g, err := user.LookupGroup(spec.Group)
+			if err != nil {
+				return fmt.Errorf("lookup group %s: %w", spec.Group, err)
+			}
+			gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+			cred.Gid = uint32(gid)
+		}
+		// 非 root 无权设置附加组
+		cred.NoSetGroups = os.Getuid() != 0
+		attr.Credential = cred
+	}
+	cmd.SysProcAttr = attr
+	return nil
+}
+
+// wrapRlimits 通过 prlimit(1) 包装命令：资源限制在 exec 目标程序之前生效，
+// 目标程序及其所有子进程从一开始就受限，无法设置时 prlimit 失败退出
+func wrapRlimits(command string, args []string, limits map[string]uint64) (string, []string, error) {
+	if len(limits) == 0 {
+		return command, args, nil
+	}
+	prlimit, err := exec.LookPath("prlimit")
+	if err != nil {
+		return "", nil, fmt.Errorf("supervise.rlimits requires prlimit (util-linux): %w", err)
+	}
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	wrapped := make([]string, 0, len(names)+len(args)+2)
+	for _, name := range names {
+		value := strconv.FormatUint(limits[name], 10)
+		if limits[name] == unix.RLIM_INFINITY {
+			value = "unlimited"
+		}
+		wrapped = append(wrapped, fmt.Sprintf("--%s=%s:%s", name, value, value))
+	}
+	wrapped = append(wrapped, "--", command)
+	return prlimit, append(wrapped, args...), nil
+}
+
+// exitStatus 解析退出码、终止信号和 core dump 标记
+func exitStatus(ps *os.ProcessState) exitInfo {
+	if ps == nil {
+		return exitInfo{Code: -1}
+	}
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	if !ok {
+		return exitInfo{Code: ps.ExitCode()}
+	}
+	if ws.Signaled() {
+		return exitInfo{Code: -1, Signal: unix.SignalName(ws.Signal()), CoreDumped: ws.CoreDump()}
+	}
+	return exitInfo{Code: ws.ExitStatus()}
+}
+
+// terminateProcess 向进程组发送 SIGTERM，超时未退出则 SIGKILL
+func terminateProcess(cmd *exec.Cmd, exited <-chan struct{}, timeout time.Duration) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	// 进程已退出时进程组 ID 可能已被复用，不再发送信号
+	select {
+	case <-exited:
+		return
+	default:
+	}
+	pid := cmd.Process.Pid
+	syscall.Kill(-pid, syscall.SIGTERM)
+	select {
+	case <-exited:
+	case <-time.After(timeout):
+		syscall.Kill(-pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// applySysProcAttr Windows 不支持切换用户和资源限制，仅创建独立进程组
+func applySysProcAttr(cmd *exec.Cmd, spec types.SuperviseSpec) error {
+	if spec.User != "" || spec.Group != "" {
+		return fmt.Errorf("supervise.user/group not supported on windows")
+	}
+	if len(spec.Rlimits) > 0 {
+		return fmt.Errorf("supervise.rlimits not supported on windows")
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	return nil
+}
+
+func wrapRlimits(command string, args []string, limits map[string]uint64) (string, []string, error) {
+	return command, args, nil
+}
+
+// exitStatus Windows 只有退出码
+func exitStatus(ps *os.ProcessState) exitInfo {
+	if ps == nil {
+		return exitInfo{Code: -1}
+	}
+	return exitInfo{Code: ps.ExitCode()}
+}
+
+// terminateProcess Windows 无 SIGTERM，直接结束进程
+func terminateProcess(cmd *exec.Cmd, exited <-chan struct{}, timeout time.Duration) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	select {
+	case <-exited:
+		return
+	default:
+	}
+	cmd.Process.Kill()
+}
@@ -10,6 +10,10 @@ type ProcProvider interface {
 	FindAllPIDsByName(name string) ([]int32, error)
 	// GetMetrics 获取进程指标
 	GetMetrics(pid int32) (*types.ProcessMetrics, error)
+	// GetCounters 获取进程原始累计计数（状态、CPU 时间、IO、上下文切换）
+	GetCounters(pid int32) (*types.ProcessCounters, error)
+	// GetExe 获取进程可执行文件路径
+	GetExe(pid int32) (string, error)
 	// IsAlive 检查进程是否存活
 	IsAlive(pid int32) bool
 	// KillProcess 杀死进程
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/shirou/gopsutil/v3/net"
 	"github.com/shirou/gopsutil/v3/process"
@@ -33,6 +34,8 @@ type netSample struct {
 	sampleTime time.Time
 	recvRate   float64
 	sendRate   float64
+	nics       map[string]net.IOCountersStat // 各网卡上次计数
+	interfaces []types.InterfaceStat
 }
 
 // commonProvider 通用 provider 实现
@@ -43,6 +46,9 @@ type commonProvider struct {
 	// 系统指标缓存（后台 goroutine 更新）
 	sysCPUMu      sync.RWMutex
 	sysCPUPercent float64
+	sysCPUCores   []float64
+	sysIowait     float64
+	sysSteal      float64
 
 	// 网络采样
 	netSampleMu sync.RWMutex
@@ -76,15 +82,19 @@ func newCommonProvider(
 	return p
 }
 
-// sampleSystemMetrics 后台定时采集系统 CPU 和网络
+// sampleSystemMetrics 后台每秒采集系统 CPU 和网络
 func (p *commonProvider) sampleSystemMetrics() {
+	prev, _ := cpu.Times(true)
 	for {
-		// CPU 采样
-		cpuPercent, _ := cpu.Percent(time.Second, false)
-		if len(cpuPercent) > 0 {
-			p.sysCPUMu.Lock()
-			p.sysCPUPercent = cpuPercent[0]
-			p.sysCPUMu.Unlock()
+		time.Sleep(time.Second)
+
+		// CPU 采样：按两次各核心时间的差值计算
+		cur, err := cpu.Times(true)
+		if err == nil && len(cur) > 0 && len(cur) == len(prev) {
+			p.updateCPU(prev, cur)
+		}
+		if err == nil {
+			prev = cur
 		}
 
 		// 网络采样
@@ -92,16 +102,64 @@ func (p *commonProvider) sampleSystemMetrics() {
 	}
 }
 
-// sampleNetwork 采集网络流量
+// cpuTimes 返回总时间、忙碌时间、iowait、steal（guest 已计入 user，不重复计算）
+func cpuTimes(t cpu.TimesStat) (total, busy, iowait, steal float64) {
+	total = t.Total() - t.Guest - t.GuestNice
+	return total, total - t.Idle - t.Iowait, t.Iowait, t.Steal
+}
+
+func (p *commonProvider) updateCPU(prev, cur []cpu.TimesStat) {
+	cores := make([]float64, len(cur))
+	var sumTotal, sumBusy, sumIowait, sumSteal float64
+	for i := range cur {
+		t1, b1, w1, s1 := cpuTimes(prev[i])
+		t2, b2, w2, s2 := cpuTimes(cur[i])
+		dt := t2 - t1
+		if dt <= 0 {
+			continue
+		}
+		cores[i] = clampPercent((b2 - b1) / dt * 100)
+		sumTotal += dt
+		sumBusy += b2 - b1
+		sumIowait += w2 - w1
+		sumSteal += s2 - s1
+	}
+	if sumTotal <= 0 {
+		return
+	}
+	p.sysCPUMu.Lock()
+	p.sysCPUPercent = clampPercent(sumBusy / sumTotal * 100)
+	p.sysCPUCores = cores
+	p.sysIowait = clampPercent(sumIowait / sumTotal * 100)
+	p.sysSteal = clampPercent(sumSteal / sumTotal * 100)
+	p.sysCPUMu.Unlock()
+}
+
+func clampPercent(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// sampleNetwork 采集网络流量（各网卡及汇总）
 func (p *commonProvider) sampleNetwork() {
-	counters, err := net.IOCounters(false) // false = 汇总所有网卡
+	counters, err := net.IOCounters(true)
 	if err != nil || len(counters) == 0 {
 		return
 	}
 
 	now := time.Now()
-	totalRecv := counters[0].BytesRecv
-	totalSent := counters[0].BytesSent
+	var totalRecv, totalSent uint64
+	nics := make(map[string]net.IOCountersStat, len(counters))
+	for _, c := range counters {
+		totalRecv += c.BytesRecv
+		totalSent += c.BytesSent
+		nics[c.Name] = c
+	}
 
 	p.netSampleMu.Lock()
 	defer p.netSampleMu.Unlock()
@@ -111,14 +169,38 @@ func (p *commonProvider) sampleNetwork() {
 			bytesRecv:  totalRecv,
 			bytesSent:  totalSent,
 			sampleTime: now,
+			nics:       nics,
 		}
 		return
 	}
 
 	deltaTime := now.Sub(p.netSample.sampleTime).Seconds()
 	if deltaTime > 0.1 {
-		p.netSample.recvRate = float64(totalRecv-p.netSample.bytesRecv) / deltaTime
-		p.netSample.sendRate = float64(totalSent-p.netSample.bytesSent) / deltaTime
+		// 网卡移除或计数器重置时汇总值可能回退，本次速率记为 0
+		p.netSample.recvRate, p.netSample.sendRate = 0, 0
+		if totalRecv >= p.netSample.bytesRecv && totalSent >= p.netSample.bytesSent {
+			p.netSample.recvRate = float64(totalRecv-p.netSample.bytesRecv) / deltaTime
+			p.netSample.sendRate = float64(totalSent-p.netSample.bytesSent) / deltaTime
+		}
+		interfaces := make([]types.InterfaceStat, 0, len(counters))
+		for _, c := range counters {
+			st := types.InterfaceStat{
+				Name:      c.Name,
+				BytesRecv: c.BytesRecv,
+				BytesSent: c.BytesSent,
+				ErrIn:     c.Errin,
+				ErrOut:    c.Errout,
+				DropIn:    c.Dropin,
+				DropOut:   c.Dropout,
+			}
+			if prev, ok := p.netSample.nics[c.Name]; ok && c.BytesRecv >= prev.BytesRecv && c.BytesSent >= prev.BytesSent {
+				st.RecvRate = float64(c.BytesRecv-prev.BytesRecv) / deltaTime
+				st.SendRate = float64(c.BytesSent-prev.BytesSent) / deltaTime
+			}
+			interfaces = append(interfaces, st)
+		}
+		p.netSample.interfaces = interfaces
+		p.netSample.nics = nics
 		p.netSample.bytesRecv = totalRecv
 		p.netSample.bytesSent = totalSent
 		p.netSample.sampleTime = now
@@ -167,15 +249,61 @@ func (p *commonProvider) GetMetrics(pid int32) (*types.ProcessMetrics, error) {
 	if memInfo != nil {
 		rss = memInfo.RSS
 	}
+	var numFDs int32
+	if p.getHandleCount != nil {
+		numFDs = p.getHandleCount(pid)
+	} else {
+		numFDs, _ = proc.NumFDs()
+	}
+	numThreads, _ := proc.NumThreads()
+	var readRate, writeRate float64
+	if ioCounters, err := proc.IOCounters(); err == nil {
+		readRate, writeRate, _, _ = p.calcDiskIO(pid, ioCounters.ReadBytes, ioCounters.WriteBytes, ioCounters.ReadCount, ioCounters.WriteCount)
+	}
 	return &types.ProcessMetrics{
-		PID:      pid,
-		Name:     name,
-		CPUPct:   cpuPct,
-		RSSBytes: rss,
-		Alive:    true,
+		PID:         pid,
+		Name:        name,
+		CPUPct:      cpuPct,
+		RSSBytes:    rss,
+		NumFDs:      numFDs,
+		NumThreads:  numThreads,
+		IOReadRate:  readRate,
+		IOWriteRate: writeRate,
+		Alive:       true,
 	}, nil
 }
 
+func (p *commonProvider) GetCounters(pid int32) (*types.ProcessCounters, error) {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	c := &types.ProcessCounters{}
+	if status, err := proc.Status(); err == nil && len(status) > 0 {
+		c.Status = status[0]
+	}
+	if times, err := proc.Times(); err == nil {
+		c.CPUTime = times.User + times.System
+	}
+	if io, err := proc.IOCounters(); err == nil {
+		c.ReadBytes = io.ReadBytes
+		c.WriteBytes = io.WriteBytes
+	}
+	if ctx, err := proc.NumCtxSwitches(); err == nil {
+		c.CtxSwitches = ctx.Voluntary + ctx.Involuntary
+	}
+	c.NumThreads, _ = proc.NumThreads()
+	return c, nil
+}
+
+func (p *commonProvider) GetExe(pid int32) (string, error) {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return "", err
+	}
+	return proc.Exe()
+}
+
 func (p *commonProvider) IsAlive(pid int32) bool {
 	proc, err := process.NewProcess(pid)
 	if err != nil {
@@ -270,11 +398,10 @@ func (p *commonProvider) ListAllProcesses() ([]types.ProcessInfo, error) {
 			numFDs, _ = proc.NumFDs()
 		}
 
-		// 如果 cmdline 为空，尝试获取可执行文件路径
-		if cmdline == "" {
-			if exe, err := proc.Exe(); err == nil && exe != "" {
-				cmdline = p.formatCmdline(exe)
-			}
+		// 如果 cmdline 为空，使用可执行文件路径
+		exe, _ := proc.Exe()
+		if cmdline == "" && exe != "" {
+			cmdline = p.formatCmdline(exe)
 		}
 
 		var rss, vms uint64
@@ -344,6 +471,7 @@ func (p *commonProvider) ListAllProcesses() ([]types.ProcessInfo, error) {
 			DiskWriteOps:  diskWriteOps,
 			Uptime:        uptime,
 			Cmdline:       cmdline,
+			Exe:           exe,
 		})
 	}
 
@@ -364,28 +492,48 @@ func (p *commonProvider) GetSystemMetrics() (*types.SystemMetrics, error) {
 
 	p.sysCPUMu.RLock()
 	cpuPct := p.sysCPUPercent
+	cores := append([]float64(nil), p.sysCPUCores...)
+	iowait, steal := p.sysIowait, p.sysSteal
 	p.sysCPUMu.RUnlock()
 
 	// 获取网络流量
 	p.netSampleMu.RLock()
 	var netRecv, netSent uint64
 	var netRecvRate, netSendRate float64
+	var interfaces []types.InterfaceStat
 	if p.netSample != nil {
 		netRecv = p.netSample.bytesRecv
 		netSent = p.netSample.bytesSent
 		netRecvRate = p.netSample.recvRate
 		netSendRate = p.netSample.sendRate
+		interfaces = append(interfaces, p.netSample.interfaces...)
 	}
 	p.netSampleMu.RUnlock()
 
-	return &types.SystemMetrics{
+	sm := &types.SystemMetrics{
+		Timestamp:     time.Now(),
 		CPUPercent:    cpuPct,
+		CPUPerCore:    cores,
+		CPUIowait:     iowait,
+		CPUSteal:      steal,
 		MemoryTotal:   memInfo.Total,
 		MemoryUsed:    memInfo.Used,
 		MemoryPercent: float64(memInfo.Used) / float64(memInfo.Total) * 100,
+		Pressure:      readPressure(),
 		NetBytesRecv:  netRecv,
 		NetBytesSent:  netSent,
 		NetRecvRate:   netRecvRate,
 		NetSendRate:   netSendRate,
-	}, nil
+		Interfaces:    interfaces,
+	}
+	if avg, err := load.Avg(); err == nil {
+		sm.Load1, sm.Load5, sm.Load15 = avg.Load1, avg.Load5, avg.Load15
+	}
+	if swap, err := mem.SwapMemory(); err == nil {
+		sm.SwapTotal, sm.SwapUsed, sm.SwapPercent = swap.Total, swap.Used, swap.UsedPercent
+	}
+	if pids, err := process.Pids(); err == nil {
+		sm.ProcessCount = len(pids)
+	}
+	return sm, nil
 }
@@ -3,7 +3,12 @@
 package provider
 
 import (
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+
+	"monitor-agent/types"
 )
 
 func New() ProcProvider {
@@ -26,3 +31,50 @@ func New() ProcProvider {
 		nil,
 	)
 }
+
+// readPressure 读取 /proc/pressure/{cpu,memory,io}，内核不支持或未启用 PSI 时返回 nil
+func readPressure() *types.Pressure {
+	var p types.Pressure
+	for _, r := range []struct {
+		name string
+		stat *types.PressureStat
+	}{{"cpu", &p.CPU}, {"memory", &p.Memory}, {"io", &p.IO}} {
+		data, err := os.ReadFile("/proc/pressure/" + r.name)
+		if err != nil {
+			return nil
+		}
+		parsePressure(string(data), r.stat)
+	}
+	return &p
+}
+
+// parsePressure 解析 "some avg10=0.00 avg60=0.00 avg300=0.00 total=0" 和 "full ..." 行
+func parsePressure(data string, stat *types.PressureStat) {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, f := range fields[1:] {
+			k, v, _ := strings.Cut(f, "=")
+			val, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			switch fields[0] + "/" + k {
+			case "some/avg10":
+				stat.Some10 = val
+			case "some/avg60":
+				stat.Some60 = val
+			case "some/avg300":
+				stat.Some300 = val
+			case "full/avg10":
+				stat.Full10 = val
+			case "full/avg60":
+				stat.Full60 = val
+			case "full/avg300":
+				stat.Full300 = val
+			}
+		}
+	}
+}
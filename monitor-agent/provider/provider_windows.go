@@ -7,6 +7,8 @@ import (
 	"os/exec"
 	"syscall"
 	"unsafe"
+
+	"monitor-agent/types"
 )
 
 var (
@@ -105,3 +107,8 @@ func New() ProcProvider {
 		getProcessMemoryPools,
 	)
 }
+
+// readPressure Windows 无 PSI
+func readPressure() *types.Pressure {
+	return nil
+}
@@ -0,0 +1,366 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"monitor-agent/history"
+	"monitor-agent/types"
+)
+
+// PeriodRange 计算 ref 所在的统计周期 [from, to)，周以周一为起点
+func PeriodRange(period string, ref time.Time) (time.Time, time.Time, error) {
+	day := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+	switch period {
+	case "day":
+		return day, day.AddDate(0, 0, 1), nil
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7
+		from := day.AddDate(0, 0, -offset)
+		return from, from.AddDate(0, 0, 7), nil
+	case "month":
+		from := time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, ref.Location())
+		return from, from.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid period %q (day/week/month)", period)
+}
+
+const (
+	// aliveGap 相邻两次存活采样间隔超过该值时视为中间不可观测（代理停止等）
+	aliveGap = 5 * time.Minute
+	// eventLookback 周期开始前的事件用于确定周期起点时的状态和 PID 链，只回溯该时长
+	eventLookback = 7 * 24 * time.Hour
+)
+
+// instance 一个目标实例：同名且通过 restart / reattach 事件的 old_pid/new_pid 串联的进程链
+type instance struct {
+	name   string
+	alias  string
+	pid    int32 // 最近的 PID
+	pids   []int32
+	events []types.Event
+}
+
+// Availability 根据历史事件和指标计算周期内各目标的可用率、MTBF/MTTR 和重启统计。
+// 统计对象为 targets 中的目标及周期内有事件的实例；当前周期只统计到 now。
+func Availability(store *history.Store, targets []types.MonitorTarget, period string, ref time.Time) (*types.AvailabilityReport, error) {
+	from, to, err := PeriodRange(period, ref)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if to.After(now) {
+		to = now
+	}
+	rep := &types.AvailabilityReport{Period: period, From: from, To: to, GeneratedAt: now, Targets: []types.TargetAvailability{}}
+	if !from.Before(to) {
+		return rep, nil
+	}
+
+	// 周期开始前的事件用于确定周期起点时的状态
+	events, err := store.QueryEvents(history.Query{From: from.Add(-eventLookback), To: to})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+
+	instances, include := groupInstances(events, targets, from)
+
+	// 各进程的存活区间，用于确定恢复时间和目标开始被监控的时间（已结束的日期使用按天摘要，不重复扫描指标）
+	runs, err := store.AliveRuns(from.Add(-24*time.Hour), to, aliveGap)
+	if err != nil {
+		return nil, err
+	}
+	firstSeen := make(map[int32]time.Time, len(runs))
+	for pid, rs := range runs {
+		firstSeen[pid] = rs[0].Start
+	}
+
+	// 同名的多个实例附加 PID 区分
+	count := make(map[string]int)
+	for _, in := range instances {
+		if include[in] {
+			count[in.name]++
+		}
+	}
+	for _, in := range instances {
+		if !include[in] {
+			continue
+		}
+		ta := targetAvailability(in, runs, firstSeen, from, to)
+		if count[in.name] > 1 {
+			ta.Name = fmt.Sprintf("%s (PID %d)", in.name, in.pid)
+		}
+		rep.Targets = append(rep.Targets, ta)
+	}
+	sort.Slice(rep.Targets, func(i, j int) bool { return rep.Targets[i].Name < rep.Targets[j].Name })
+	return rep, nil
+}
+
+// groupInstances 按名称和 PID 链把事件归入实例，返回实例列表和需要统计的实例
+func groupInstances(events []types.Event, targets []types.MonitorTarget, from time.Time) ([]*instance, map[*instance]bool) {
+	parent := make(map[string]string)
+	pidOf := make(map[string]int32)
+	var find func(k string) string
+	find = func(k string) string {
+		p, ok := parent[k]
+		if !ok || p == k {
+			parent[k] = k
+			return k
+		}
+		root := find(p)
+		parent[k] = root
+		return root
+	}
+	key := func(name string, pid int32) string {
+		k := fmt.Sprintf("%s/%d", name, pid)
+		pidOf[k] = pid
+		return k
+	}
+
+	var relevant []types.Event
+	for _, evt := range events {
+		switch evt.Type {
+		case "exit", "restart", "reattach":
+		default:
+			continue
+		}
+		relevant = append(relevant, evt)
+		find(key(evt.Name, evt.PID))
+		oldPID, ok1 := detailPID(evt.Details, "old_pid")
+		newPID, ok2 := detailPID(evt.Details, "new_pid")
+		if ok1 && ok2 {
+			a, b := find(key(evt.Name, oldPID)), find(key(evt.Name, newPID))
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+
+	byRoot := make(map[string]*instance)
+	var instances []*instance
+	get := func(name string, pid int32) *instance {
+		root := find(key(name, pid))
+		in, ok := byRoot[root]
+		if !ok {
+			in = &instance{name: name, pid: pid}
+			byRoot[root] = in
+			instances = append(instances, in)
+		}
+		return in
+	}
+	include := make(map[*instance]bool)
+	for _, evt := range relevant {
+		in := get(evt.Name, evt.PID)
+		in.events = append(in.events, evt)
+		in.pid = evt.PID
+		if newPID, ok := detailPID(evt.Details, "new_pid"); ok {
+			in.pid = newPID
+		}
+		if !evt.Timestamp.Before(from) {
+			include[in] = true
+		}
+	}
+	for _, t := range targets {
+		in := get(t.Name, t.PID)
+		in.alias, in.pid = t.Alias, t.PID
+		include[in] = true
+	}
+	for k := range parent {
+		if in, ok := byRoot[find(k)]; ok {
+			in.pids = append(in.pids, pidOf[k])
+		}
+	}
+	return instances, include
+}
+
+// targetAvailability 按时间顺序回放实例状态：非计划 exit 进入不可用，之后任一进程的首次存活采样恢复。
+// 没有指标历史时以成功的 restart / reattach 事件作为恢复。统计从周期起点或实例首次出现的时间开始。
+func targetAvailability(in *instance, runs map[int32][]history.AliveRun, firstSeen map[int32]time.Time, from, to time.Time) types.TargetAvailability {
+	ta := types.TargetAvailability{Name: in.name, Alias: in.alias, PID: in.pid, RestartsByReason: make(map[string]int), Downtimes: []types.DowntimePeriod{}}
+
+	// 实例首次出现（事件或采样）晚于周期起点时，从首次出现开始统计
+	var first time.Time
+	if len(in.events) > 0 {
+		first = in.events[0].Timestamp
+	}
+	sampled := false
+	for _, pid := range in.pids {
+		if t, ok := firstSeen[pid]; ok {
+			sampled = true
+			if first.IsZero() || t.Before(first) {
+				first = t
+			}
+		}
+	}
+	start := from
+	if first.After(from) {
+		start = first
+	}
+	if !start.Before(to) {
+		return ta
+	}
+
+	var (
+		down      bool
+		planned   bool
+		downStart time.Time
+		cause     string
+		plannedT  float64
+		repairT   float64
+		repairs   int
+	)
+
+	closeDown := func(end time.Time, ongoing bool) {
+		down = false
+		s, e := clip(downStart, end, start, to)
+		if !e.After(s) {
+			return
+		}
+		if planned {
+			plannedT += e.Sub(s).Seconds()
+			return
+		}
+		ta.Downtimes = append(ta.Downtimes, types.DowntimePeriod{
+			Start:    s,
+			End:      e,
+			Duration: e.Sub(s).Seconds(),
+			Cause:    cause,
+			Ongoing:  ongoing,
+		})
+		ta.Downtime += e.Sub(s).Seconds()
+		if !ongoing {
+			repairT += end.Sub(downStart).Seconds()
+			repairs++
+		}
+	}
+	// recover 有指标历史时，不可用期间首次存活采样早于 before 则在该时刻恢复
+	recover := func(before time.Time) {
+		if !down || !sampled {
+			return
+		}
+		if t, ok := in.aliveAfter(downStart, runs); ok && !t.After(before) {
+			closeDown(t, false)
+		}
+	}
+
+	for _, evt := range in.events {
+		recover(evt.Timestamp)
+		inPeriod := !evt.Timestamp.Before(from)
+		switch evt.Type {
+		case "exit":
+			if down {
+				continue
+			}
+			down, downStart, cause = true, evt.Timestamp, evt.Message
+			planned = detailBool(evt.Details, "planned", false)
+			if inPeriod && !planned {
+				ta.Failures++
+			}
+		case "restart":
+			if !restartSucceeded(evt) {
+				continue
+			}
+			if inPeriod {
+				ta.Restarts++
+				ta.RestartsByReason[restartReason(evt)]++
+			}
+			if down && !sampled {
+				closeDown(evt.Timestamp, false)
+			}
+		case "reattach":
+			if down && !sampled {
+				closeDown(evt.Timestamp, false)
+			}
+		}
+	}
+	recover(to)
+	if down {
+		closeDown(to, true)
+	}
+
+	ta.Observed = to.Sub(start).Seconds() - plannedT
+	ta.Uptime = ta.Observed - ta.Downtime
+	if ta.Observed > 0 {
+		ta.UptimePct = ta.Uptime / ta.Observed * 100
+	}
+	if ta.Failures > 0 {
+		ta.MTBF = ta.Uptime / float64(ta.Failures)
+	}
+	if repairs > 0 {
+		ta.MTTR = repairT / float64(repairs)
+	}
+	return ta
+}
+
+// aliveAfter 实例的任一进程在 t 之后首次被采样到存活的时间
+func (in *instance) aliveAfter(t time.Time, runs map[int32][]history.AliveRun) (time.Time, bool) {
+	var best time.Time
+	for _, pid := range in.pids {
+		for _, r := range runs[pid] {
+			if !r.End.After(t) {
+				continue
+			}
+			at := r.Start
+			if at.Before(t) {
+				at = t
+			}
+			if best.IsZero() || at.Before(best) {
+				best = at
+			}
+			break
+		}
+	}
+	return best, !best.IsZero()
+}
+
+// clip 将 [start, end) 裁剪到 [from, to)
+func clip(start, end, from, to time.Time) (time.Time, time.Time) {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	return start, end
+}
+
+func detailPID(details map[string]interface{}, key string) (int32, bool) {
+	switch v := details[key].(type) {
+	case float64: // 从历史存储读取的 JSON 数值
+		return int32(v), true
+	case int32:
+		return v, true
+	}
+	return 0, false
+}
+
+func detailBool(details map[string]interface{}, key string, def bool) bool {
+	if v, ok := details[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+var reasonPattern = regexp.MustCompile(`原因:([^,，)）]+)`)
+
+// restartSucceeded 判断重启是否成功；旧版本事件无 details 时按描述判断
+func restartSucceeded(evt types.Event) bool {
+	if v, ok := evt.Details["success"].(bool); ok {
+		return v
+	}
+	return !strings.Contains(evt.Message, "失败") && !strings.Contains(evt.Message, "最大重启次数")
+}
+
+func restartReason(evt types.Event) string {
+	if v, ok := evt.Details["reason"].(string); ok && v != "" {
+		return v
+	}
+	if m := reasonPattern.FindStringSubmatch(evt.Message); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return "unknown"
+}
@@ -0,0 +1,118 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"monitor-agent/types"
+)
+
+// WriteCSV 导出可用性报告（每个目标一行）
+func WriteCSV(w io.Writer, rep *types.AvailabilityReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "alias", "pid", "period", "from", "to", "uptime_pct", "uptime_s", "downtime_s",
+		"failures", "mtbf_s", "mttr_s", "restarts", "restarts_by_reason", "downtime_periods"})
+	for _, t := range rep.Targets {
+		cw.Write([]string{
+			t.Name,
+			t.Alias,
+			fmt.Sprint(t.PID),
+			rep.Period,
+			rep.From.Format(time.RFC3339),
+			rep.To.Format(time.RFC3339),
+			fmt.Sprintf("%.3f", t.UptimePct),
+			fmt.Sprintf("%.0f", t.Uptime),
+			fmt.Sprintf("%.0f", t.Downtime),
+			fmt.Sprint(t.Failures),
+			fmt.Sprintf("%.0f", t.MTBF),
+			fmt.Sprintf("%.0f", t.MTTR),
+			fmt.Sprint(t.Restarts),
+			reasonsString(t.RestartsByReason),
+			fmt.Sprint(len(t.Downtimes)),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func reasonsString(reasons map[string]int) string {
+	keys := make([]string, 0, len(reasons))
+	for k := range reasons {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%d", k, reasons[k]))
+	}
+	return strings.Join(parts, ";")
+}
+
+// formatDuration 秒数格式化为 1d2h3m4s
+func formatDuration(sec float64) string {
+	if sec <= 0 {
+		return "-"
+	}
+	d := time.Duration(sec) * time.Second
+	days := int(d.Hours()) / 24
+	d -= time.Duration(days) * 24 * time.Hour
+	if days > 0 {
+		return fmt.Sprintf("%dd%s", days, d.String())
+	}
+	return d.String()
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"dur":     formatDuration,
+	"reasons": reasonsString,
+	"time":    func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"pct":     func(v float64) string { return fmt.Sprintf("%.3f%%", v) },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>可用性报告 {{time .From}} - {{time .To}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f0f0f0; }
+.bad { color: #c00; }
+</style>
+</head>
+<body>
+<h1>可用性报告</h1>
+<p>统计周期：{{.Period}}，{{time .From}} 至 {{time .To}}；生成时间：{{time .GeneratedAt}}</p>
+<table>
+<tr><th>目标</th><th>可用率</th><th>不可用时长</th><th>故障次数</th><th>MTBF</th><th>MTTR</th><th>重启次数</th><th>重启原因</th></tr>
+{{range .Targets}}<tr>
+<td>{{.Name}}{{if .Alias}}（{{.Alias}}）{{end}}</td>
+<td{{if lt .UptimePct 100.0}} class="bad"{{end}}>{{pct .UptimePct}}</td>
+<td>{{dur .Downtime}}</td>
+<td>{{.Failures}}</td>
+<td>{{dur .MTBF}}</td>
+<td>{{dur .MTTR}}</td>
+<td>{{.Restarts}}</td>
+<td>{{reasons .RestartsByReason}}</td>
+</tr>
+{{end}}</table>
+{{range .Targets}}{{if .Downtimes}}
+<h2>{{.Name}} 不可用时段</h2>
+<table>
+<tr><th>开始</th><th>结束</th><th>时长</th><th>原因</th></tr>
+{{range .Downtimes}}<tr><td>{{time .Start}}</td><td>{{time .End}}{{if .Ongoing}}（未恢复）{{end}}</td><td>{{dur .Duration}}</td><td>{{.Cause}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML 导出可用性报告为 HTML 页面
+func WriteHTML(w io.Writer, rep *types.AvailabilityReport) error {
+	return htmlTemplate.Execute(w, rep)
+}
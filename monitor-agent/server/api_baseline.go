@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GET /api/baseline?name=xxx - 获取目标学习基线（不带 name 返回已学习的目标列表）
+func (s *WebServer) handleBaseline(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.jsonResponse(w, s.multiMonitor.ListBaselines())
+		return
+	}
+	info := s.multiMonitor.GetBaseline(name)
+	if info == nil {
+		s.errorResponse(w, 404, "baseline not found")
+		return
+	}
+	s.jsonResponse(w, info)
+}
+
+// POST /api/baseline/reset - 重置目标学习基线
+func (s *WebServer) handleBaselineReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var req struct {
+		Name   string `json:"name"`
+		Metric string `json:"metric"` // 为空时重置全部指标
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.ResetBaseline(req.Name, req.Metric); err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"monitor-agent/types"
+)
+
+// GET /api/checks - 外部检查状态（状态、输出、性能数据）
+func (s *WebServer) handleChecks(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.multiMonitor.GetChecks())
+}
+
+// POST /api/checks/add - 添加外部检查
+func (s *WebServer) handleCheckAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var check types.ExecCheck
+	if err := json.NewDecoder(r.Body).Decode(&check); err != nil {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.AddCheck(check); err != nil {
+		s.errorResponse(w, 400, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/checks/remove - 移除外部检查
+func (s *WebServer) handleCheckRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.RemoveCheck(req.Name); err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/checks/run - 立即执行一次检查
+func (s *WebServer) handleCheckRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.RunCheck(req.Name); err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "scheduled"})
+}
+
+// GET /api/checks/metrics?name=xxx[&n=60] - 检查最近的执行结果指标
+func (s *WebServer) handleCheckMetrics(w http.ResponseWriter, r *http.Request) {
+	n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+	if n <= 0 {
+		n = 60
+	}
+	metrics, err := s.multiMonitor.GetCheckMetrics(r.URL.Query().Get("name"), n)
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, metrics)
+}
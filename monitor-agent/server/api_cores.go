@@ -0,0 +1,8 @@
+package server
+
+import "net/http"
+
+// GET /api/cores?name=xxx - 列出检测到的 core 文件（不带 name 返回全部）
+func (s *WebServer) handleCoreDumps(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.multiMonitor.ListCoreDumps(r.URL.Query().Get("name")))
+}
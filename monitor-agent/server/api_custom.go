@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// GET /api/custom?pid=xxx[&metric=queue_len&n=60] - 目标的自定义指标（指定 metric 时返回采样点）
+func (s *WebServer) handleCustomSeries(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.ParseInt(r.URL.Query().Get("pid"), 10, 32)
+	if err != nil {
+		s.errorResponse(w, 400, "invalid pid")
+		return
+	}
+	n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+	if n <= 0 {
+		n = 60
+	}
+	series, err := s.multiMonitor.GetCustomSeries(int32(pid), r.URL.Query().Get("metric"), n)
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, series)
+}
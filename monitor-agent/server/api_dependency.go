@@ -0,0 +1,8 @@
+package server
+
+import "net/http"
+
+// GET /api/dependencies - 依赖图（启动层级、就绪状态、降级原因、等待启动/重启的目标）
+func (s *WebServer) handleDependencies(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.multiMonitor.GetDependencies())
+}
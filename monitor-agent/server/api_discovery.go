@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"monitor-agent/types"
+)
+
+// GET /api/discovery - 自动发现规则及其实例
+func (s *WebServer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.multiMonitor.GetDiscoveryStatus())
+}
+
+// POST /api/discovery/add - 添加自动发现规则（同名覆盖）
+func (s *WebServer) handleDiscoveryAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var rule types.DiscoveryRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.AddDiscoveryRule(rule); err != nil {
+		s.errorResponse(w, 400, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/discovery/remove - 移除自动发现规则（已加入的目标保留）
+func (s *WebServer) handleDiscoveryRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.RemoveDiscoveryRule(req.Name); err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
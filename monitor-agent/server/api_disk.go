@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"monitor-agent/history"
+	"monitor-agent/types"
+)
+
+// GET /api/disks[?n=60] - 最近的磁盘采样（默认只返回最新一次）
+func (s *WebServer) handleDisks(w http.ResponseWriter, r *http.Request) {
+	n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+	if n <= 0 {
+		n = 1
+	}
+	samples, err := s.multiMonitor.GetDiskMetrics(n)
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, samples)
+}
+
+// GET /api/disks/history?from=&to=&n= - 历史磁盘采样（from/to 为 RFC3339，默认最近 24 小时）
+func (s *WebServer) handleDiskHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := history.Query{Limit: 1000, From: time.Now().Add(-24 * time.Hour)}
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.errorResponse(w, 400, "invalid from")
+			return
+		}
+		q.From = t
+	}
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.errorResponse(w, 400, "invalid to")
+			return
+		}
+		q.To = t
+	}
+	if n, _ := strconv.Atoi(query.Get("n")); n > 0 {
+		q.Limit = n
+	}
+	samples, err := s.multiMonitor.History().QueryDisk(q)
+	if err != nil {
+		s.errorResponse(w, 500, err.Error())
+		return
+	}
+	if samples == nil {
+		samples = []types.DiskMetrics{}
+	}
+	s.jsonResponse(w, samples)
+}
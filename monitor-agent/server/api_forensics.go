@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+)
+
+// GET /api/forensics?name=xxx - 列出崩溃现场包（不带 name 返回全部）
+func (s *WebServer) handleForensics(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.multiMonitor.ListForensics(r.URL.Query().Get("name")))
+}
+
+// GET /api/forensics/download?id=xxx - 下载现场包（tar.gz）
+func (s *WebServer) handleForensicsDownload(w http.ResponseWriter, r *http.Request) {
+	path, err := s.multiMonitor.ForensicsPath(r.URL.Query().Get("id"))
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filepath.Base(path))
+	http.ServeFile(w, r, path)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"monitor-agent/history"
+	"monitor-agent/types"
+)
+
+// GET /api/groups?name=xxx - 分组汇总状态（不带 name 返回全部）
+func (s *WebServer) handleGroups(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.jsonResponse(w, s.multiMonitor.GetGroups())
+		return
+	}
+	st, err := s.multiMonitor.GetGroupStatus(name)
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, st)
+}
+
+// POST /api/groups/add - 添加分组（同名覆盖）
+func (s *WebServer) handleGroupAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var group types.AppGroup
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.AddGroup(group); err != nil {
+		s.errorResponse(w, 400, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// handleGroupAction 返回处理 {"name":"xxx"} 请求的分组操作（移除、暂停/恢复监控、启动/停止成员）
+func (s *WebServer) handleGroupAction(action func(name string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.errorResponse(w, 405, "method not allowed")
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+			s.errorResponse(w, 400, "invalid request body")
+			return
+		}
+		if err := action(req.Name); err != nil {
+			s.errorResponse(w, 500, err.Error())
+			return
+		}
+		s.jsonResponse(w, map[string]string{"status": "ok"})
+	}
+}
+
+// GET /api/groups/events?name=xxx&from=&to=&type=&n= - 分组成员的历史事件（from/to 为 RFC3339，默认最近 24 小时）
+func (s *WebServer) handleGroupEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		s.errorResponse(w, 400, "name required")
+		return
+	}
+	q := history.Query{Type: query.Get("type"), Limit: 100, From: time.Now().Add(-24 * time.Hour)}
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.errorResponse(w, 400, "invalid from")
+			return
+		}
+		q.From = t
+	}
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.errorResponse(w, 400, "invalid to")
+			return
+		}
+		q.To = t
+	}
+	if n, _ := strconv.Atoi(query.Get("n")); n > 0 {
+		q.Limit = n
+	}
+	events, err := s.multiMonitor.GroupEvents(name, q)
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	if events == nil {
+		events = []types.Event{}
+	}
+	s.jsonResponse(w, events)
+}
@@ -0,0 +1,8 @@
+package server
+
+import "net/http"
+
+// GET /api/heartbeat - 配置了心跳的目标状态（最近心跳、序号、丢失数、状态字段）
+func (s *WebServer) handleHeartbeats(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.multiMonitor.GetHeartbeats())
+}
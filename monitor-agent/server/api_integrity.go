@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// GET /api/integrity?pid=xxx - 完整性校验状态（不带 pid 返回全部启用校验的目标）
+func (s *WebServer) handleIntegrity(w http.ResponseWriter, r *http.Request) {
+	var pid int64
+	if v := r.URL.Query().Get("pid"); v != "" {
+		var err error
+		if pid, err = strconv.ParseInt(v, 10, 32); err != nil {
+			s.errorResponse(w, 400, "invalid pid")
+			return
+		}
+	}
+	s.jsonResponse(w, s.multiMonitor.GetIntegrity(int32(pid)))
+}
+
+// POST /api/integrity/accept - 确认文件变更，重新记录目标的文件哈希
+func (s *WebServer) handleIntegrityAccept(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var req struct {
+		PID int32 `json:"pid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PID == 0 {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.AcceptIntegrity(req.PID); err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
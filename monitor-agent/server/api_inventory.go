@@ -0,0 +1,39 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GET /api/inventory?status=baseline|unknown|denied - 进程清单（不带 status 返回全部）
+func (s *WebServer) handleInventory(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.multiMonitor.ListInventory(r.URL.Query().Get("status"))
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, entries)
+}
+
+// handleInventoryUpdate 处理 {"ids":[...]} 请求：加入基线或从基线移除
+func (s *WebServer) handleInventoryUpdate(update func(ids []string) (int, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.errorResponse(w, 405, "method not allowed")
+			return
+		}
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.IDs) == 0 {
+			s.errorResponse(w, 400, "invalid request body")
+			return
+		}
+		n, err := update(req.IDs)
+		if err != nil {
+			s.errorResponse(w, 404, err.Error())
+			return
+		}
+		s.jsonResponse(w, map[string]int{"updated": n})
+	}
+}
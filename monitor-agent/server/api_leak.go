@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// GET /api/leak?pid=xxx - 获取泄漏趋势预测（不带 pid 返回所有目标）
+func (s *WebServer) handleLeakForecast(w http.ResponseWriter, r *http.Request) {
+	pidStr := r.URL.Query().Get("pid")
+	if pidStr == "" {
+		s.jsonResponse(w, s.multiMonitor.GetAllLeakForecasts())
+		return
+	}
+	pid, err := strconv.ParseInt(pidStr, 10, 32)
+	if err != nil {
+		s.errorResponse(w, 400, "invalid pid")
+		return
+	}
+	forecast := s.multiMonitor.GetLeakForecast(int32(pid))
+	if forecast == nil {
+		s.errorResponse(w, 404, "no forecast for target (leak check disabled or not analyzed yet)")
+		return
+	}
+	s.jsonResponse(w, forecast)
+}
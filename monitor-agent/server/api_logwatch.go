@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// GET /api/logwatch?pid=xxx - 日志监视状态：跟踪的文件、各规则匹配计数（不带 pid 返回全部）
+func (s *WebServer) handleLogWatch(w http.ResponseWriter, r *http.Request) {
+	var pid int64
+	if v := r.URL.Query().Get("pid"); v != "" {
+		var err error
+		if pid, err = strconv.ParseInt(v, 10, 32); err != nil {
+			s.errorResponse(w, 400, "invalid pid")
+			return
+		}
+	}
+	s.jsonResponse(w, s.multiMonitor.GetLogWatch(int32(pid)))
+}
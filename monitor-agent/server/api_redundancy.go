@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"monitor-agent/types"
+)
+
+// GET /api/redundancy - 冗余组状态（主用成员、成员探测结果、最近一次切换）
+func (s *WebServer) handleRedundancy(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.multiMonitor.GetRedundancyStatus())
+}
+
+// POST /api/redundancy/add - 添加冗余组
+func (s *WebServer) handleRedundancyAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var group types.RedundancyGroup
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.AddRedundancyGroup(group); err != nil {
+		s.errorResponse(w, 400, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/redundancy/remove - 移除冗余组
+func (s *WebServer) handleRedundancyRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.RemoveRedundancyGroup(req.Name); err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/redundancy/switchover - 手动主备切换（to 为空时切换到下一个成员）
+func (s *WebServer) handleRedundancySwitchover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.errorResponse(w, 405, "method not allowed")
+		return
+	}
+	var req struct {
+		Group string `json:"group"`
+		To    string `json:"to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Group == "" {
+		s.errorResponse(w, 400, "invalid request body")
+		return
+	}
+	if err := s.multiMonitor.SwitchoverRedundancy(req.Group, req.To); err != nil {
+		s.errorResponse(w, 409, err.Error())
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "switching"})
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"monitor-agent/report"
+)
+
+// GET /api/report/availability?period=day|week|month&date=2006-01-02&name=xxx&format=json|csv|html
+// 可用性报告：可用率、不可用时段、MTBF/MTTR、按原因统计的重启次数
+func (s *WebServer) handleAvailability(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	period := q.Get("period")
+	if period == "" {
+		period = "month"
+	}
+	ref := time.Now()
+	if date := q.Get("date"); date != "" {
+		t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			s.errorResponse(w, 400, "invalid date (YYYY-MM-DD)")
+			return
+		}
+		ref = t
+	}
+
+	rep, err := report.Availability(s.multiMonitor.History(), s.multiMonitor.GetTargets(), period, ref)
+	if err != nil {
+		s.errorResponse(w, 400, err.Error())
+		return
+	}
+	if name := q.Get("name"); name != "" {
+		filtered := rep.Targets[:0]
+		for _, t := range rep.Targets {
+			if t.Name == name || t.Alias == name || strings.HasPrefix(t.Name, name+" (PID ") {
+				filtered = append(filtered, t)
+			}
+		}
+		rep.Targets = filtered
+	}
+
+	filename := fmt.Sprintf("availability_%s_%s", period, rep.From.Format("20060102"))
+	switch q.Get("format") {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename+".csv")
+		report.WriteCSV(w, rep)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if q.Get("download") != "" {
+			w.Header().Set("Content-Disposition", "attachment; filename="+filename+".html")
+		}
+		report.WriteHTML(w, rep)
+	default:
+		s.jsonResponse(w, rep)
+	}
+}
@@ -0,0 +1,13 @@
+package server
+
+import "net/http"
+
+// GET /api/sensors - 最近一次硬件传感器读数（温度、风扇、电压及生效的阈值）
+func (s *WebServer) handleSensors(w http.ResponseWriter, r *http.Request) {
+	readings, err := s.multiMonitor.GetSensors()
+	if err != nil {
+		s.errorResponse(w, 404, err.Error())
+		return
+	}
+	s.jsonResponse(w, readings)
+}
@@ -6,7 +6,9 @@ import (
 	"io/fs"
 	"net/http"
 	"strconv"
+	"time"
 
+	"monitor-agent/history"
 	"monitor-agent/monitor"
 	"monitor-agent/types"
 )
@@ -52,6 +54,45 @@ func NewWebServerWithAuth(mm *monitor.MultiMonitor, authCfg AuthConfig) *WebServ
 	s.mux.HandleFunc("/api/events", s.handleEvents)
 	s.mux.HandleFunc("/api/status", s.handleStatus)
 	s.mux.HandleFunc("/api/system", s.handleSystem)
+	s.mux.HandleFunc("/api/leak", s.handleLeakForecast)
+	s.mux.HandleFunc("/api/baseline", s.handleBaseline)
+	s.mux.HandleFunc("/api/baseline/reset", s.handleBaselineReset)
+	s.mux.HandleFunc("/api/report/availability", s.handleAvailability)
+	s.mux.HandleFunc("/api/forensics", s.handleForensics)
+	s.mux.HandleFunc("/api/forensics/download", s.handleForensicsDownload)
+	s.mux.HandleFunc("/api/cores", s.handleCoreDumps)
+	s.mux.HandleFunc("/api/redundancy", s.handleRedundancy)
+	s.mux.HandleFunc("/api/redundancy/add", s.handleRedundancyAdd)
+	s.mux.HandleFunc("/api/redundancy/remove", s.handleRedundancyRemove)
+	s.mux.HandleFunc("/api/redundancy/switchover", s.handleRedundancySwitchover)
+	s.mux.HandleFunc("/api/dependencies", s.handleDependencies)
+	s.mux.HandleFunc("/api/groups", s.handleGroups)
+	s.mux.HandleFunc("/api/groups/add", s.handleGroupAdd)
+	s.mux.HandleFunc("/api/groups/remove", s.handleGroupAction(s.multiMonitor.RemoveGroup))
+	s.mux.HandleFunc("/api/groups/enable", s.handleGroupAction(func(name string) error { return s.multiMonitor.SetGroupEnabled(name, true) }))
+	s.mux.HandleFunc("/api/groups/disable", s.handleGroupAction(func(name string) error { return s.multiMonitor.SetGroupEnabled(name, false) }))
+	s.mux.HandleFunc("/api/groups/start", s.handleGroupAction(s.multiMonitor.StartGroup))
+	s.mux.HandleFunc("/api/groups/stop", s.handleGroupAction(s.multiMonitor.StopGroup))
+	s.mux.HandleFunc("/api/groups/events", s.handleGroupEvents)
+	s.mux.HandleFunc("/api/discovery", s.handleDiscovery)
+	s.mux.HandleFunc("/api/discovery/add", s.handleDiscoveryAdd)
+	s.mux.HandleFunc("/api/discovery/remove", s.handleDiscoveryRemove)
+	s.mux.HandleFunc("/api/inventory", s.handleInventory)
+	s.mux.HandleFunc("/api/inventory/promote", s.handleInventoryUpdate(s.multiMonitor.PromoteInventory))
+	s.mux.HandleFunc("/api/inventory/remove", s.handleInventoryUpdate(s.multiMonitor.RemoveInventory))
+	s.mux.HandleFunc("/api/integrity", s.handleIntegrity)
+	s.mux.HandleFunc("/api/integrity/accept", s.handleIntegrityAccept)
+	s.mux.HandleFunc("/api/logwatch", s.handleLogWatch)
+	s.mux.HandleFunc("/api/heartbeat", s.handleHeartbeats)
+	s.mux.HandleFunc("/api/custom", s.handleCustomSeries)
+	s.mux.HandleFunc("/api/checks", s.handleChecks)
+	s.mux.HandleFunc("/api/checks/add", s.handleCheckAdd)
+	s.mux.HandleFunc("/api/checks/remove", s.handleCheckRemove)
+	s.mux.HandleFunc("/api/checks/run", s.handleCheckRun)
+	s.mux.HandleFunc("/api/checks/metrics", s.handleCheckMetrics)
+	s.mux.HandleFunc("/api/disks", s.handleDisks)
+	s.mux.HandleFunc("/api/disks/history", s.handleDiskHistory)
+	s.mux.HandleFunc("/api/sensors", s.handleSensors)
 
 	// 静态文件
 	staticFS, _ := fs.Sub(staticFiles, "static")
@@ -95,12 +136,13 @@ func (s *WebServer) handleListProcesses(w http.ResponseWriter, r *http.Request)
 	s.jsonResponse(w, procs)
 }
 
-// GET /api/monitor/targets - 获取监控目标列表
+// GET /api/monitor/targets - 获取监控目标列表（外部检查以 check:<名称> 目标附在最后）
 func (s *WebServer) handleTargets(w http.ResponseWriter, r *http.Request) {
 	targets := s.multiMonitor.GetTargets()
 	if targets == nil {
 		targets = []types.MonitorTarget{}
 	}
+	targets = append(targets, s.multiMonitor.GetCheckTargets()...)
 	s.jsonResponse(w, targets)
 }
 
@@ -231,8 +273,47 @@ func (s *WebServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GET /api/system - 获取系统指标
+// GET /api/system[?n=60 | ?from=&to=] - 获取系统指标：无参数返回当前值，
+// n 返回最近 n 次记录的序列，from/to（RFC3339）从历史存储查询
 func (s *WebServer) handleSystem(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("from") != "" || query.Get("to") != "" {
+		q := history.Query{Limit: 1000}
+		if v := query.Get("from"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.errorResponse(w, 400, "invalid from")
+				return
+			}
+			q.From = t
+		}
+		if v := query.Get("to"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.errorResponse(w, 400, "invalid to")
+				return
+			}
+			q.To = t
+		}
+		if n, _ := strconv.Atoi(query.Get("n")); n > 0 {
+			q.Limit = n
+		}
+		series, err := s.multiMonitor.History().QuerySystem(q)
+		if err != nil {
+			s.errorResponse(w, 500, err.Error())
+			return
+		}
+		if series == nil {
+			series = []types.SystemMetrics{}
+		}
+		s.jsonResponse(w, series)
+		return
+	}
+	if n, _ := strconv.Atoi(query.Get("n")); n > 0 {
+		s.jsonResponse(w, s.multiMonitor.GetSystemSeries(n))
+		return
+	}
+
 	metrics, err := s.multiMonitor.GetSystemMetrics()
 	if err != nil {
 		s.errorResponse(w, 500, err.Error())
@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"time"
 
+	"monitor-agent/exporter"
+	"monitor-agent/history"
 	"monitor-agent/monitor"
 	"monitor-agent/provider"
 	"monitor-agent/server"
@@ -17,17 +19,33 @@ import (
 
 // Config 服务配置
 type Config struct {
-	Addr           string
-	CPUThreshold   float64
-	CPUExceedCount int
-	LogDir         string
-	ConfigFile     string
+	Addr              string
+	CPUThreshold      float64
+	CPUExceedCount    int
+	LogDir            string
+	ConfigFile        string
+	HistoryRetention  int                       // 历史保留天数
+	MQTT              exporter.MQTTConfig       // Broker 为空时不启用 MQTT 发布
+	Outbox            exporter.OutboxConfig     // Dir 为空时不启用单向外发
+	Forensics         types.ForensicsConfig     // 崩溃现场采集
+	KernelLog         string                    // 内核日志路径，为空不启用
+	CoreDumps         types.CoreDumpConfig      // core 文件检测
+	DiscoveryInterval int                       // 自动发现扫描间隔（秒）
+	Inventory         types.InventoryConfig     // 进程白名单检测
+	Heartbeat         types.HeartbeatConfig     // 应用心跳接收
+	CustomMetrics     types.CustomMetricsConfig // 自定义指标接收
+	Checks            types.ChecksConfig        // 外部检查
+	Disk              types.DiskConfig          // 磁盘空间/IO 监控
+	SystemInterval    int                       // 系统指标记录间隔（秒）
+	Sensors           types.SensorConfig        // 硬件传感器监控
 }
 
 // Service 监控服务
 type Service struct {
 	config     Config
 	mm         *monitor.MultiMonitor
+	mqtt       *exporter.MQTTPublisher
+	outbox     *exporter.OutboxExporter
 	httpServer *http.Server
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -36,10 +54,7 @@ type Service struct {
 // New 创建服务实例
 func New(cfg Config) (*Service, error) {
 	// 确保日志目录存在
-	if cfg.LogDir == "" {
-		exe, _ := os.Executable()
-		cfg.LogDir = filepath.Join(filepath.Dir(exe), "logs")
-	}
+	cfg.LogDir = resolveLogDir(cfg.LogDir)
 	os.MkdirAll(cfg.LogDir, 0755)
 
 	// 设置日志输出到文件
@@ -50,12 +65,24 @@ func New(cfg Config) (*Service, error) {
 	}
 
 	monitorCfg := types.MultiMonitorConfig{
-		CPUThreshold:     cfg.CPUThreshold,
-		CPUExceedCount:   cfg.CPUExceedCount,
-		SampleInterval:   1,
-		MetricsBufferLen: 300,
-		EventsBufferLen:  100,
-		LogDir:           cfg.LogDir,
+		CPUThreshold:      cfg.CPUThreshold,
+		CPUExceedCount:    cfg.CPUExceedCount,
+		SampleInterval:    1,
+		SystemInterval:    cfg.SystemInterval,
+		MetricsBufferLen:  300,
+		EventsBufferLen:   100,
+		LogDir:            cfg.LogDir,
+		HistoryRetention:  cfg.HistoryRetention,
+		Forensics:         cfg.Forensics,
+		KernelLog:         cfg.KernelLog,
+		CoreDumps:         cfg.CoreDumps,
+		DiscoveryInterval: cfg.DiscoveryInterval,
+		Inventory:         cfg.Inventory,
+		Heartbeat:         cfg.Heartbeat,
+		CustomMetrics:     cfg.CustomMetrics,
+		Checks:            cfg.Checks,
+		Disk:              cfg.Disk,
+		Sensors:           cfg.Sensors,
 	}
 
 	prov := provider.New()
@@ -64,11 +91,31 @@ func New(cfg Config) (*Service, error) {
 		return nil, fmt.Errorf("create multi monitor: %w", err)
 	}
 
+	var mqttPub *exporter.MQTTPublisher
+	if cfg.MQTT.Broker != "" {
+		mqttPub, err = exporter.NewMQTTPublisher(cfg.MQTT)
+		if err != nil {
+			return nil, fmt.Errorf("create mqtt publisher: %w", err)
+		}
+		mm.AddSink(mqttPub)
+	}
+
+	var outbox *exporter.OutboxExporter
+	if cfg.Outbox.Dir != "" {
+		outbox, err = exporter.NewOutboxExporter(cfg.Outbox)
+		if err != nil {
+			return nil, fmt.Errorf("create outbox exporter: %w", err)
+		}
+		mm.AddSink(outbox)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Service{
 		config: cfg,
 		mm:     mm,
+		mqtt:   mqttPub,
+		outbox: outbox,
 		ctx:    ctx,
 		cancel: cancel,
 	}, nil
@@ -94,6 +141,13 @@ func (s *Service) Start() error {
 		}
 	}()
 
+	if s.mqtt != nil {
+		s.mqtt.Start()
+	}
+	if s.outbox != nil {
+		s.outbox.Start()
+	}
+
 	// 自动启动监控（如果有保存的配置）
 	s.loadSavedTargets()
 
@@ -108,8 +162,16 @@ func (s *Service) Stop() error {
 	// 保存当前监控目标
 	s.saveTargets()
 
-	// 停止监控
+	// 停止监控及托管进程
 	s.mm.Stop()
+	s.mm.StopSupervised()
+
+	if s.mqtt != nil {
+		s.mqtt.Stop()
+	}
+	if s.outbox != nil {
+		s.outbox.Stop()
+	}
 
 	// 关闭 HTTP 服务器
 	if s.httpServer != nil {
@@ -130,6 +192,25 @@ func (s *Service) Wait() {
 	<-s.ctx.Done()
 }
 
+// ImportOutbox 将单向网关接收目录中的批次导入本机历史存储
+func ImportOutbox(cfg Config, dir string) (exporter.ImportResult, error) {
+	store, err := history.Open(filepath.Join(resolveLogDir(cfg.LogDir), "history"), cfg.HistoryRetention)
+	if err != nil {
+		return exporter.ImportResult{}, err
+	}
+	defer store.Close()
+	return exporter.ImportOutbox(dir, store)
+}
+
+// resolveLogDir 未指定时使用程序所在目录下的 logs
+func resolveLogDir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+	exe, _ := os.Executable()
+	return filepath.Join(filepath.Dir(exe), "logs")
+}
+
 // loadSavedTargets 加载保存的监控目标
 func (s *Service) loadSavedTargets() {
 	// TODO: 从配置文件加载保存的监控目标
@@ -0,0 +1,76 @@
+package stats
+
+import "math"
+
+// LinearFit 最小二乘线性拟合结果 y = Intercept + Slope*x
+type LinearFit struct {
+	Slope      float64
+	Intercept  float64
+	R2         float64 // 拟合优度
+	SlopeSE    float64 // 斜率标准误
+	Confidence float64 // 斜率 > 0 的单侧置信度（t 统计量按正态近似）
+	N          int
+}
+
+// Fit 对 (xs, ys) 做最小二乘拟合，样本不足 3 个时返回 ok=false
+func Fit(xs, ys []float64) (LinearFit, bool) {
+	n := len(xs)
+	if n < 3 || len(ys) != n {
+		return LinearFit{}, false
+	}
+	var sumX, sumY float64
+	for i := 0; i < n; i++ {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+
+	var sxx, sxy, syy float64
+	for i := 0; i < n; i++ {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return LinearFit{}, false
+	}
+
+	fit := LinearFit{N: n}
+	fit.Slope = sxy / sxx
+	fit.Intercept = meanY - fit.Slope*meanX
+
+	// 残差平方和
+	sse := syy - fit.Slope*sxy
+	if sse < 0 {
+		sse = 0
+	}
+	if syy > 0 {
+		fit.R2 = 1 - sse/syy
+	} else {
+		fit.R2 = 1
+	}
+	fit.SlopeSE = math.Sqrt(sse/float64(n-2)) / math.Sqrt(sxx)
+
+	switch {
+	case fit.SlopeSE > 0:
+		fit.Confidence = NormalCDF(fit.Slope / fit.SlopeSE)
+	case fit.Slope > 0:
+		fit.Confidence = 1 // 完全线性增长
+	case fit.Slope < 0:
+		fit.Confidence = 0
+	default:
+		fit.Confidence = 0.5
+	}
+	return fit, true
+}
+
+// At 计算 x 处的拟合值
+func (f LinearFit) At(x float64) float64 {
+	return f.Intercept + f.Slope*x
+}
+
+// NormalCDF 标准正态分布累积分布函数
+func NormalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
@@ -4,21 +4,42 @@ import "time"
 
 // ProcessMetrics 进程指标
 type ProcessMetrics struct {
-	Timestamp time.Time `json:"timestamp"`
-	PID       int32     `json:"pid"`
-	Name      string    `json:"name"`
-	CPUPct    float64   `json:"cpu_pct"`
-	RSSBytes  uint64    `json:"rss_bytes"`
-	Alive     bool      `json:"alive"`
+	Timestamp   time.Time          `json:"timestamp"`
+	PID         int32              `json:"pid"`
+	Name        string             `json:"name"`
+	CPUPct      float64            `json:"cpu_pct"`
+	RSSBytes    uint64             `json:"rss_bytes"`
+	Alive       bool               `json:"alive"`
+	NumFDs      int32              `json:"num_fds,omitempty"`       // 文件描述符数/句柄数
+	NumThreads  int32              `json:"num_threads,omitempty"`   // 线程数
+	IOReadRate  float64            `json:"io_read_rate,omitempty"`  // 磁盘读取速率 (B/s)
+	IOWriteRate float64            `json:"io_write_rate,omitempty"` // 磁盘写入速率 (B/s)
+	Sockets     *SocketStats       `json:"sockets,omitempty"`       // 套接字统计（目标配置 sockets 时采集）
+	LogMatches  map[string]uint64  `json:"log_matches,omitempty"`   // 日志规则累计匹配次数（目标配置 logs 时记录）
+	Custom      map[string]float64 `json:"custom,omitempty"`        // 应用上报的自定义指标（心跳状态字段等）
+}
+
+// SocketStats 进程套接字统计
+type SocketStats struct {
+	Listening    []string       `json:"listening"`       // 监听地址，如 tcp/0.0.0.0:502、udp/[::]:161
+	Established  int            `json:"established"`     // 已建立的 TCP 连接数
+	Peers        map[string]int `json:"peers,omitempty"` // 远端地址 -> 已建立连接数
+	CloseWait    int            `json:"close_wait"`      // CLOSE_WAIT 连接数
+	TimeWait     int            `json:"time_wait"`       // 本地端口为监听端口的 TIME_WAIT 连接数
+	RecvQueue    uint64         `json:"recv_queue"`      // 已建立连接接收队列积压之和（字节）
+	SendQueue    uint64         `json:"send_queue"`      // 已建立连接发送队列积压之和（字节）
+	MaxRecvQueue uint64         `json:"max_recv_queue"`  // 单个连接最大接收队列
+	MaxSendQueue uint64         `json:"max_send_queue"`  // 单个连接最大发送队列
 }
 
 // Event 事件记录
 type Event struct {
-	Timestamp time.Time `json:"timestamp"`
-	Type      string    `json:"type"` // "exit", "cpu_threshold", "restart"
-	PID       int32     `json:"pid"`
-	Name      string    `json:"name"`
-	Message   string    `json:"message"`
+	Timestamp time.Time              `json:"timestamp"`
+	Type      string                 `json:"type"` // "exit", "cpu_threshold", "restart"
+	PID       int32                  `json:"pid"`
+	Name      string                 `json:"name"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"` // 附加信息（退出码、信号等）
 }
 
 // MonitorConfig 监控配置
@@ -61,43 +82,690 @@ type ProcessInfo struct {
 	DiskWriteOps  float64 `json:"disk_write_ops"`  // 磁盘写入次数/秒
 	Uptime        int64   `json:"uptime"`          // 已运行时间（秒）
 	Cmdline       string  `json:"cmdline"`         // 命令行
+	Exe           string  `json:"exe,omitempty"`   // 可执行文件路径
 }
 
 // MonitorTarget 监控目标
 type MonitorTarget struct {
-	PID             int32   `json:"pid"`
-	Name            string  `json:"name"`                        // 进程名
-	Alias           string  `json:"alias,omitempty"`             // 备注名称（如：电力监控主进程）
-	Cmdline         string  `json:"cmdline,omitempty"`           // 进程命令行（用于自动填充重启命令）
-	RestartCmd      string  `json:"restart_cmd,omitempty"`       // 重启命令
-	AutoRestart     bool    `json:"auto_restart"`                // 退出时自动重启
-	CPUThreshold    float64 `json:"cpu_threshold,omitempty"`     // CPU阈值 (%)
-	MemThreshold    uint64  `json:"mem_threshold,omitempty"`     // 内存阈值 (bytes)
-	CPUExceedCount  int     `json:"cpu_exceed_count,omitempty"`  // CPU连续超限次数触发
-	MemExceedCount  int     `json:"mem_exceed_count,omitempty"`  // 内存连续超限次数触发
-	RestartCooldown int     `json:"restart_cooldown,omitempty"`  // 重启冷却时间（秒）
+	PID              int32             `json:"pid"`
+	Name             string            `json:"name"`                        // 进程名
+	Alias            string            `json:"alias,omitempty"`             // 备注名称（如：电力监控主进程）
+	Cmdline          string            `json:"cmdline,omitempty"`           // 进程命令行（用于自动填充重启命令）
+	RestartCmd       string            `json:"restart_cmd,omitempty"`       // 重启命令
+	AutoRestart      bool              `json:"auto_restart"`                // 退出时自动重启
+	CPUThreshold     float64           `json:"cpu_threshold,omitempty"`     // CPU阈值 (%)
+	MemThreshold     uint64            `json:"mem_threshold,omitempty"`     // 内存阈值 (bytes)
+	CPUExceedCount   int               `json:"cpu_exceed_count,omitempty"`  // CPU连续超限次数触发
+	MemExceedCount   int               `json:"mem_exceed_count,omitempty"`  // 内存连续超限次数触发
+	RestartCooldown  int               `json:"restart_cooldown,omitempty"`  // 重启冷却时间（秒）
+	Supervise        *SuperviseSpec    `json:"supervise,omitempty"`         // 托管模式：由代理启动并持有进程
+	Hang             *HangCheck        `json:"hang,omitempty"`              // 挂死检测
+	Leak             *LeakCheck        `json:"leak,omitempty"`              // 泄漏趋势检测
+	Baseline         *BaselineCheck    `json:"baseline,omitempty"`          // 学习基线异常检测
+	DependsOn        []string          `json:"depends_on,omitempty"`        // 依赖的上游目标（备注名称或进程名）
+	Ready            *ReadinessCheck   `json:"ready,omitempty"`             // 就绪检查（作为上游时使用）
+	CascadeRestart   bool              `json:"cascade_restart,omitempty"`   // 上游重启后随之重启
+	Disabled         bool              `json:"disabled,omitempty"`          // 暂停监控（不采集、不产生事件）
+	Discovery        string            `json:"discovery,omitempty"`         // 自动发现该目标的规则名称
+	Integrity        *IntegrityCheck   `json:"integrity,omitempty"`         // 可执行文件完整性校验
+	Sockets          *SocketCheck      `json:"sockets,omitempty"`           // 监听端口和网络连接监控
+	Logs             *LogWatch         `json:"logs,omitempty"`              // 应用日志监视
+	Freshness        []FreshnessCheck  `json:"freshness,omitempty"`         // 输出文件新鲜度检查
+	Heartbeat        *HeartbeatCheck   `json:"heartbeat,omitempty"`         // 应用心跳
+	CustomThresholds []CustomThreshold `json:"custom_thresholds,omitempty"` // 自定义指标阈值
+	Check            *CheckStatus      `json:"check,omitempty"`             // 外部检查作为目标展示时的状态（只读，名称为 check:<检查名>）
+}
+
+// SuperviseSpec 托管进程启动参数（直接 exec，不经过 sh -c）
+type SuperviseSpec struct {
+	Command       string            `json:"command"`                  // 可执行文件路径
+	Args          []string          `json:"args,omitempty"`           // 参数
+	Env           []string          `json:"env,omitempty"`            // 追加的环境变量 KEY=VALUE
+	Dir           string            `json:"dir,omitempty"`            // 工作目录
+	User          string            `json:"user,omitempty"`           // 运行用户（仅 Linux）
+	Group         string            `json:"group,omitempty"`          // 运行组（仅 Linux，默认用户主组）
+	Rlimits       map[string]uint64 `json:"rlimits,omitempty"`        // 资源限制（仅 Linux）：nofile/core/nproc/as/data/stack/fsize/memlock/cpu
+	RestartPolicy string            `json:"restart_policy,omitempty"` // always / on-failure / never，为空时按 auto_restart
+	RestartDelay  int               `json:"restart_delay,omitempty"`  // 重启前等待（秒），默认 1
+	MaxRestarts   int               `json:"max_restarts,omitempty"`   // 最多自动重启次数，0 不限制
+	StopTimeout   int               `json:"stop_timeout,omitempty"`   // 停止时 SIGTERM 后等待多久 SIGKILL（秒），默认 10
+	LogMaxSize    int64             `json:"log_max_size,omitempty"`   // stdout/stderr 日志滚动大小（字节），默认 10MB
+	LogMaxFiles   int               `json:"log_max_files,omitempty"`  // 保留的滚动文件数，默认 5
 }
 
 // MultiMonitorConfig 多进程监控配置
 type MultiMonitorConfig struct {
-	Targets          []MonitorTarget `json:"targets"`
-	CPUThreshold     float64         `json:"cpu_threshold"`
-	CPUExceedCount   int             `json:"cpu_exceed_count"`
-	SampleInterval   int             `json:"sample_interval"` // 采样间隔（秒）
-	MetricsBufferLen int             `json:"metrics_buffer_len"`
-	EventsBufferLen  int             `json:"events_buffer_len"`
-	LogDir           string          `json:"log_dir"`
+	Targets           []MonitorTarget     `json:"targets"`
+	CPUThreshold      float64             `json:"cpu_threshold"`
+	CPUExceedCount    int                 `json:"cpu_exceed_count"`
+	SampleInterval    int                 `json:"sample_interval"`           // 采样间隔（秒）
+	SystemInterval    int                 `json:"system_interval,omitempty"` // 系统指标记录间隔（秒），默认 5
+	MetricsBufferLen  int                 `json:"metrics_buffer_len"`
+	EventsBufferLen   int                 `json:"events_buffer_len"`
+	LogDir            string              `json:"log_dir"`
+	HistoryDir        string              `json:"history_dir"`                  // 历史存储目录（默认 LogDir/history）
+	HistoryRetention  int                 `json:"history_retention_days"`       // 历史保留天数
+	Forensics         ForensicsConfig     `json:"forensics"`                    // 现场采集
+	KernelLog         string              `json:"kernel_log,omitempty"`         // 内核日志路径（/dev/kmsg 或 kern.log），为空不启用
+	CoreDumps         CoreDumpConfig      `json:"core_dumps"`                   // core 文件检测
+	DiscoveryInterval int                 `json:"discovery_interval,omitempty"` // 自动发现扫描间隔（秒），默认 10
+	Inventory         InventoryConfig     `json:"inventory"`                    // 进程白名单检测
+	Heartbeat         HeartbeatConfig     `json:"heartbeat"`                    // 应用心跳接收
+	CustomMetrics     CustomMetricsConfig `json:"custom_metrics"`               // 自定义指标接收
+	Checks            ChecksConfig        `json:"checks"`                       // 外部检查
+	Disk              DiskConfig          `json:"disk"`                         // 磁盘空间/IO 监控
+	Sensors           SensorConfig        `json:"sensors"`                      // 硬件传感器监控
 }
 
 // SystemMetrics 系统指标
 type SystemMetrics struct {
-	CPUPercent    float64 `json:"cpu_percent"`
-	MemoryTotal   uint64  `json:"memory_total"`
-	MemoryUsed    uint64  `json:"memory_used"`
-	MemoryPercent float64 `json:"memory_percent"`
+	Timestamp     time.Time `json:"timestamp"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	CPUPerCore    []float64 `json:"cpu_per_core,omitempty"` // 各核心使用率 (%)
+	CPUIowait     float64   `json:"cpu_iowait"`             // 等待 IO 的时间占比 (%)
+	CPUSteal      float64   `json:"cpu_steal"`              // 被虚拟化宿主占用的时间占比 (%)
+	Load1         float64   `json:"load1"`                  // 平均负载（Windows 为估算值）
+	Load5         float64   `json:"load5"`
+	Load15        float64   `json:"load15"`
+	MemoryTotal   uint64    `json:"memory_total"`
+	MemoryUsed    uint64    `json:"memory_used"`
+	MemoryPercent float64   `json:"memory_percent"`
+	SwapTotal     uint64    `json:"swap_total"`
+	SwapUsed      uint64    `json:"swap_used"`
+	SwapPercent   float64   `json:"swap_percent"`
+	ProcessCount  int       `json:"process_count"`
+	Pressure      *Pressure `json:"pressure,omitempty"` // Linux PSI（内核 4.20+ 且启用时）
 	// 网络流量
-	NetBytesRecv uint64  `json:"net_bytes_recv"` // 网络接收总字节
-	NetBytesSent uint64  `json:"net_bytes_sent"` // 网络发送总字节
-	NetRecvRate  float64 `json:"net_recv_rate"`  // 接收速率 (B/s)
-	NetSendRate  float64 `json:"net_send_rate"`  // 发送速率 (B/s)
+	NetBytesRecv uint64          `json:"net_bytes_recv"` // 网络接收总字节
+	NetBytesSent uint64          `json:"net_bytes_sent"` // 网络发送总字节
+	NetRecvRate  float64         `json:"net_recv_rate"`  // 接收速率 (B/s)
+	NetSendRate  float64         `json:"net_send_rate"`  // 发送速率 (B/s)
+	Interfaces   []InterfaceStat `json:"interfaces,omitempty"`
+	Sensors      []SensorReading `json:"sensors,omitempty"` // 硬件传感器（启用传感器监控时）
+}
+
+// SensorConfig 硬件传感器监控配置（Linux hwmon / thermal）
+type SensorConfig struct {
+	Enabled    bool              `json:"enabled"`
+	SysfsRoot  string            `json:"sysfs_root,omitempty"` // sysfs 根目录，默认 /sys（测试时可指向样例目录）
+	Interval   int               `json:"interval,omitempty"`   // 采样间隔（秒），默认 10
+	TempMax    float64           `json:"temp_max,omitempty"`   // 所有温度传感器的告警上限 (°C)，0 时只使用芯片自带的 crit/max
+	Thresholds []SensorThreshold `json:"thresholds,omitempty"` // 按传感器指定的阈值，优先于 TempMax 和芯片限值
+	Hysteresis float64           `json:"hysteresis,omitempty"` // 恢复余量（阈值的百分比），告警后需回到余量以内才恢复，默认 5
+}
+
+// SensorThreshold 传感器阈值，0 表示不限制
+type SensorThreshold struct {
+	Sensor string  `json:"sensor"` // 传感器名称通配符，如 coretemp/Core*、thermal/x86_pkg_temp、nct6775/fan1
+	Max    float64 `json:"max,omitempty"`
+	Min    float64 `json:"min,omitempty"`
+}
+
+// SensorReading 一个传感器读数
+type SensorReading struct {
+	Name  string  `json:"name"` // 芯片/标签，如 coretemp/Package id 0
+	Kind  string  `json:"kind"` // temperature / fan / voltage
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`          // °C / RPM / V
+	Max   float64 `json:"max,omitempty"` // 生效的告警上限
+	Min   float64 `json:"min,omitempty"` // 生效的告警下限
+	Alarm bool    `json:"alarm,omitempty"`
+}
+
+// Pressure Linux PSI 资源压力（/proc/pressure）
+type Pressure struct {
+	CPU    PressureStat `json:"cpu"`
+	Memory PressureStat `json:"memory"`
+	IO     PressureStat `json:"io"`
+}
+
+// PressureStat 最近 10/60/300 秒内任务因资源不足而等待的时间占比 (%)：some 为至少一个任务等待，full 为全部任务等待
+type PressureStat struct {
+	Some10  float64 `json:"some10"`
+	Some60  float64 `json:"some60"`
+	Some300 float64 `json:"some300"`
+	Full10  float64 `json:"full10"`
+	Full60  float64 `json:"full60"`
+	Full300 float64 `json:"full300"`
+}
+
+// InterfaceStat 网卡流量与错误/丢包计数
+type InterfaceStat struct {
+	Name      string  `json:"name"`
+	BytesRecv uint64  `json:"bytes_recv"`
+	BytesSent uint64  `json:"bytes_sent"`
+	RecvRate  float64 `json:"recv_rate"` // B/s
+	SendRate  float64 `json:"send_rate"` // B/s
+	ErrIn     uint64  `json:"err_in"`    // 累计接收错误
+	ErrOut    uint64  `json:"err_out"`
+	DropIn    uint64  `json:"drop_in"` // 累计接收丢包
+	DropOut   uint64  `json:"drop_out"`
+}
+
+// DiskConfig 磁盘空间/IO 监控配置
+type DiskConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Interval       int      `json:"interval,omitempty"`        // 采样间隔（秒），默认 30
+	Include        []string `json:"include,omitempty"`         // 包含的挂载点通配符，为空时包含全部
+	Exclude        []string `json:"exclude,omitempty"`         // 排除的挂载点通配符
+	UsedPercent    float64  `json:"used_percent,omitempty"`    // 空间使用率告警阈值 (%)，默认 90
+	InodePercent   float64  `json:"inode_percent,omitempty"`   // inode 使用率告警阈值 (%)，默认 90
+	ForecastHours  float64  `json:"forecast_hours,omitempty"`  // 预计多少小时内写满时告警，默认 24，小于 0 不预测
+	ForecastWindow int      `json:"forecast_window,omitempty"` // 增长趋势拟合窗口（分钟），默认 360
+}
+
+// DiskUsage 文件系统空间和 inode 使用
+type DiskUsage struct {
+	Mount         string  `json:"mount"`
+	Device        string  `json:"device"`
+	FSType        string  `json:"fstype"`
+	Total         uint64  `json:"total"`
+	Used          uint64  `json:"used"`
+	Free          uint64  `json:"free"`
+	UsedPercent   float64 `json:"used_percent"`
+	InodesTotal   uint64  `json:"inodes_total"` // 不支持 inode 的文件系统（如 NTFS）为 0
+	InodesUsed    uint64  `json:"inodes_used"`
+	InodesFree    uint64  `json:"inodes_free"`
+	InodesPercent float64 `json:"inodes_percent"`
+	GrowthPerHour float64 `json:"growth_per_hour,omitempty"` // 已用空间增长趋势 (B/h)
+	FullInHours   float64 `json:"full_in_hours,omitempty"`   // 按趋势预计写满的剩余小时数
+}
+
+// DiskIOStats 磁盘 IO 统计（两次采样间的平均值）
+type DiskIOStats struct {
+	Device       string  `json:"device"`
+	ReadRate     float64 `json:"read_rate"`     // B/s
+	WriteRate    float64 `json:"write_rate"`    // B/s
+	ReadOps      float64 `json:"read_ops"`      // 次/秒
+	WriteOps     float64 `json:"write_ops"`     // 次/秒
+	ReadLatency  float64 `json:"read_latency"`  // 平均每次读耗时 (ms)
+	WriteLatency float64 `json:"write_latency"` // 平均每次写耗时 (ms)
+	Util         float64 `json:"util"`          // 忙碌时间占比 (%)
+}
+
+// DiskMetrics 一次磁盘采样
+type DiskMetrics struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Mounts    []DiskUsage   `json:"mounts"`
+	Disks     []DiskIOStats `json:"disks,omitempty"` // 首次采样无 IO 速率
+}
+
+// HangCheck 挂死检测配置
+type HangCheck struct {
+	StateDuration int      `json:"state_duration,omitempty"` // 持续处于 D（不可中断睡眠）/T（停止）状态多少秒判定挂死，0 不检测
+	StallDuration int      `json:"stall_duration,omitempty"` // CPU 时间、IO 字节、上下文切换均无推进多少秒判定挂死，0 不检测
+	BusyWindows   []string `json:"busy_windows,omitempty"`   // 预期忙碌时段（如 "08:00-20:00"），为空表示始终预期忙碌；仅对停滞检测生效
+	Restart       bool     `json:"restart,omitempty"`        // 判定挂死后重启
+}
+
+// ProcessCounters 进程原始累计计数，用于判断进程是否仍在推进
+type ProcessCounters struct {
+	Status      string  `json:"status"`       // running/sleep/blocked/stop/zombie...
+	CPUTime     float64 `json:"cpu_time"`     // user+system 累计 CPU 秒
+	ReadBytes   uint64  `json:"read_bytes"`   // 累计读字节
+	WriteBytes  uint64  `json:"write_bytes"`  // 累计写字节
+	CtxSwitches int64   `json:"ctx_switches"` // 自愿+非自愿上下文切换次数
+	NumThreads  int32   `json:"num_threads"`
+}
+
+// LeakCheck 内存/句柄泄漏趋势检测配置
+type LeakCheck struct {
+	Window          int     `json:"window,omitempty"`           // 拟合窗口（分钟），默认 360
+	Interval        int     `json:"interval,omitempty"`         // 分析周期（秒），默认 300
+	MinSamples      int     `json:"min_samples,omitempty"`      // 最少样本数（按分钟降采样），默认 30
+	Confidence      float64 `json:"confidence,omitempty"`       // 判定增长的置信度 (0-1)，默认 0.99
+	MinRSSGrowth    float64 `json:"min_rss_growth,omitempty"`   // RSS 最小增长速率（字节/小时），低于此值不报警
+	FDThreshold     float64 `json:"fd_threshold,omitempty"`     // 句柄数阈值（用于预测到达时间）
+	ThreadThreshold float64 `json:"thread_threshold,omitempty"` // 线程数阈值（用于预测到达时间）
+}
+
+// TrendForecast 单项指标的增长趋势
+type TrendForecast struct {
+	Metric           string  `json:"metric"`                       // rss / fds / threads
+	Current          float64 `json:"current"`                      // 当前值（拟合值）
+	SlopePerHour     float64 `json:"slope_per_hour"`               // 增长速率（每小时）
+	Confidence       float64 `json:"confidence"`                   // 斜率为正的置信度
+	R2               float64 `json:"r2"`                           // 拟合优度
+	Samples          int     `json:"samples"`                      // 样本数
+	Threshold        float64 `json:"threshold,omitempty"`          // 阈值
+	HoursToThreshold float64 `json:"hours_to_threshold,omitempty"` // 预计到达阈值的小时数（无阈值或不增长时为 0）
+	Suspected        bool    `json:"suspected"`                    // 是否疑似泄漏
+}
+
+// LeakForecast 目标泄漏预测
+type LeakForecast struct {
+	PID       int32           `json:"pid"`
+	Name      string          `json:"name"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Trends    []TrendForecast `json:"trends"`
+}
+
+// BaselineCheck 学习基线（按一周内小时分桶的均值/标准差）异常检测配置
+type BaselineCheck struct {
+	Metrics     []string `json:"metrics,omitempty"`      // 参与检测的指标：cpu / rss / io，默认全部
+	Sigma       float64  `json:"sigma,omitempty"`        // 偏离多少个标准差判定异常，默认 3
+	MinSamples  int      `json:"min_samples,omitempty"`  // 分桶最少样本数，未达到时只学习不告警，默认 300
+	MinWeeks    int      `json:"min_weeks,omitempty"`    // 分桶最少覆盖的周数，未达到时只学习不告警，默认 2
+	ExceedCount int      `json:"exceed_count,omitempty"` // 连续偏离次数触发，默认 5
+	LearnDays   int      `json:"learn_days,omitempty"`   // 首次建模时从历史存储回溯的天数，默认 7
+}
+
+// ReadinessCheck 就绪检查：进程存活满 Delay 秒且检查命令退出码为 0 时视为就绪
+type ReadinessCheck struct {
+	Cmd     string `json:"cmd,omitempty"`     // 检查命令，为空时只看存活
+	Delay   int    `json:"delay,omitempty"`   // 进程启动后至少等待的秒数
+	Timeout int    `json:"timeout,omitempty"` // 检查命令超时（秒），默认 10
+}
+
+// IntegrityCheck 完整性校验：添加目标时记录可执行文件（及列出的库、配置文件）的 SHA-256，定期及每次重启前复核
+type IntegrityCheck struct {
+	Files        []string `json:"files,omitempty"`         // 额外校验的共享库、配置文件（绝对路径）
+	Interval     int      `json:"interval,omitempty"`      // 定期复核间隔（秒），默认 300
+	BlockRestart bool     `json:"block_restart,omitempty"` // 不一致时阻止重启
+}
+
+// SocketCheck 监听端口和网络连接监控
+type SocketCheck struct {
+	ExpectListen []string `json:"expect_listen,omitempty"`  // 必须监听的端口，如 "502"（TCP）、"tcp/2404"、"udp/161"
+	ExpectPeers  []string `json:"expect_peers,omitempty"`   // 必须保持已建立连接的远端，"ip:port" 或 "ip"（任意端口）
+	CloseWaitMax int      `json:"close_wait_max,omitempty"` // CLOSE_WAIT 超过该值判定泄漏，0 不检测
+	FailCount    int      `json:"fail_count,omitempty"`     // 连续多少次采样不满足才告警，默认 3
+	Restart      bool     `json:"restart,omitempty"`        // 告警时重启
+}
+
+// FreshnessCheck 输出文件新鲜度检查：超过 max_age 未更新、小于 min_size 或内容不匹配时判定过期
+type FreshnessCheck struct {
+	Path    string `json:"path"`               // 文件路径，支持通配符（取最近修改的文件）
+	MaxAge  int    `json:"max_age"`            // 最长未更新时间（秒）
+	MinSize int64  `json:"min_size,omitempty"` // 最小字节数
+	Content string `json:"content,omitempty"`  // 内容需匹配的正则（读取前 1MB）
+}
+
+// HeartbeatCheck 应用心跳：超过 timeout 秒未收到心跳判定失联
+type HeartbeatCheck struct {
+	AppID   string `json:"app_id,omitempty"`  // 心跳中的应用 ID，为空时使用备注名称或进程名
+	Timeout int    `json:"timeout,omitempty"` // 超时（秒），默认 30
+}
+
+// HeartbeatStatus 目标心跳状态
+type HeartbeatStatus struct {
+	PID      int32                  `json:"pid"`
+	Name     string                 `json:"name"`
+	AppID    string                 `json:"app_id"`
+	LastSeen time.Time              `json:"last_seen,omitempty"`
+	Seq      uint64                 `json:"seq"`
+	Received uint64                 `json:"received"` // 收到的心跳数
+	Lost     uint64                 `json:"lost"`     // 按序号间隔推算丢失的心跳数
+	Resets   uint64                 `json:"resets"`   // 序号回退次数（应用重启）
+	Missed   bool                   `json:"missed"`   // 当前处于超时状态
+	Status   map[string]interface{} `json:"status,omitempty"`
+}
+
+// CustomThreshold 自定义指标阈值（应用推送的指标和心跳数值字段）
+type CustomThreshold struct {
+	Metric      string  `json:"metric"`                 // 指标名
+	Op          string  `json:"op,omitempty"`           // ">" 或 "<"，默认 ">"
+	Value       float64 `json:"value"`                  // 阈值
+	ExceedCount int     `json:"exceed_count,omitempty"` // 连续超限次数触发，默认 3
+}
+
+// CustomPoint 自定义指标采样点
+type CustomPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// CustomSeries 自定义指标序列
+type CustomSeries struct {
+	Name   string        `json:"name"`
+	Kind   string        `json:"kind"`            // gauge / counter（counter 按采样间隔记录每秒速率）
+	Value  float64       `json:"value"`           // 最近一次采样值
+	Total  float64       `json:"total,omitempty"` // counter 累计值
+	Points []CustomPoint `json:"points,omitempty"`
+}
+
+// CustomMetricsConfig 自定义指标接收监听地址，为空不启用
+type CustomMetricsConfig struct {
+	StatsDAddr string `json:"statsd_addr,omitempty"` // StatsD UDP 监听地址，如 :8125
+	HTTPAddr   string `json:"http_addr,omitempty"`   // HTTP 监听地址，POST /metrics
+	MaxSeries  int    `json:"max_series,omitempty"`  // 每个目标最多的指标数，超出的新指标被拒绝，默认 100
+}
+
+// HeartbeatConfig 心跳接收监听地址，为空不启用
+type HeartbeatConfig struct {
+	UDPAddr  string `json:"udp_addr,omitempty"`  // UDP 监听地址，如 :9110
+	HTTPAddr string `json:"http_addr,omitempty"` // HTTP 监听地址，POST /heartbeat
+}
+
+// LogWatch 应用日志监视：跟踪文件新增内容（处理滚动/截断），按正则规则产生事件
+type LogWatch struct {
+	Paths     []string  `json:"paths"`                // 日志文件路径，支持通配符
+	Rules     []LogRule `json:"rules"`                // 匹配规则
+	FromStart bool      `json:"from_start,omitempty"` // 启动时已存在的文件从头读取（默认只读新增内容）
+}
+
+// LogRule 日志匹配规则
+type LogRule struct {
+	Name      string `json:"name"`
+	Pattern   string `json:"pattern"`              // 正则表达式
+	Severity  string `json:"severity,omitempty"`   // info / warning / critical，默认 warning
+	RateLimit int    `json:"rate_limit,omitempty"` // 每分钟最多产生的事件数，默认 10，超出只计数
+	Restart   bool   `json:"restart,omitempty"`    // 匹配时重启目标
+}
+
+// LogWatchStatus 目标日志监视状态
+type LogWatchStatus struct {
+	PID   int32           `json:"pid"`
+	Name  string          `json:"name"`
+	Files []LogFileStatus `json:"files"`
+	Rules []LogRuleStatus `json:"rules"`
+}
+
+// LogFileStatus 跟踪中的日志文件
+type LogFileStatus struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"` // 已读取到的位置
+}
+
+// LogRuleStatus 日志规则计数
+type LogRuleStatus struct {
+	Name       string    `json:"name"`
+	Severity   string    `json:"severity"`
+	Matches    uint64    `json:"matches"`    // 累计匹配次数
+	Suppressed uint64    `json:"suppressed"` // 超出频率限制未产生事件的次数
+	LastMatch  time.Time `json:"last_match,omitempty"`
+	LastLine   string    `json:"last_line,omitempty"`
+}
+
+// IntegrityFile 完整性校验文件
+type IntegrityFile struct {
+	Path    string `json:"path"`
+	SHA256  string `json:"sha256"`            // 记录的哈希
+	Current string `json:"current,omitempty"` // 不一致时最近一次计算的哈希（文件缺失时为空）
+	OK      bool   `json:"ok"`
+}
+
+// IntegrityStatus 目标完整性校验状态
+type IntegrityStatus struct {
+	PID        int32           `json:"pid"`
+	Name       string          `json:"name"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	CheckedAt  time.Time       `json:"checked_at,omitempty"`
+	Violated   bool            `json:"violated"`
+	Files      []IntegrityFile `json:"files"`
+}
+
+// DependencyNode 依赖图节点
+type DependencyNode struct {
+	Name       string   `json:"name"`
+	PID        int32    `json:"pid,omitempty"`
+	DependsOn  []string `json:"depends_on,omitempty"`
+	Dependents []string `json:"dependents,omitempty"`
+	Level      int      `json:"level"` // 启动顺序层级，0 为无依赖
+	Ready      bool     `json:"ready"`
+	DegradedBy []string `json:"degraded_by,omitempty"` // 未就绪的上游（含间接上游）
+	Waiting    string   `json:"waiting,omitempty"`     // start / restart：等待上游就绪后启动 / 重启
+}
+
+// BaselineBucket 基线分桶统计
+type BaselineBucket struct {
+	HourOfWeek int     `json:"hour_of_week"` // 0 = 周日 00 时
+	N          int64   `json:"n"`
+	Weeks      int     `json:"weeks"` // 样本覆盖的周数
+	Mean       float64 `json:"mean"`
+	Std        float64 `json:"std"`
+}
+
+// BaselineInfo 目标的学习基线
+type BaselineInfo struct {
+	Name      string                      `json:"name"`
+	UpdatedAt time.Time                   `json:"updated_at"`
+	Metrics   map[string][]BaselineBucket `json:"metrics"`
+}
+
+// DowntimePeriod 不可用时段
+type DowntimePeriod struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration float64   `json:"duration"` // 秒
+	Cause    string    `json:"cause"`    // 退出原因（退出事件描述）
+	Ongoing  bool      `json:"ongoing"`  // 统计截止时仍未恢复
+}
+
+// TargetAvailability 单个目标的可用性统计
+type TargetAvailability struct {
+	Name             string           `json:"name"`
+	Alias            string           `json:"alias,omitempty"`
+	PID              int32            `json:"pid"`        // 最近的 PID
+	Observed         float64          `json:"observed"`   // 统计时长（秒，已扣除计划停机）
+	Uptime           float64          `json:"uptime"`     // 可用时长（秒）
+	Downtime         float64          `json:"downtime"`   // 不可用时长（秒）
+	UptimePct        float64          `json:"uptime_pct"` // 可用率 (%)
+	Failures         int              `json:"failures"`   // 非计划退出次数
+	MTBF             float64          `json:"mtbf"`       // 平均故障间隔（秒），无故障时为 0
+	MTTR             float64          `json:"mttr"`       // 平均恢复时间（秒），无恢复时为 0
+	Restarts         int              `json:"restarts"`   // 成功重启次数
+	RestartsByReason map[string]int   `json:"restarts_by_reason"`
+	Downtimes        []DowntimePeriod `json:"downtimes"`
+}
+
+// AvailabilityReport 可用性报告
+type AvailabilityReport struct {
+	Period      string               `json:"period"` // day / week / month
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	GeneratedAt time.Time            `json:"generated_at"`
+	Targets     []TargetAvailability `json:"targets"`
+}
+
+// ForensicsConfig 崩溃现场采集配置
+type ForensicsConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Dir           string `json:"dir,omitempty"`            // 现场包目录（默认 LogDir/forensics）
+	Minutes       int    `json:"minutes,omitempty"`        // 回溯指标分钟数，默认 5
+	Events        int    `json:"events,omitempty"`         // 最近事件条数，默认 50
+	TopN          int    `json:"top_n,omitempty"`          // 进程快照按 CPU/内存各取前 N 个，默认 20
+	RetentionDays int    `json:"retention_days,omitempty"` // 保留天数，默认 30
+	MaxPerTarget  int    `json:"max_per_target,omitempty"` // 每个目标最多保留的现场包数，默认 20
+	MinInterval   int    `json:"min_interval,omitempty"`   // 同一目标同类事件两次采集的最小间隔（秒），默认 60
+}
+
+// ForensicsBundle 现场包信息
+type ForensicsBundle struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	PID       int32     `json:"pid"`
+	Trigger   string    `json:"trigger"` // 触发事件类型
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int64     `json:"size"`
+	Files     []string  `json:"files"`
+}
+
+// CoreDumpConfig core 文件检测与归档配置
+type CoreDumpConfig struct {
+	Enabled              bool     `json:"enabled"`
+	Dirs                 []string `json:"dirs,omitempty"`                   // 额外扫描的目录（core_pattern 之外）
+	Interval             int      `json:"interval,omitempty"`               // 扫描间隔（秒），默认 30
+	ArchiveDir           string   `json:"archive_dir,omitempty"`            // 归档目录，为空时不移动 core 文件
+	ArchiveMaxBytes      int64    `json:"archive_max_bytes,omitempty"`      // 归档目录容量上限，超出时删除最旧的文件，默认 10GB
+	ArchiveRetentionDays int      `json:"archive_retention_days,omitempty"` // 归档保留天数，默认 30
+}
+
+// CoreDump 检测到的 core 文件
+type CoreDump struct {
+	Path         string    `json:"path"`             // 当前路径（归档后为归档路径）
+	OriginalPath string    `json:"original_path"`    // 生成时的路径
+	Size         int64     `json:"size"`             // 文件大小（字节）
+	Time         time.Time `json:"time"`             // 文件修改时间
+	PID          int32     `json:"pid"`              // 崩溃进程 PID
+	Name         string    `json:"name"`             // 监控目标名称
+	Exe          string    `json:"exe,omitempty"`    // core 中记录的进程名/路径
+	Signal       string    `json:"signal,omitempty"` // 导致转储的信号（core_pattern 含 %s 时）
+	Archived     bool      `json:"archived"`         // 是否已移入归档目录
+}
+
+// RedundancyGroup 冗余组：同一时刻只允许一个成员处于主用状态
+type RedundancyGroup struct {
+	Name           string             `json:"name"`
+	Members        []RedundancyMember `json:"members"`                   // 按优先级排列，第一个为首选主用
+	ProbeInterval  int                `json:"probe_interval,omitempty"`  // 探测间隔（秒），默认 5
+	ProbeTimeout   int                `json:"probe_timeout,omitempty"`   // 探测/切换命令超时（秒），默认 10
+	FailoverDelay  int                `json:"failover_delay,omitempty"`  // 无主用持续多少秒后切换，默认 3
+	PromoteTimeout int                `json:"promote_timeout,omitempty"` // 升主后等待成为主用的时间（秒），默认 30
+}
+
+// RedundancyMember 冗余组成员
+type RedundancyMember struct {
+	Name       string `json:"name"`                 // 成员名称（如 A / B）
+	Target     string `json:"target"`               // 对应监控目标的备注名称或进程名
+	ProbeCmd   string `json:"probe_cmd,omitempty"`  // 主用探测命令（退出码 0 为主用），为空时按进程存活判断
+	PromoteCmd string `json:"promote_cmd"`          // 升为主用的命令（启动进程或切换角色）
+	DemoteCmd  string `json:"demote_cmd,omitempty"` // 降为备用的命令，为空时结束进程（双主、手动切换，或故障切换时原主用仍存活）
+}
+
+// RedundancyMemberStatus 成员状态
+type RedundancyMemberStatus struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	PID    int32  `json:"pid,omitempty"`
+	Alive  bool   `json:"alive"`
+	Active bool   `json:"active"`
+}
+
+// SwitchoverRecord 主备切换记录
+type SwitchoverRecord struct {
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Reason      string    `json:"reason"`                 // failover / manual / external
+	DetectedAt  time.Time `json:"detected_at"`            // 检测到需要切换的时间
+	PromotedAt  time.Time `json:"promoted_at"`            // 执行升主命令的时间
+	CompletedAt time.Time `json:"completed_at,omitempty"` // 新成员成为主用的时间
+	DurationMs  int64     `json:"duration_ms"`            // 从检测到完成的耗时
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// RedundancyStatus 冗余组状态
+type RedundancyStatus struct {
+	Group          RedundancyGroup          `json:"group"`
+	Active         string                   `json:"active"` // 当前主用成员（无主用时为空）
+	Members        []RedundancyMemberStatus `json:"members"`
+	Switching      string                   `json:"switching,omitempty"` // 正在升主的成员
+	Switchovers    int                      `json:"switchovers"`
+	LastSwitchover *SwitchoverRecord        `json:"last_switchover,omitempty"`
+}
+
+// ChecksConfig 外部检查（Nagios 插件）调度配置
+type ChecksConfig struct {
+	File          string `json:"file,omitempty"`           // 启动时加载的检查定义文件（JSON 数组）
+	MaxConcurrent int    `json:"max_concurrent,omitempty"` // 同时执行的检查数上限，默认 4
+}
+
+// ExecCheck 外部检查：按 Nagios 插件约定执行命令，退出码 0/1/2/3 对应 OK/WARNING/CRITICAL/UNKNOWN，
+// 输出中 | 之后为性能数据
+type ExecCheck struct {
+	Name     string `json:"name"`
+	Command  string `json:"command"`            // 检查命令（经系统 shell 执行）
+	Interval int    `json:"interval,omitempty"` // 执行间隔（秒），默认 60
+	Timeout  int    `json:"timeout,omitempty"`  // 超时（秒），默认 10，超时结果为 UNKNOWN
+}
+
+// PerfData 一项性能数据：'label'=value[UOM];[warn];[crit];[min];[max]
+type PerfData struct {
+	Label string   `json:"label"`
+	Value float64  `json:"value"`
+	Unit  string   `json:"unit,omitempty"`
+	Warn  string   `json:"warn,omitempty"` // 阈值范围（原样保留）
+	Crit  string   `json:"crit,omitempty"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// CheckStatus 外部检查状态
+type CheckStatus struct {
+	Check      ExecCheck  `json:"check"`
+	State      string     `json:"state"` // PENDING（尚未执行）/ OK / WARNING / CRITICAL / UNKNOWN
+	ExitCode   int        `json:"exit_code"`
+	Output     string     `json:"output,omitempty"`      // 第一行输出
+	LongOutput string     `json:"long_output,omitempty"` // 其余行
+	PerfData   []PerfData `json:"perf_data,omitempty"`
+	LastRun    time.Time  `json:"last_run,omitempty"`
+	Duration   float64    `json:"duration"`              // 最近一次执行耗时（秒）
+	LastChange time.Time  `json:"last_change,omitempty"` // 最近一次状态变化
+	Runs       uint64     `json:"runs"`
+}
+
+// AppGroup 应用分组：按业务系统组织的监控目标
+type AppGroup struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Members     []string `json:"members"` // 目标备注名称或进程名
+}
+
+// GroupMemberStatus 分组成员状态
+type GroupMemberStatus struct {
+	Ref      string  `json:"ref"` // 分组中配置的成员名称
+	PID      int32   `json:"pid,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Health   string  `json:"health"` // ok / warning / critical / down / disabled
+	Reason   string  `json:"reason,omitempty"`
+	CPUPct   float64 `json:"cpu_pct"`
+	RSSBytes uint64  `json:"rss_bytes"`
+}
+
+// GroupStatus 分组汇总状态
+type GroupStatus struct {
+	Group    AppGroup            `json:"group"`
+	Health   string              `json:"health"` // ok / warning / critical / down
+	Alive    int                 `json:"alive"`
+	Total    int                 `json:"total"` // 未暂停监控的成员数
+	CPUPct   float64             `json:"cpu_pct"`
+	RSSBytes uint64              `json:"rss_bytes"`
+	Members  []GroupMemberStatus `json:"members"`
+}
+
+// DiscoveryRule 自动发现规则：匹配的新进程按模板自动加入监控，至少配置一个匹配条件
+type DiscoveryRule struct {
+	Name              string        `json:"name"`
+	NamePattern       string        `json:"name_pattern,omitempty"`       // 进程名通配符（* ?）
+	ExePattern        string        `json:"exe_pattern,omitempty"`        // 可执行文件路径通配符（* 不跨目录）
+	User              string        `json:"user,omitempty"`               // 运行用户
+	CmdlineRegex      string        `json:"cmdline_regex,omitempty"`      // 命令行正则
+	Template          MonitorTarget `json:"template"`                     // 目标模板（阈值、重启配置等），PID/名称/命令行取自发现的进程
+	AliasPattern      string        `json:"alias_pattern,omitempty"`      // 备注名称模板，支持 {name} {pid} {index}
+	ExpectedInstances int           `json:"expected_instances,omitempty"` // 期望运行的实例数，低于该值时告警
+	RetireAfter       int           `json:"retire_after,omitempty"`       // 实例退出多少秒后仍未恢复则移除，默认 300
+}
+
+// DiscoveryStatus 自动发现规则状态
+type DiscoveryStatus struct {
+	Rule      DiscoveryRule `json:"rule"`
+	Instances []int32       `json:"instances"` // 该规则加入的目标 PID
+	Running   int           `json:"running"`
+	Below     bool          `json:"below_expected"` // 运行实例数低于期望值
+}
+
+// InventoryConfig 进程白名单检测配置：首次运行时以当前进程建立基线，之后基线外的进程告警
+type InventoryConfig struct {
+	Enabled          bool     `json:"enabled"`
+	Interval         int      `json:"interval,omitempty"`          // 扫描间隔（秒），默认 30
+	Allow            []string `json:"allow,omitempty"`             // 额外允许的可执行文件路径通配符
+	Deny             []string `json:"deny,omitempty"`              // 禁止的可执行文件路径或进程名通配符
+	KillDenied       bool     `json:"kill_denied,omitempty"`       // 自动结束禁止的进程
+	UnknownRetention int      `json:"unknown_retention,omitempty"` // 不再运行的基线外条目保留时长（小时），默认 24
+}
+
+// InventoryEntry 进程清单条目（可执行文件路径 + SHA-256 + 运行用户）
+type InventoryEntry struct {
+	ID        string    `json:"id"`
+	Exe       string    `json:"exe"`
+	SHA256    string    `json:"sha256,omitempty"` // 无权读取时为空
+	User      string    `json:"user,omitempty"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`           // baseline / unknown / denied
+	Reason    string    `json:"reason,omitempty"` // unknown 的原因：new / hash_changed / user_changed
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	PIDs      []int32   `json:"pids,omitempty"` // 当前运行的进程
 }